@@ -0,0 +1,30 @@
+// Command gym serves reinforcement learning environments over the War rules
+// on a local HTTP and JSON API, for agents trained outside Go. See
+// gym.NewEnvHandler for the endpoints.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"es2.uff/war-server/gym"
+	"es2.uff/war-server/internal/ws"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8090", "address to serve on")
+	external := flag.String("external", "", "external bots as name=command or name=URL separated by semicolons")
+	iterations := flag.Int("mcts-iterations", ws.DefaultMCTSBudget.Iterations, "search iterations of each expert attack")
+	mctsTime := flag.Duration("mcts-time", 0, "time limit of each expert attack, 0 for none")
+	flag.Parse()
+
+	if err := ws.RegisterExternalBots(*external); err != nil {
+		log.Fatalf("Invalid -external: %v", err)
+	}
+	ws.DefaultMCTSBudget.Iterations = *iterations
+	ws.DefaultMCTSBudget.Time = *mctsTime
+
+	log.Printf("Serving environments on http://%s/envs", *addr)
+	log.Fatal(http.ListenAndServe(*addr, gym.NewEnvHandler()))
+}
@@ -2,9 +2,15 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"strconv"
+	"time"
 
+	"es2.uff/war-server/internal/auth"
+	"es2.uff/war-server/internal/bus"
 	"es2.uff/war-server/internal/handlers"
+	"es2.uff/war-server/internal/repository"
 	"es2.uff/war-server/internal/ws"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -23,17 +29,130 @@ func main() {
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORS())
 
+	// Initialize storage, kept in memory unless REDIS_URL is set
+	var (
+		players repository.PlayerRepository = repository.NewMemoryPlayerRepository()
+		rooms   repository.RoomRepository   = repository.NewMemoryRoomRepository()
+		games   repository.GameRepository   = repository.NewMemoryGameRepository()
+	)
+
+	// Replicas share rooms and games over Redis, a single instance keeps them local
+	cluster := ws.NewLocalCluster()
+
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		client, err := repository.NewRedisClient(redisURL)
+		if err != nil {
+			log.Fatalf("Error connecting to Redis: %v", err)
+		}
+
+		store := repository.NewRedisStore(client, "war:")
+		players = store.Players()
+		rooms = store.Rooms()
+		games = store.Games()
+
+		cluster.Bus = bus.NewRedisBus(client, "war:")
+		cluster.Leases = bus.NewRedisLeases(client, "war:")
+	}
+
+	// Match history and accounts live in an embedded SQLite database
+	sqlitePath := os.Getenv("SQLITE_PATH")
+	if sqlitePath == "" {
+		sqlitePath = "war.db"
+	}
+
+	db, err := repository.OpenSQLite(sqlitePath)
+	if err != nil {
+		log.Fatalf("Error opening SQLite database: %v", err)
+	}
+	defer db.Close()
+
+	history := repository.NewSQLiteHistoryRepository(db)
+	accounts := repository.NewSQLiteAccountRepository(db)
+
 	// Initialize WebSocket room server
-	roomServer := ws.NewRoomServer()
+	roomServer, err := ws.NewRoomServer(rooms, players, cluster)
+	if err != nil {
+		log.Fatalf("Error starting room server: %v", err)
+	}
+
+	// Expert bots search each attack within BOT_MCTS_ITERATIONS and BOT_MCTS_TIME
+	if raw := os.Getenv("BOT_MCTS_ITERATIONS"); raw != "" {
+		if ws.DefaultMCTSBudget.Iterations, err = strconv.Atoi(raw); err != nil {
+			log.Fatalf("Invalid BOT_MCTS_ITERATIONS: %v", err)
+		}
+	}
+	if raw := os.Getenv("BOT_MCTS_TIME"); raw != "" {
+		if ws.DefaultMCTSBudget.Time, err = time.ParseDuration(raw); err != nil {
+			log.Fatalf("Invalid BOT_MCTS_TIME: %v", err)
+		}
+	}
+
+	// BOT_PACING_SCALE speeds bots up below 1 and slows them down above it
+	if raw := os.Getenv("BOT_PACING_SCALE"); raw != "" {
+		scale, err := strconv.ParseFloat(raw, 64)
+		if err != nil || scale < 0 {
+			log.Fatalf("Invalid BOT_PACING_SCALE: %q", raw)
+		}
+		ws.DefaultBotPacing = ws.DefaultBotPacing.Scaled(scale)
+	}
+
+	// External bots are listed in BOT_EXTERNAL as name=command or name=URL
+	// separated by semicolons, rooms pick them as "external:name"
+	if err := ws.RegisterExternalBots(os.Getenv("BOT_EXTERNAL")); err != nil {
+		log.Fatalf("Invalid BOT_EXTERNAL: %v", err)
+	}
+	if raw := os.Getenv("BOT_EXTERNAL_TIMEOUT"); raw != "" {
+		if ws.ExternalBotTimeout, err = time.ParseDuration(raw); err != nil {
+			log.Fatalf("Invalid BOT_EXTERNAL_TIMEOUT: %v", err)
+		}
+	}
+
+	// Initialize game manager, after the bots are configured as restored
+	// games resume their bot turns right away
+	gameManager := ws.NewGameManager(rooms, games, history, cluster)
+	if err := gameManager.RestoreGames(); err != nil {
+		log.Printf("Error restoring games: %v", err)
+	}
+
+	// Session tokens are signed with AUTH_SECRET, which replicas must share.
+	// Only development servers, started with DEV_MODE, may run without it.
+	devMode, _ := strconv.ParseBool(os.Getenv("DEV_MODE"))
+	authKey := []byte(os.Getenv("AUTH_SECRET"))
+	if len(authKey) == 0 {
+		if !devMode {
+			log.Fatalf("AUTH_SECRET is not set, set DEV_MODE=true to run with a random key")
+		}
+		log.Printf("AUTH_SECRET is not set, tokens will not survive a restart")
+		if authKey, err = auth.NewKey(); err != nil {
+			log.Fatalf("Error generating auth key: %v", err)
+		}
+	}
+
+	tokenTTL := 24 * time.Hour
+	if raw := os.Getenv("AUTH_TOKEN_TTL"); raw != "" {
+		if tokenTTL, err = time.ParseDuration(raw); err != nil {
+			log.Fatalf("Invalid AUTH_TOKEN_TTL: %v", err)
+		}
+	}
+
+	// Refreshing tokens keeps a session going for at most AUTH_SESSION_MAX
+	maxSession := 30 * 24 * time.Hour
+	if raw := os.Getenv("AUTH_SESSION_MAX"); raw != "" {
+		if maxSession, err = time.ParseDuration(raw); err != nil {
+			log.Fatalf("Invalid AUTH_SESSION_MAX: %v", err)
+		}
+	}
 
-	// Initialize game manager
-	gameManager := ws.NewGameManager()
+	tokens := auth.NewTokenManager(authKey, tokenTTL, maxSession)
 
 	// Initialize handlers
-	roomHandler := handlers.NewRoomHandler(roomServer)
-	gameHandler := handlers.NewGameHandler(gameManager)
+	roomHandler := handlers.NewRoomHandler(roomServer, players, rooms, tokens)
+	gameHandler := handlers.NewGameHandler(gameManager, players)
+	authHandler := handlers.NewAuthHandler(tokens)
+	accountHandler := handlers.NewAccountHandler(players, accounts, tokens)
+	playerHandler := handlers.NewPlayerHandler(players, history)
 
-	handlers.SetupRoutes(e, roomHandler, gameHandler)
+	handlers.SetupRoutes(e, roomHandler, gameHandler, authHandler, accountHandler, playerHandler)
 
 	e.Logger.Fatal(e.Start(fmt.Sprintf(":%s", port)))
 }
@@ -0,0 +1,30 @@
+// Command refbot is the reference external bot. It plays like a built-in
+// difficulty over JSON lines on stdin and stdout, or serves the HTTP variant
+// of the protocol with -http.
+package main
+
+import (
+	"flag"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"os"
+
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/ws"
+)
+
+func main() {
+	difficulty := flag.String("difficulty", string(room.BotHard), "built-in difficulty to play like")
+	addr := flag.String("http", "", "serve the HTTP protocol on this address instead of stdin")
+	flag.Parse()
+
+	strategy := ws.NewBotStrategy(room.BotDifficulty(*difficulty), rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+
+	if *addr != "" {
+		log.Fatal(http.ListenAndServe(*addr, ws.NewBotHandler(strategy)))
+	}
+	if err := ws.ServeBot(os.Stdin, os.Stdout, strategy); err != nil {
+		log.Fatal(err)
+	}
+}
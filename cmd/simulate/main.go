@@ -0,0 +1,76 @@
+// Command simulate plays games between bots in process and prints how each
+// strategy, objective and seat fared. Runs with the same flags repeat
+// exactly, unless -mcts-time bounds the expert bots by the clock.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/simulate"
+	"es2.uff/war-server/internal/ws"
+)
+
+func main() {
+	games := flag.Int("games", 100, "games to play")
+	seed := flag.Uint64("seed", 1, "seed of the first game, the next ones count up from it")
+	bots := flag.String("bots", "hard,medium,easy", "comma separated strategy of each seat")
+	variant := flag.String("variant", string(room.VariantObjectives), "rule variant")
+	maxTurns := flag.Int("max-turns", simulate.DefaultMaxTurns, "turns before a game ends without a winner")
+	rotate := flag.Bool("rotate", true, "shift the seats by one each game")
+	workers := flag.Int("workers", 0, "games played at once, 0 for one per CPU")
+	external := flag.String("external", "", "external bots as name=command or name=URL separated by semicolons")
+	iterations := flag.Int("mcts-iterations", ws.DefaultMCTSBudget.Iterations, "search iterations of each expert attack")
+	mctsTime := flag.Duration("mcts-time", 0, "time limit of each expert attack, 0 for none")
+	asJSON := flag.Bool("json", false, "print the summary as JSON")
+	flag.Parse()
+
+	// External bots must be known before the strategies are checked
+	if err := ws.RegisterExternalBots(*external); err != nil {
+		log.Fatalf("Invalid -external: %v", err)
+	}
+
+	cfg := simulate.Config{
+		Games:    *games,
+		Seed:     *seed,
+		Variant:  room.RuleVariant(*variant),
+		MaxTurns: *maxTurns,
+		Rotate:   *rotate,
+		Workers:  *workers,
+	}
+	for _, name := range strings.Split(*bots, ",") {
+		d := room.BotDifficulty(strings.TrimSpace(name))
+		if !d.Valid() {
+			log.Fatalf("Invalid strategy: %q", name)
+		}
+		cfg.Bots = append(cfg.Bots, d)
+	}
+	if !slices.Contains(room.RuleVariants, cfg.Variant) {
+		log.Fatalf("Invalid rule variant: %q", *variant)
+	}
+
+	ws.DefaultMCTSBudget.Iterations = *iterations
+	ws.DefaultMCTSBudget.Time = *mctsTime
+
+	start := time.Now()
+	results, err := simulate.Run(cfg)
+	if err != nil {
+		log.Fatalf("Error simulating: %v", err)
+	}
+	summary := simulate.Summarize(results)
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(summary)
+		return
+	}
+	summary.Write(os.Stdout)
+	log.Printf("Played %d games in %v", len(results), time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,84 @@
+// Command tournament plays a tournament between bot strategies over seeded
+// games and rates them, to tell whether a new bot is stronger. External bots
+// enter with -external and play as "external:name".
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/simulate"
+	"es2.uff/war-server/internal/ws"
+)
+
+func main() {
+	bots := flag.String("bots", "easy,medium,hard", "comma separated strategies entering")
+	format := flag.String("format", string(simulate.RoundRobin), "round_robin or swiss")
+	table := flag.Int("table", 2, "players at each game")
+	rounds := flag.Int("rounds", 0, "rounds to play, 0 for the format's default")
+	games := flag.Int("games", 0, "games of each match, 0 for one from each seat")
+	seed := flag.Uint64("seed", 1, "seed of the first game")
+	variant := flag.String("variant", string(room.VariantObjectives), "rule variant")
+	maxTurns := flag.Int("max-turns", simulate.DefaultMaxTurns, "turns before a game ends without a winner")
+	workers := flag.Int("workers", 0, "games played at once, 0 for one per CPU")
+	external := flag.String("external", "", "external bots as name=command or name=URL separated by semicolons")
+	iterations := flag.Int("mcts-iterations", ws.DefaultMCTSBudget.Iterations, "search iterations of each expert attack")
+	mctsTime := flag.Duration("mcts-time", 0, "time limit of each expert attack, 0 for none")
+	jsonOut := flag.String("json", "", "write the JSON report to this file")
+	markdownOut := flag.String("markdown", "", "write the Markdown report to this file instead of stdout")
+	flag.Parse()
+
+	if err := ws.RegisterExternalBots(*external); err != nil {
+		log.Fatalf("Invalid -external: %v", err)
+	}
+	ws.DefaultMCTSBudget.Iterations = *iterations
+	ws.DefaultMCTSBudget.Time = *mctsTime
+
+	cfg := simulate.TournamentConfig{
+		Format:    simulate.Format(*format),
+		TableSize: *table,
+		Rounds:    *rounds,
+		Games:     *games,
+		Seed:      *seed,
+		Variant:   room.RuleVariant(*variant),
+		MaxTurns:  *maxTurns,
+		Workers:   *workers,
+	}
+	for _, name := range strings.Split(*bots, ",") {
+		d := room.BotDifficulty(strings.TrimSpace(name))
+		if !d.Valid() {
+			log.Fatalf("Invalid strategy: %q", name)
+		}
+		cfg.Entrants = append(cfg.Entrants, d)
+	}
+
+	report, err := simulate.RunTournament(cfg)
+	if err != nil {
+		log.Fatalf("Error running the tournament: %v", err)
+	}
+
+	if *jsonOut != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Error encoding the report: %v", err)
+		}
+		if err := os.WriteFile(*jsonOut, data, 0o644); err != nil {
+			log.Fatalf("Error writing the report: %v", err)
+		}
+	}
+
+	if *markdownOut == "" {
+		report.WriteMarkdown(os.Stdout)
+		return
+	}
+	f, err := os.Create(*markdownOut)
+	if err != nil {
+		log.Fatalf("Error writing the report: %v", err)
+	}
+	defer f.Close()
+	report.WriteMarkdown(f)
+}
@@ -0,0 +1,284 @@
+// Package gym is a reinforcement learning environment over the War rules
+// engine, to train agents in process or, through NewEnvHandler, from any
+// language over HTTP.
+package gym
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/ws"
+)
+
+// The environment speaks the external bot protocol, these name its types
+// for code outside the module
+type (
+	Action     = ws.BotAction
+	Attack     = ws.BotAttack
+	Decision   = ws.BotDecision
+	State      = ws.BotView
+	Difficulty = room.BotDifficulty
+	Variant    = room.RuleVariant
+)
+
+const (
+	DecideTrade   = ws.DecideTrade
+	DecideDeploy  = ws.DecideDeploy
+	DecideAttack  = ws.DecideAttack
+	DecideOccupy  = ws.DecideOccupy
+	DecideFortify = ws.DecideFortify
+)
+
+// EnvConfig describes the games of an Env
+type EnvConfig struct {
+	// Strategy of each opponent in turn order, one hard bot when empty
+	Opponents []Difficulty `json:"opponents"`
+	// Seat of the agent in turn order, 0 plays first
+	Seat     int     `json:"seat"`
+	Variant  Variant `json:"variant"`
+	MaxTurns int     `json:"max_turns"` // Turns of every player before a game is cut short, 1000 when zero
+}
+
+// Observation is what the agent sees before each decision, the same a bot
+// sees in the external protocol. Decision is empty once the game ended.
+type Observation struct {
+	Decision Decision `json:"decision"`
+	State    *State   `json:"state"`
+	Attack   *Attack  `json:"attack,omitempty"` // The won attack to occupy after
+}
+
+type StepResult struct {
+	Observation Observation `json:"observation"`
+	// 1 when the agent wins, -1 when it loses and 0 otherwise
+	Reward    float64 `json:"reward"`
+	Done      bool    `json:"done"`      // The game is won or the agent was eliminated
+	Truncated bool    `json:"truncated"` // The game hit MaxTurns first
+}
+
+// Env is a reinforcement learning environment over the rules engine. The
+// agent plays one seat one decision at a time, with the actions of the
+// external bot protocol, and the opponents play their whole turns in
+// between. An Env is not safe for concurrent use.
+type Env struct {
+	cfg      EnvConfig
+	state    *ws.GameState
+	agentID  string
+	rng      *rand.Rand // Decisions of the opponents
+	decision Decision
+	attack   *Attack // Won and waiting to be occupied
+	moves    int     // Fortify moves this turn
+	err      error   // Of an opponent, ends the game
+}
+
+// NewEnv returns an environment for cfg, Reset starts its first game
+func NewEnv(cfg EnvConfig) (*Env, error) {
+	if len(cfg.Opponents) == 0 {
+		cfg.Opponents = []Difficulty{room.BotHard}
+	}
+	if len(cfg.Opponents)+1 > room.MaxPlayerLimit {
+		return nil, fmt.Errorf("at most %d opponents", room.MaxPlayerLimit-1)
+	}
+	for _, d := range cfg.Opponents {
+		if !d.Valid() {
+			return nil, fmt.Errorf("invalid opponent %q", d)
+		}
+	}
+	if cfg.Seat < 0 || cfg.Seat > len(cfg.Opponents) {
+		return nil, fmt.Errorf("seat must be between 0 and %d", len(cfg.Opponents))
+	}
+	if cfg.Variant == "" {
+		cfg.Variant = room.VariantObjectives
+	}
+	if !slices.Contains(room.RuleVariants, cfg.Variant) {
+		return nil, fmt.Errorf("invalid rule variant %q", cfg.Variant)
+	}
+	if cfg.MaxTurns <= 0 {
+		cfg.MaxTurns = 1000
+	}
+	return &Env{cfg: cfg}, nil
+}
+
+// Reset starts a new game seeded with seed and plays the opponents up to the
+// agent's first decision
+func (e *Env) Reset(seed uint64) (Observation, error) {
+	seats := slices.Insert(slices.Clone(e.cfg.Opponents), e.cfg.Seat, "")
+	settings := room.DefaultSettings()
+	settings.MaxPlayers = len(seats)
+	settings.RuleVariant = e.cfg.Variant
+
+	e.state = ws.NewSeededGame(seed, settings, seats)
+	e.agentID = e.state.TurnOrder[e.cfg.Seat]
+	e.rng = rand.New(rand.NewPCG(seed, ^seed))
+	e.decision, e.attack, e.err = "", nil, nil
+
+	e.play()
+	return e.Observation(), e.err
+}
+
+// Observation returns what the agent sees now
+func (e *Env) Observation() Observation {
+	if e.state == nil {
+		return Observation{}
+	}
+	return Observation{Decision: e.decision, State: e.state.BotView(e.agentID), Attack: e.attack}
+}
+
+// LegalActions returns the actions Step accepts now, none once the game
+// ended. Actions with armies allow any number from 1 to theirs.
+func (e *Env) LegalActions() []Action {
+	if e.state == nil || e.decision == "" {
+		return nil
+	}
+	return ws.LegalActions(e.decision, e.state.BotView(e.agentID), e.attack)
+}
+
+// Step plays the agent's action and, when it ends the agent's turn, the
+// opponents' turns up to the agent's next decision. An illegal action is an
+// error and changes nothing.
+func (e *Env) Step(action Action) (StepResult, error) {
+	if e.state == nil || e.decision == "" {
+		return StepResult{}, errors.New("the game is over, reset the environment")
+	}
+	if err := ws.CheckAction(e.decision, e.LegalActions(), action); err != nil {
+		return StepResult{}, err
+	}
+
+	gs, id := e.state, e.agentID
+	switch e.decision {
+	case DecideTrade:
+		if action.Pass {
+			e.next(DecideDeploy)
+			break
+		}
+		if _, err := gs.Trade(id, action.Cards[0], action.Cards[1], action.Cards[2]); err != nil {
+			return StepResult{}, err
+		}
+		e.next(DecideTrade)
+
+	case DecideDeploy:
+		if err := gs.Deploy(id, action.TerritoryID); err != nil {
+			return StepResult{}, err
+		}
+		e.next(DecideDeploy)
+
+	case DecideAttack:
+		if action.Pass {
+			e.next(DecideFortify)
+			break
+		}
+		if _, err := gs.Attack(id, action.From, action.To, action.Armies); err != nil {
+			return StepResult{}, err
+		}
+		attack := Attack{From: action.From, To: action.To, Armies: action.Armies}
+		if view := gs.BotView(id); !e.over() && view.Territory(attack.To).Owner == id &&
+			len(ws.LegalActions(DecideOccupy, view, &attack)) > 0 {
+			e.decision, e.attack = DecideOccupy, &attack
+			break
+		}
+		e.next(DecideAttack)
+
+	case DecideOccupy:
+		if !action.Pass {
+			if err := gs.Move(id, action.From, action.To, action.Armies); err != nil {
+				return StepResult{}, err
+			}
+		}
+		e.attack = nil
+		e.next(DecideAttack)
+
+	case DecideFortify:
+		if action.Pass {
+			e.endTurn()
+			break
+		}
+		if err := gs.Move(id, action.From, action.To, action.Armies); err != nil {
+			return StepResult{}, err
+		}
+		e.moves++
+		e.next(DecideFortify)
+	}
+
+	return e.result(), e.err
+}
+
+// next moves on to the first decision from d the agent has a choice in,
+// ending its turn when there is none
+func (e *Env) next(d Decision) {
+	if e.over() {
+		e.decision = ""
+		return
+	}
+
+	decisions := []Decision{DecideTrade, DecideDeploy, DecideAttack, DecideFortify}
+	view := e.state.BotView(e.agentID)
+	for _, decision := range decisions[slices.Index(decisions, d):] {
+		if decision == DecideFortify && e.moves >= ws.MaxBotDecisions {
+			break
+		}
+		if len(ws.LegalActions(decision, view, nil)) > 0 {
+			e.decision = decision
+			return
+		}
+	}
+	e.endTurn()
+}
+
+// endTurn passes the agent's turn and plays on to its next decision
+func (e *Env) endTurn() {
+	if _, err := e.state.NextTurn(e.agentID); err != nil {
+		e.err = err
+		e.decision = ""
+		return
+	}
+	e.play()
+}
+
+// play plays the opponents' turns until the agent has a decision to make or
+// the game is over
+func (e *Env) play() {
+	for !e.over() {
+		current := e.state.CurrentTurn
+		if current == e.agentID {
+			e.moves = 0
+			e.next(DecideTrade)
+			return
+		}
+
+		difficulty := room.BotDifficulty(e.state.Players[current].BotDifficulty)
+		if err := ws.PlayBotTurn(e.state, ws.NewBotStrategy(difficulty, e.rng)); err != nil {
+			e.err = fmt.Errorf("opponent %s: %w", difficulty, err)
+			break
+		}
+	}
+	e.decision = ""
+}
+
+// over reports whether the game is won, cut short or broken, or the agent
+// lost its last territory
+func (e *Env) over() bool {
+	return e.err != nil || e.state.Winner() != "" || e.state.TurnNumber > e.cfg.MaxTurns || e.eliminated()
+}
+
+func (e *Env) eliminated() bool {
+	return len(e.state.BotView(e.agentID).Owned()) == 0
+}
+
+func (e *Env) result() StepResult {
+	r := StepResult{Observation: e.Observation()}
+	if e.decision != "" {
+		return r
+	}
+
+	switch winner := e.state.Winner(); {
+	case winner == e.agentID:
+		r.Reward, r.Done = 1, true
+	case winner != "" || e.eliminated():
+		r.Reward, r.Done = -1, true
+	default:
+		r.Truncated = true
+	}
+	return r
+}
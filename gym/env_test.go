@@ -0,0 +1,115 @@
+package gym
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+
+	"es2.uff/war-server/internal/domain/room"
+)
+
+// playRandomly plays the agent with random legal actions to the end of the
+// game and returns the last result
+func playRandomly(t *testing.T, env *Env, rng *rand.Rand) StepResult {
+	t.Helper()
+
+	for steps := 0; ; steps++ {
+		if steps == 100000 {
+			t.Fatal("game not over after 100000 steps")
+		}
+		actions := env.LegalActions()
+		if len(actions) == 0 {
+			t.Fatalf("no legal actions at decision %q", env.Observation().Decision)
+		}
+		action := actions[rng.IntN(len(actions))]
+		if action.Armies > 0 {
+			action.Armies = 1 + rng.IntN(action.Armies)
+		}
+
+		result, err := env.Step(action)
+		if err != nil {
+			t.Fatalf("Step(%+v) error = %v", action, err)
+		}
+		if result.Done || result.Truncated {
+			return result
+		}
+	}
+}
+
+func TestEnv_PlaysToTheEnd(t *testing.T) {
+	env, err := NewEnv(EnvConfig{Opponents: []Difficulty{room.BotMedium, room.BotEasy}, Seat: 1})
+	if err != nil {
+		t.Fatalf("NewEnv() error = %v", err)
+	}
+
+	obs, err := env.Reset(3)
+	if err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if obs.Decision == "" || obs.State.BotID != env.state.TurnOrder[1] {
+		t.Fatalf("first observation = %+v, want a decision of the second seat", obs)
+	}
+
+	result := playRandomly(t, env, rand.New(rand.NewPCG(1, 2)))
+	if result.Observation.Decision != "" || len(env.LegalActions()) != 0 {
+		t.Errorf("ended game still has decision %q", result.Observation.Decision)
+	}
+	if result.Done && result.Reward == 0 {
+		t.Error("game ended without a reward")
+	}
+	if _, err := env.Step(Action{Pass: true}); err == nil {
+		t.Error("Step() after the end error = nil")
+	}
+}
+
+func TestEnv_ResetRepeatsForASeed(t *testing.T) {
+	env, _ := NewEnv(EnvConfig{Opponents: []Difficulty{room.BotHard}, Seat: 1})
+
+	first, _ := env.Reset(9)
+	again, _ := env.Reset(9)
+	if !reflect.DeepEqual(first, again) {
+		t.Error("seed 9 dealt two different games")
+	}
+}
+
+func TestEnv_RejectsIllegalActions(t *testing.T) {
+	env, _ := NewEnv(EnvConfig{})
+	obs, _ := env.Reset(1)
+	if obs.Decision != DecideDeploy {
+		t.Fatalf("first decision = %q, want deploy", obs.Decision)
+	}
+
+	var enemy string
+	for _, t := range obs.State.Territories {
+		if t.Owner != obs.State.BotID {
+			enemy = t.ID
+			break
+		}
+	}
+	if _, err := env.Step(Action{TerritoryID: enemy}); err == nil {
+		t.Error("deploying on an enemy territory error = nil")
+	}
+	if got := env.Observation(); !reflect.DeepEqual(got, obs) {
+		t.Error("an illegal action changed the game")
+	}
+}
+
+func TestNewEnv_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  EnvConfig
+	}{
+		{"Unknown opponent", EnvConfig{Opponents: []Difficulty{"grandmaster"}}},
+		{"Seat past the table", EnvConfig{Opponents: []Difficulty{room.BotEasy}, Seat: 2}},
+		{"Too many opponents", EnvConfig{Opponents: []Difficulty{"easy", "easy", "easy", "easy", "easy", "easy"}}},
+		{"Unknown variant", EnvConfig{Variant: "capture_the_flag"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEnv(tt.cfg); err == nil {
+				t.Error("NewEnv() error = nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package gym
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// envReply is the body of every environment response, the legal actions
+// come along so the agent needs no second request
+type envReply struct {
+	ID string `json:"id"`
+	StepResult
+	Actions []Action `json:"actions"`
+}
+
+// envSession is an environment behind the HTTP API, which serves one
+// request of it at a time
+type envSession struct {
+	mu  sync.Mutex
+	env *Env
+}
+
+// NewEnvHandler serves environments over HTTP and JSON:
+//
+//	POST   /envs             creates one from an EnvConfig and returns its id
+//	POST   /envs/{id}/reset  starts a game from {"seed": n}
+//	POST   /envs/{id}/step   plays an Action
+//	GET    /envs/{id}        returns the observation and legal actions
+//	DELETE /envs/{id}        drops it
+//
+// The environments live in memory until they are dropped.
+func NewEnvHandler() http.Handler {
+	var (
+		mu   sync.Mutex
+		envs = make(map[string]*envSession)
+	)
+
+	session := func(w http.ResponseWriter, r *http.Request) *envSession {
+		mu.Lock()
+		defer mu.Unlock()
+		s := envs[r.PathValue("id")]
+		if s == nil {
+			http.Error(w, "Environment not found", http.StatusNotFound)
+		}
+		return s
+	}
+
+	reply := func(w http.ResponseWriter, id string, s *envSession, result StepResult) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(envReply{ID: id, StepResult: result, Actions: s.env.LegalActions()})
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /envs", func(w http.ResponseWriter, r *http.Request) {
+		var cfg EnvConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			http.Error(w, "Invalid config", http.StatusBadRequest)
+			return
+		}
+		env, err := NewEnv(cfg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		id := uuid.NewString()
+		s := &envSession{env: env}
+		mu.Lock()
+		envs[id] = s
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		reply(w, id, s, StepResult{})
+	})
+
+	mux.HandleFunc("POST /envs/{id}/reset", func(w http.ResponseWriter, r *http.Request) {
+		s := session(w, r)
+		if s == nil {
+			return
+		}
+		var req struct {
+			Seed uint64 `json:"seed"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, err := s.env.Reset(req.Seed); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		reply(w, r.PathValue("id"), s, s.env.result())
+	})
+
+	mux.HandleFunc("POST /envs/{id}/step", func(w http.ResponseWriter, r *http.Request) {
+		s := session(w, r)
+		if s == nil {
+			return
+		}
+		var action Action
+		if err := json.NewDecoder(r.Body).Decode(&action); err != nil {
+			http.Error(w, "Invalid action", http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		result, err := s.env.Step(action)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		reply(w, r.PathValue("id"), s, result)
+	})
+
+	mux.HandleFunc("GET /envs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s := session(w, r)
+		if s == nil {
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.env.state == nil {
+			reply(w, r.PathValue("id"), s, StepResult{})
+			return
+		}
+		reply(w, r.PathValue("id"), s, s.env.result())
+	})
+
+	mux.HandleFunc("DELETE /envs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		delete(envs, r.PathValue("id"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
@@ -0,0 +1,49 @@
+package gym
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"es2.uff/war-server/internal/domain/room"
+)
+
+func TestEnvHandler(t *testing.T) {
+	server := httptest.NewServer(NewEnvHandler())
+	t.Cleanup(server.Close)
+
+	post := func(path string, body any) (envReply, int) {
+		data, _ := json.Marshal(body)
+		resp, err := http.Post(server.URL+path, "application/json", bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("POST %s error = %v", path, err)
+		}
+		defer resp.Body.Close()
+		var reply envReply
+		json.NewDecoder(resp.Body).Decode(&reply)
+		return reply, resp.StatusCode
+	}
+
+	created, status := post("/envs", EnvConfig{Opponents: []Difficulty{room.BotEasy}})
+	if status != http.StatusCreated || created.ID == "" {
+		t.Fatalf("create = %d %+v", status, created)
+	}
+
+	reset, status := post("/envs/"+created.ID+"/reset", map[string]uint64{"seed": 4})
+	if status != http.StatusOK || reset.Observation.Decision != DecideDeploy || len(reset.Actions) == 0 {
+		t.Fatalf("reset = %d %+v, want a deploy decision with actions", status, reset)
+	}
+
+	step, status := post("/envs/"+created.ID+"/step", reset.Actions[0])
+	if status != http.StatusOK || step.Observation.State == nil {
+		t.Errorf("step = %d %+v", status, step)
+	}
+	if _, status := post("/envs/"+created.ID+"/step", Action{TerritoryID: "nowhere"}); status != http.StatusUnprocessableEntity {
+		t.Errorf("illegal step status = %d, want %d", status, http.StatusUnprocessableEntity)
+	}
+	if _, status := post("/envs/missing/step", Action{Pass: true}); status != http.StatusNotFound {
+		t.Errorf("missing environment status = %d, want %d", status, http.StatusNotFound)
+	}
+}
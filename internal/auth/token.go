@@ -0,0 +1,165 @@
+// Package auth issues and checks the session tokens players use to identify
+// themselves. Tokens are HS256 JSON Web Tokens signed with a server key.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// header is the same for every token, so it is encoded once
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type Claims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	AuthTime  int64  `json:"auth_time"` // When the session started, kept across refreshes
+}
+
+type TokenManager struct {
+	key        []byte
+	ttl        time.Duration
+	maxSession time.Duration
+	now        func() time.Time
+}
+
+// NewTokenManager signs tokens with key, each one valid for ttl. Tokens are
+// refreshed until maxSession after the player first got one.
+func NewTokenManager(key []byte, ttl time.Duration, maxSession time.Duration) *TokenManager {
+	return &TokenManager{
+		key:        key,
+		ttl:        ttl,
+		maxSession: maxSession,
+		now:        time.Now,
+	}
+}
+
+// NewKey returns a random signing key, for servers started without one.
+// Tokens signed with it stop working when the server restarts.
+func NewKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Issue returns a token starting a new session for the player and when it
+// expires
+func (tm *TokenManager) Issue(playerID uuid.UUID) (string, time.Time, error) {
+	return tm.issue(playerID, tm.now().Unix())
+}
+
+func (tm *TokenManager) issue(playerID uuid.UUID, authTime int64) (string, time.Time, error) {
+	now := tm.now()
+	expiresAt := now.Add(tm.ttl)
+
+	payload, err := json.Marshal(Claims{
+		Subject:   playerID.String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		AuthTime:  authTime,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + tm.sign(unsigned), time.Unix(expiresAt.Unix(), 0), nil
+}
+
+// Verify checks the token's signature and expiry and returns the player it
+// was issued to
+func (tm *TokenManager) Verify(token string) (uuid.UUID, error) {
+	claims, err := tm.parse(token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if tm.now().Unix() >= claims.ExpiresAt {
+		return uuid.Nil, ErrExpiredToken
+	}
+	return uuid.Parse(claims.Subject)
+}
+
+// Refresh issues a new token for the player of a valid token. Expired tokens
+// are still accepted for as long as they were valid, so a player coming back
+// after a while keeps their identity. The new token belongs to the same
+// session, which ends maxSession after it started.
+func (tm *TokenManager) Refresh(token string) (string, time.Time, error) {
+	claims, err := tm.parse(token)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := tm.now()
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(tm.ttl)) {
+		return "", time.Time{}, ErrExpiredToken
+	}
+
+	// Tokens issued before sessions were tracked start theirs when issued
+	authTime := claims.AuthTime
+	if authTime == 0 {
+		authTime = claims.IssuedAt
+	}
+	if now.After(time.Unix(authTime, 0).Add(tm.maxSession)) {
+		return "", time.Time{}, ErrExpiredToken
+	}
+
+	playerID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidToken
+	}
+	return tm.issue(playerID, authTime)
+}
+
+func (tm *TokenManager) parse(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(tm.sign(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, expected) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims := new(Claims)
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if _, err := uuid.Parse(claims.Subject); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func (tm *TokenManager) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, tm.key)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
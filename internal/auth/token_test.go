@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestManager(now *time.Time) *TokenManager {
+	tm := NewTokenManager([]byte("test-key"), time.Hour, 4*time.Hour)
+	tm.now = func() time.Time { return *now }
+	return tm
+}
+
+func TestTokenManager_IssueAndVerify(t *testing.T) {
+	now := time.Now()
+	tm := newTestManager(&now)
+	playerID := uuid.New()
+
+	token, expiresAt, err := tm.Issue(playerID)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if expiresAt.Sub(now) > time.Hour || expiresAt.Sub(now) < time.Hour-time.Second {
+		t.Errorf("Issue() expires at %v, want an hour from now", expiresAt)
+	}
+
+	got, err := tm.Verify(token)
+	if err != nil || got != playerID {
+		t.Errorf("Verify() = %v, %v, want %v", got, err, playerID)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := tm.Verify(token); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Verify() of an expired token error = %v, want %v", err, ErrExpiredToken)
+	}
+}
+
+func TestTokenManager_RejectsForgedTokens(t *testing.T) {
+	now := time.Now()
+	tm := newTestManager(&now)
+	token, _, _ := tm.Issue(uuid.New())
+
+	other := NewTokenManager([]byte("other-key"), time.Hour, 4*time.Hour)
+	otherToken, _, _ := other.Issue(uuid.New())
+
+	parts := strings.Split(token, ".")
+	forged := parts[0] + "." + strings.Split(otherToken, ".")[1] + "." + parts[2]
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"garbage", "not.a.token"},
+		{"other key", otherToken},
+		{"swapped claims", forged},
+		{"no signature", parts[0] + "." + parts[1] + "."},
+		{"unsigned", `eyJhbGciOiJub25lIn0.` + parts[1] + "."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tm.Verify(tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestTokenManager_Refresh(t *testing.T) {
+	now := time.Now()
+	tm := newTestManager(&now)
+	playerID := uuid.New()
+	token, _, _ := tm.Issue(playerID)
+
+	// An expired token can still be refreshed for a while
+	now = now.Add(90 * time.Minute)
+	refreshed, _, err := tm.Refresh(token)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if got, err := tm.Verify(refreshed); err != nil || got != playerID {
+		t.Errorf("Verify() of the refreshed token = %v, %v, want %v", got, err, playerID)
+	}
+
+	now = now.Add(time.Hour)
+	if _, _, err := tm.Refresh(token); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Refresh() of a long expired token error = %v, want %v", err, ErrExpiredToken)
+	}
+}
+
+func TestTokenManager_RefreshEndsWithTheSession(t *testing.T) {
+	now := time.Now()
+	tm := newTestManager(&now)
+	playerID := uuid.New()
+	token, _, _ := tm.Issue(playerID)
+
+	// Refreshing every 50 minutes keeps the session alive for 4 hours only
+	for range 4 {
+		now = now.Add(50 * time.Minute)
+		var err error
+		if token, _, err = tm.Refresh(token); err != nil {
+			t.Fatalf("Refresh() at %v error = %v", now, err)
+		}
+	}
+
+	now = now.Add(50 * time.Minute)
+	if _, _, err := tm.Refresh(token); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Refresh() after the session ended error = %v, want %v", err, ErrExpiredToken)
+	}
+	if _, err := tm.Verify(token); err != nil {
+		t.Errorf("Verify() of the last refreshed token error = %v", err)
+	}
+
+	// Signing in again starts a new session
+	fresh, _, _ := tm.Issue(playerID)
+	if _, _, err := tm.Refresh(fresh); err != nil {
+		t.Errorf("Refresh() of a new session error = %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package bus
+
+import "time"
+
+// Bus carries messages between server instances. Delivery is at most once,
+// a subscriber that falls behind loses messages.
+type Bus interface {
+	Publish(topic string, payload []byte) error
+	// Subscribe starts receiving the messages published to topic from the
+	// moment it returns
+	Subscribe(topic string) (Subscription, error)
+}
+
+type Subscription interface {
+	Messages() <-chan []byte
+	Close() error
+}
+
+// Leases grant ownership of a key to a single instance at a time. A lease
+// expires unless its owner renews it before the ttl runs out.
+type Leases interface {
+	// Acquire takes the lease on key for owner, or renews it if owner already
+	// holds it. It returns false while another owner holds the lease.
+	Acquire(key string, owner string, ttl time.Duration) (bool, error)
+	// Release gives up the lease if owner still holds it
+	Release(key string, owner string) error
+}
+
+// Buffered messages per subscription before new ones are dropped
+const subscriptionBuffer = 256
@@ -0,0 +1,130 @@
+package bus
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis uses the Redis at REDIS_HOST when it is set, as in CI, and an
+// in-process stand-in otherwise. Keys are namespaced by the returned prefix.
+func newTestRedis(t *testing.T) (*redis.Client, string) {
+	t.Helper()
+
+	addr := ""
+	if host := os.Getenv("REDIS_HOST"); host != "" {
+		port := os.Getenv("REDIS_PORT")
+		if port == "" {
+			port = "6379"
+		}
+		addr = host + ":" + port
+	} else {
+		addr = miniredis.RunT(t).Addr()
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+
+	return client, "war-test:" + uuid.NewString() + ":"
+}
+
+func receive(t *testing.T, sub Subscription) string {
+	t.Helper()
+
+	select {
+	case msg := <-sub.Messages():
+		return string(msg)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+		return ""
+	}
+}
+
+func testBus(t *testing.T, b Bus) {
+	first, err := b.Subscribe("room:1")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	second, _ := b.Subscribe("room:1")
+	other, _ := b.Subscribe("room:2")
+	defer other.Close()
+
+	if err := b.Publish("room:1", []byte("hello")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	if got := receive(t, first); got != "hello" {
+		t.Errorf("first subscriber got %q, want hello", got)
+	}
+	if got := receive(t, second); got != "hello" {
+		t.Errorf("second subscriber got %q, want hello", got)
+	}
+
+	first.Close()
+	second.Close()
+
+	if err := b.Publish("room:2", []byte("bye")); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if got := receive(t, other); got != "bye" {
+		t.Errorf("subscriber of another topic got %q, want bye", got)
+	}
+}
+
+func testLeases(t *testing.T, l Leases) {
+	ttl := 200 * time.Millisecond
+
+	if ok, err := l.Acquire("game:1", "node-a", ttl); err != nil || !ok {
+		t.Fatalf("Acquire() of a free lease = %v, %v, want true", ok, err)
+	}
+	if ok, _ := l.Acquire("game:1", "node-b", ttl); ok {
+		t.Error("Acquire() of a held lease = true, want false")
+	}
+	if ok, _ := l.Acquire("game:1", "node-a", ttl); !ok {
+		t.Error("Acquire() by the owner = false, want the lease renewed")
+	}
+
+	// Releasing someone else's lease does nothing
+	_ = l.Release("game:1", "node-b")
+	if ok, _ := l.Acquire("game:1", "node-b", ttl); ok {
+		t.Error("Acquire() after a foreign release = true, want false")
+	}
+
+	_ = l.Release("game:1", "node-a")
+	if ok, _ := l.Acquire("game:1", "node-b", ttl); !ok {
+		t.Error("Acquire() after release = false, want true")
+	}
+}
+
+func TestMemoryBus(t *testing.T) {
+	testBus(t, NewMemoryBus())
+}
+
+func TestMemoryLeases(t *testing.T) {
+	testLeases(t, NewMemoryLeases())
+}
+
+func TestMemoryLeases_Expire(t *testing.T) {
+	l := NewMemoryLeases()
+
+	_, _ = l.Acquire("game:1", "node-a", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if ok, _ := l.Acquire("game:1", "node-b", time.Second); !ok {
+		t.Error("Acquire() of an expired lease = false, want true")
+	}
+}
+
+func TestRedisBus(t *testing.T) {
+	client, prefix := newTestRedis(t)
+	testBus(t, NewRedisBus(client, prefix))
+}
+
+func TestRedisLeases(t *testing.T) {
+	client, prefix := newTestRedis(t)
+	testLeases(t, NewRedisLeases(client, prefix))
+}
@@ -0,0 +1,111 @@
+package bus
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// MemoryBus delivers messages between subscribers of a single process
+type MemoryBus struct {
+	sync.RWMutex
+	subscribers map[string]map[*memorySubscription]bool
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		subscribers: make(map[string]map[*memorySubscription]bool),
+	}
+}
+
+func (b *MemoryBus) Publish(topic string, payload []byte) error {
+	b.RLock()
+	defer b.RUnlock()
+
+	for sub := range b.subscribers[topic] {
+		select {
+		case sub.messages <- payload:
+		default:
+			log.Printf("Dropping message on topic %s, subscriber is full", topic)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(topic string) (Subscription, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	sub := &memorySubscription{
+		bus:      b,
+		topic:    topic,
+		messages: make(chan []byte, subscriptionBuffer),
+	}
+
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = make(map[*memorySubscription]bool)
+	}
+	b.subscribers[topic][sub] = true
+
+	return sub, nil
+}
+
+type memorySubscription struct {
+	bus      *MemoryBus
+	topic    string
+	messages chan []byte
+}
+
+func (s *memorySubscription) Messages() <-chan []byte {
+	return s.messages
+}
+
+func (s *memorySubscription) Close() error {
+	s.bus.Lock()
+	defer s.bus.Unlock()
+
+	delete(s.bus.subscribers[s.topic], s)
+	if len(s.bus.subscribers[s.topic]) == 0 {
+		delete(s.bus.subscribers, s.topic)
+	}
+	return nil
+}
+
+type memoryLease struct {
+	owner   string
+	expires time.Time
+}
+
+type MemoryLeases struct {
+	sync.Mutex
+	leases map[string]memoryLease
+}
+
+func NewMemoryLeases() *MemoryLeases {
+	return &MemoryLeases{
+		leases: make(map[string]memoryLease),
+	}
+}
+
+func (l *MemoryLeases) Acquire(key string, owner string, ttl time.Duration) (bool, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	if current, held := l.leases[key]; held && current.owner != owner && now.Before(current.expires) {
+		return false, nil
+	}
+
+	l.leases[key] = memoryLease{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (l *MemoryLeases) Release(key string, owner string) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if current, held := l.leases[key]; held && current.owner == owner {
+		delete(l.leases, key)
+	}
+	return nil
+}
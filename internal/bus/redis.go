@@ -0,0 +1,121 @@
+package bus
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBus publishes messages over Redis pub/sub so every replica connected
+// to the same Redis receives them
+type RedisBus struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisBus(client *redis.Client, prefix string) *RedisBus {
+	return &RedisBus{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (b *RedisBus) Publish(topic string, payload []byte) error {
+	return b.client.Publish(context.Background(), b.prefix+topic, payload).Err()
+}
+
+func (b *RedisBus) Subscribe(topic string) (Subscription, error) {
+	ctx := context.Background()
+	pubsub := b.client.Subscribe(ctx, b.prefix+topic)
+
+	// Wait for Redis to confirm so no message published afterwards is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	sub := &redisSubscription{
+		pubsub:   pubsub,
+		messages: make(chan []byte, subscriptionBuffer),
+	}
+	go sub.forward()
+
+	return sub, nil
+}
+
+type redisSubscription struct {
+	pubsub   *redis.PubSub
+	messages chan []byte
+}
+
+// forward copies payloads from Redis until the subscription is closed
+func (s *redisSubscription) forward() {
+	defer close(s.messages)
+
+	for msg := range s.pubsub.Channel(redis.WithChannelSize(subscriptionBuffer)) {
+		select {
+		case s.messages <- []byte(msg.Payload):
+		default:
+			log.Printf("Dropping message on channel %s, subscriber is full", msg.Channel)
+		}
+	}
+}
+
+func (s *redisSubscription) Messages() <-chan []byte {
+	return s.messages
+}
+
+func (s *redisSubscription) Close() error {
+	return s.pubsub.Close()
+}
+
+// Takes the lease when it is free and extends it when owner already holds it
+var acquireScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if current == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+if current then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisLeases keeps each lease as a key holding its owner, expiring with the
+// lease's ttl
+type RedisLeases struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisLeases(client *redis.Client, prefix string) *RedisLeases {
+	return &RedisLeases{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (l *RedisLeases) Acquire(key string, owner string, ttl time.Duration) (bool, error) {
+	acquired, err := acquireScript.Run(
+		context.Background(), l.client, []string{l.prefix + "lease:" + key}, owner, ttl.Milliseconds(),
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return acquired == 1, nil
+}
+
+func (l *RedisLeases) Release(key string, owner string) error {
+	return releaseScript.Run(context.Background(), l.client, []string{l.prefix + "lease:" + key}, owner).Err()
+}
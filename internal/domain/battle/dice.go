@@ -4,10 +4,20 @@ import "math/rand/v2"
 
 // RollDice simulates rolling n dice and returns sorted results (highest first)
 func RollDice(n int) []int {
+	return rollDice(rand.IntN, n)
+}
+
+// RollDiceWith rolls n dice drawn from r, so the rolls can be reproduced
+// from the state of its source
+func RollDiceWith(r *rand.Rand, n int) []int {
+	return rollDice(r.IntN, n)
+}
+
+func rollDice(intN func(int) int, n int) []int {
 	dice := make([]int, n)
 
 	for i := range n {
-		dice[i] = rand.IntN(6) + 1
+		dice[i] = intN(6) + 1
 	}
 
 	// Sort descending
@@ -1,6 +1,10 @@
 package battle
 
-import "testing"
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
 
 func TestRollDice(t *testing.T) {
 	tests := []struct {
@@ -110,3 +114,15 @@ func TestCompareDice_UnequalDice(t *testing.T) {
 		t.Errorf("CompareDice() defenderLosses = %d, want 1", defenderLosses)
 	}
 }
+
+func TestRollDiceWith_Reproducible(t *testing.T) {
+	source := rand.NewPCG(1, 2)
+	first := RollDiceWith(rand.New(source), 3)
+
+	source.Seed(1, 2)
+	second := RollDiceWith(rand.New(source), 3)
+
+	if !slices.Equal(first, second) {
+		t.Errorf("RollDiceWith() = %v then %v from the same seed", first, second)
+	}
+}
@@ -2,6 +2,7 @@ package card
 
 import (
 	"math/rand"
+	randv2 "math/rand/v2"
 
 	"es2.uff/war-server/internal/domain/territory"
 )
@@ -39,6 +40,14 @@ func (d *Deck) Shuffle() {
 	})
 }
 
+// ShuffleWith shuffles the deck with rng, for games that must play out the
+// same for a seed
+func (d *Deck) ShuffleWith(rng *randv2.Rand) {
+	rng.Shuffle(len(d.Cards), func(i, j int) {
+		d.Cards[i], d.Cards[j] = d.Cards[j], d.Cards[i]
+	})
+}
+
 func (d *Deck) Draw() *Card {
 	if len(d.Cards) == 0 {
 		return nil
@@ -189,3 +189,49 @@ func TestDeck_Size(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSet(t *testing.T) {
+	circle1 := Card{TerritoryID: territory.Brazil, TerritoryName: "Brasil", Shape: territory.Circle}
+	circle2 := Card{TerritoryID: territory.Chile, TerritoryName: "Chile", Shape: territory.Circle}
+	circle3 := Card{TerritoryID: territory.Mexico, TerritoryName: "México", Shape: territory.Circle}
+	square := Card{TerritoryID: territory.Egypt, TerritoryName: "Egito", Shape: territory.Square}
+
+	tests := []struct {
+		name    string
+		cards   []Card
+		wantErr bool
+	}{
+		{"Same shape", []Card{circle1, circle2, circle3}, false},
+		{"Mixed shapes", []Card{circle1, circle2, square}, true},
+		{"Same card twice", []Card{circle1, circle1, circle2}, true},
+		{"Two cards", []Card{circle1, circle2}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSet(tt.cards)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindSet(t *testing.T) {
+	hand := []Card{
+		{TerritoryID: territory.Brazil, TerritoryName: "Brasil", Shape: territory.Circle},
+		{TerritoryID: territory.Egypt, TerritoryName: "Egito", Shape: territory.Square},
+		{TerritoryID: territory.Chile, TerritoryName: "Chile", Shape: territory.Circle},
+		{TerritoryID: territory.Sudan, TerritoryName: "Sudão", Shape: territory.Square},
+	}
+
+	if set, ok := FindSet(hand); ok {
+		t.Errorf("FindSet() = %v, want no set in two pairs", set)
+	}
+
+	hand = append(hand, Card{TerritoryID: territory.Congo, TerritoryName: "Congo", Shape: territory.Square})
+	set, ok := FindSet(hand)
+	if !ok || ValidateSet(set) != nil || set[0].Shape != territory.Square {
+		t.Errorf("FindSet() = %v, %v, want the three squares", set, ok)
+	}
+}
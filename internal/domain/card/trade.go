@@ -0,0 +1,57 @@
+package card
+
+import "fmt"
+
+const (
+	// SetSize is how many cards are traded at once
+	SetSize = 3
+
+	// MaxHand is the hand size at which a player must trade
+	MaxHand = 5
+)
+
+// ValidateSet checks that cards can be traded together: three different
+// cards of the same shape
+func ValidateSet(cards []Card) error {
+	if len(cards) != SetSize {
+		return fmt.Errorf("must trade exactly %d cards", SetSize)
+	}
+
+	for i, c := range cards {
+		if c.Shape != cards[0].Shape {
+			return fmt.Errorf("all cards must have the same shape")
+		}
+		for _, other := range cards[:i] {
+			if other.TerritoryName == c.TerritoryName {
+				return fmt.Errorf("card %s is traded twice", c.TerritoryName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// FindSet returns the first set in hand that can be traded
+func FindSet(hand []Card) ([]Card, bool) {
+	sets := Sets(hand)
+	if len(sets) == 0 {
+		return nil, false
+	}
+	return sets[0], true
+}
+
+// Sets returns every set in hand that can be traded, in hand order
+func Sets(hand []Card) [][]Card {
+	sets := [][]Card{}
+	for i := range hand {
+		for j := i + 1; j < len(hand); j++ {
+			for k := j + 1; k < len(hand); k++ {
+				set := []Card{hand[i], hand[j], hand[k]}
+				if ValidateSet(set) == nil {
+					sets = append(sets, set)
+				}
+			}
+		}
+	}
+	return sets
+}
@@ -16,7 +16,9 @@ type Game struct {
 	TerritoriesList []int
 }
 
-func InstantiateGameTerritories(players []*player.Player) []*territory.Territory {
+// InstantiateGameTerritories deals the territories to the players in turn,
+// each one drawn at random with rng
+func InstantiateGameTerritories(players []*player.Player, rng *rand.Rand) []*territory.Territory {
 	var tl []*territory.Territory
 
 	for _, territoryID := range territory.AllTerritories {
@@ -35,7 +37,7 @@ func InstantiateGameTerritories(players []*player.Player) []*territory.Territory
 	copy(copiedTerritoriesList, tl)
 
 	for len(copiedTerritoriesList) > 0 {
-		randomIndex := rand.IntN(len(copiedTerritoriesList))
+		randomIndex := rng.IntN(len(copiedTerritoriesList))
 		randomTerritory := copiedTerritoriesList[randomIndex]
 
 		randomTerritory.OwnerID = players[playerIterator].ID
@@ -56,12 +58,14 @@ func InstantiateGameTerritories(players []*player.Player) []*territory.Territory
 	return tl
 }
 
-func AssignObjectivesToPlayers(players []*player.Player) {
+// AssignObjectivesToPlayers gives each player a different objective drawn
+// with rng
+func AssignObjectivesToPlayers(players []*player.Player, rng *rand.Rand) {
 	availableObjectives := make([]objective.ObjectiveID, len(objective.AllObjectives))
 	copy(availableObjectives, objective.AllObjectives)
 
 	for _, p := range players {
-		randomIndex := rand.IntN(len(availableObjectives))
+		randomIndex := rng.IntN(len(availableObjectives))
 		randomObjective := availableObjectives[randomIndex]
 
 		p.ObjectiveID = randomObjective
@@ -0,0 +1,64 @@
+package objective
+
+import (
+	"slices"
+
+	"es2.uff/war-server/internal/domain/territory"
+)
+
+// Holding is a territory held by a player
+type Holding struct {
+	Region territory.Region
+	Armies int
+}
+
+var allRegions = []territory.Region{
+	territory.Europe,
+	territory.Asia,
+	territory.Africa,
+	territory.Oceania,
+	territory.SouthAmerica,
+	territory.NorthAmerica,
+}
+
+// IsCompleted reports whether a player holding these territories fulfils the
+// objective
+func (o Objective) IsCompleted(holdings []Holding) bool {
+	switch o.Type {
+	case TerritoryCount:
+		count := 0
+		for _, h := range holdings {
+			if h.Armies >= o.MinArmiesPerTerritory {
+				count++
+			}
+		}
+		return count >= o.RequiredTerritoryCount
+
+	case RegionConquest:
+		held := make(map[territory.Region]int)
+		for _, h := range holdings {
+			held[h.Region]++
+		}
+		conquered := func(r territory.Region) bool {
+			return held[r] == territory.RegionSize(r)
+		}
+
+		for _, r := range o.RequiredRegions {
+			if !conquered(r) {
+				return false
+			}
+		}
+
+		if !o.RequiresAdditionalRegion {
+			return true
+		}
+
+		for _, r := range allRegions {
+			if !slices.Contains(o.RequiredRegions, r) && conquered(r) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+package objective
+
+import (
+	"testing"
+
+	"es2.uff/war-server/internal/domain/territory"
+)
+
+// holdRegions returns every territory of the given regions with armies each
+func holdRegions(armies int, regions ...territory.Region) []Holding {
+	var holdings []Holding
+	for _, region := range regions {
+		for range territory.RegionSize(region) {
+			holdings = append(holdings, Holding{Region: region, Armies: armies})
+		}
+	}
+	return holdings
+}
+
+func TestObjective_IsCompleted(t *testing.T) {
+	tests := []struct {
+		name      string
+		objective ObjectiveID
+		holdings  []Holding
+		want      bool
+	}{
+		{"Both regions", ConquerAsiaAfrica, holdRegions(1, territory.Asia, territory.Africa), true},
+		{"Missing a region", ConquerAsiaAfrica, holdRegions(1, territory.Asia), false},
+		{"Region partially held", ConquerAsiaAfrica, holdRegions(1, territory.Asia, territory.Africa)[1:], false},
+		{"Third region missing", ConquerEuropeOceaniaAndOne, holdRegions(1, territory.Europe, territory.Oceania), false},
+		{"Third region held", ConquerEuropeOceaniaAndOne, holdRegions(1, territory.Europe, territory.Oceania, territory.Africa), true},
+		{"24 territories", Conquer24Territories, make([]Holding, 24), false},
+		{"24 territories with armies", Conquer24Territories, holdRegions(1, territory.Asia, territory.Europe, territory.Africa)[:24], true},
+		{"18 territories with one army", Conquer18TerritoriesWith2Armies, holdRegions(1, territory.Asia, territory.Europe)[:18], false},
+		{"18 territories with two armies", Conquer18TerritoriesWith2Armies, holdRegions(2, territory.Asia, territory.Europe)[:18], true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ObjectiveDetails[tt.objective].IsCompleted(tt.holdings); got != tt.want {
+				t.Errorf("IsCompleted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package player
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores anything past 72 bytes
+	maxPasswordLength = 72
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,20}$`)
+
+// Account is the login of a registered player. Guests have a player but no
+// account, registering gives their player a stable identity.
+type Account struct {
+	PlayerID     uuid.UUID
+	Username     string
+	PasswordHash []byte
+	CreatedAt    time.Time
+}
+
+func NewAccount(playerID uuid.UUID, username, password string) (*Account, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("username must have 3 to 20 letters, digits or . _ -")
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return nil, fmt.Errorf("password must have %d to %d characters", minPasswordLength, maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Account{
+		PlayerID:     playerID,
+		Username:     username,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// NormalizeUsername makes usernames unique regardless of case
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func (a *Account) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword(a.PasswordHash, []byte(password)) == nil
+}
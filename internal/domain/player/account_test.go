@@ -0,0 +1,50 @@
+package player
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  bool
+	}{
+		{"Valid", "alice_01", "correct horse", false},
+		{"Short username", "al", "correct horse", true},
+		{"Long username", strings.Repeat("a", 21), "correct horse", true},
+		{"Spaces in username", "alice smith", "correct horse", true},
+		{"Short password", "alice", "short", true},
+		{"Long password", "alice", strings.Repeat("p", 73), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAccount(uuid.New(), tt.username, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewAccount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAccount_CheckPassword(t *testing.T) {
+	a, err := NewAccount(uuid.New(), "alice", "correct horse")
+	if err != nil {
+		t.Fatalf("NewAccount() error = %v", err)
+	}
+
+	if string(a.PasswordHash) == "correct horse" {
+		t.Error("NewAccount() stored the password in plain text")
+	}
+	if !a.CheckPassword("correct horse") {
+		t.Error("CheckPassword() rejected the right password")
+	}
+	if a.CheckPassword("battery staple") {
+		t.Error("CheckPassword() accepted a wrong password")
+	}
+}
@@ -0,0 +1,10 @@
+package player
+
+import "slices"
+
+// Palette holds the colors available to players, one per seat
+var Palette = []string{"#FF0000", "#0066FF", "#00CC00", "#FFD700", "#9933FF", "#FF6600"}
+
+func IsValidColor(color string) bool {
+	return slices.Contains(Palette, color)
+}
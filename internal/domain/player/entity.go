@@ -5,12 +5,10 @@ import (
 	"github.com/google/uuid"
 )
 
-var Players []*Player
-
 type Player struct {
 	ID    uuid.UUID
 	Name  string
-	Color string
+	Color string // Preferred color, empty until the player picks one
 
 	ArmyLeft    int
 	ObjectiveID objective.ObjectiveID
@@ -18,20 +16,9 @@ type Player struct {
 
 func NewPlayer(name string) (*Player, error) {
 	newPlayer := &Player{
-		ID:    uuid.New(),
-		Name:  name,
-		Color: "#fff",
+		ID:   uuid.New(),
+		Name: name,
 	}
 
-	Players = append(Players, newPlayer)
 	return newPlayer, nil
 }
-
-func GetPlayer(playerID uuid.UUID) *Player {
-	for _, player := range Players {
-		if player.ID == playerID {
-			return player
-		}
-	}
-	return nil
-}
@@ -0,0 +1,96 @@
+package room
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Invite codes skip characters that are easily confused (0/O, 1/I/L)
+const (
+	inviteCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	inviteCodeLength   = 6
+)
+
+// GenerateInviteCode returns a random invite code, uniqueness is enforced by
+// the room repository
+func GenerateInviteCode() string {
+	size := big.NewInt(int64(len(inviteCodeAlphabet)))
+	code := make([]byte, inviteCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			panic(fmt.Sprintf("crypto/rand failed: %v", err))
+		}
+		code[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(code)
+}
+
+// NormalizeInviteCode makes codes typed by players case and space insensitive
+func NormalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// RegenerateInviteCode replaces the room's invite code, invalidating the old one
+func (r *Room) RegenerateInviteCode() string {
+	r.InviteCode = GenerateInviteCode()
+	return r.InviteCode
+}
+
+// RevokeInviteCode removes the invite code, private rooms can then only be
+// rejoined by their current players
+func (r *Room) RevokeInviteCode() {
+	r.InviteCode = ""
+}
+
+// SetPassword protects the room with a password, an empty password removes it
+func (r *Room) SetPassword(password string) error {
+	if password == "" {
+		r.PasswordHash = nil
+		return nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	r.PasswordHash = hash
+	return nil
+}
+
+func (r *Room) HasPassword() bool {
+	return len(r.PasswordHash) > 0
+}
+
+// CheckPassword validates the password of a new player. bcrypt is slow on
+// purpose, so callers check it before taking any lock.
+func (r *Room) CheckPassword(password string) error {
+	if r.HasPassword() && bcrypt.CompareHashAndPassword(r.PasswordHash, []byte(password)) != nil {
+		return fmt.Errorf("wrong room password")
+	}
+	return nil
+}
+
+// CheckAccess validates whether a new player may join the room with the given
+// invite code, the password is checked apart by CheckPassword
+func (r *Room) CheckAccess(playerID uuid.UUID, inviteCode string) error {
+	if r.Kicked[playerID] {
+		return fmt.Errorf("you were removed from this room")
+	}
+
+	if len(r.Players) >= r.MaxPlayers {
+		return fmt.Errorf("room is full")
+	}
+
+	if r.Private && (r.InviteCode == "" || NormalizeInviteCode(inviteCode) != r.InviteCode) {
+		return fmt.Errorf("a valid invite code is required to join this room")
+	}
+
+	return nil
+}
@@ -1,13 +1,19 @@
 package room
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"es2.uff/war-server/internal/domain/player"
 	"github.com/google/uuid"
 )
 
-var OpenRooms []*Room
+// ErrStarted is returned for changes a room no longer accepts once its game
+// has started
+var ErrStarted = errors.New("the game has already started")
 
 type Room struct {
 	RoomID      uuid.UUID
@@ -17,9 +23,26 @@ type Room struct {
 	Players     []*player.Player
 	PlayerCount int
 	MaxPlayers  int
+	Started     bool
+	Settings    RoomSettings
+
+	// Colors maps each player to the palette color picked in the lobby
+	Colors map[uuid.UUID]string
+
+	// JoinedAt records when each player entered, used to pick the next owner
+	JoinedAt map[uuid.UUID]time.Time
+	Kicked   map[uuid.UUID]bool
+
+	// Private rooms are hidden from the listing and joined through InviteCode
+	Private      bool
+	InviteCode   string
+	PasswordHash []byte
 }
 
-func NewRoom(name string, ownerID uuid.UUID, ownerName string) (*Room, error) {
+func NewRoom(name string, ownerID uuid.UUID, ownerName string, settings RoomSettings) (*Room, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
 
 	newRoom := &Room{
 		RoomID:      uuid.New(),
@@ -28,41 +51,203 @@ func NewRoom(name string, ownerID uuid.UUID, ownerName string) (*Room, error) {
 		Name:        name,
 		PlayerCount: 0,
 		Players:     []*player.Player{},
+		MaxPlayers:  settings.MaxPlayers,
+		Settings:    settings,
+		Colors:      make(map[uuid.UUID]string),
+		JoinedAt:    make(map[uuid.UUID]time.Time),
+		Kicked:      make(map[uuid.UUID]bool),
+		InviteCode:  GenerateInviteCode(),
 	}
 
-	OpenRooms = append(OpenRooms, newRoom)
 	return newRoom, nil
 }
 
-func ListRooms() ([]*Room, error) {
-	return OpenRooms, nil
+// Clone returns a deep copy of the room, so a stored room is never shared
+// between goroutines
+func (r *Room) Clone() *Room {
+	clone := *r
+
+	clone.Players = make([]*player.Player, len(r.Players))
+	for i, p := range r.Players {
+		cp := *p
+		clone.Players[i] = &cp
+	}
+
+	clone.Colors = maps.Clone(r.Colors)
+	clone.JoinedAt = maps.Clone(r.JoinedAt)
+	clone.Kicked = maps.Clone(r.Kicked)
+	clone.PasswordHash = slices.Clone(r.PasswordHash)
+
+	return &clone
+}
+
+// AddPlayer seats a player in the room, ignoring players already seated
+func (r *Room) AddPlayer(p *player.Player) {
+	if r.HasPlayer(p.ID) {
+		return
+	}
+
+	r.Players = append(r.Players, p)
+	r.PlayerCount = len(r.Players)
+	r.JoinedAt[p.ID] = time.Now()
+
+	// Keep the player's preferred color when it is still free
+	if p.Color != "" {
+		_ = r.ChooseColor(p.ID, p.Color)
+	}
+}
+
+// RemovePlayer takes a player out of the room, handing ownership to the
+// longest-present player when the owner leaves. Returns true when the room is
+// left empty.
+func (r *Room) RemovePlayer(playerID uuid.UUID) bool {
+	r.Players = slices.DeleteFunc(r.Players, func(p *player.Player) bool {
+		return p.ID == playerID
+	})
+	r.PlayerCount = len(r.Players)
+	delete(r.Colors, playerID)
+	delete(r.JoinedAt, playerID)
+
+	if len(r.Players) == 0 {
+		return true
+	}
+
+	if r.OwnerID == playerID {
+		next := r.Players[0]
+		for _, p := range r.Players[1:] {
+			if r.JoinedAt[p.ID].Before(r.JoinedAt[next.ID]) {
+				next = p
+			}
+		}
+		r.OwnerID = next.ID
+		r.OwnerName = next.Name
+	}
+
+	return false
+}
+
+// Kick removes a player and prevents them from joining the room again
+func (r *Room) Kick(playerID uuid.UUID) bool {
+	r.Kicked[playerID] = true
+	return r.RemovePlayer(playerID)
+}
+
+// Start marks the room's game as started, which happens only once
+func (r *Room) Start() error {
+	if r.Started {
+		return ErrStarted
+	}
+	r.Started = true
+	return nil
+}
+
+// UpdateSettings validates and applies new settings to the room
+func (r *Room) UpdateSettings(settings RoomSettings) error {
+	if r.Started {
+		return ErrStarted
+	}
+
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	if settings.MaxPlayers < len(r.Players) {
+		return fmt.Errorf("room already has %d players", len(r.Players))
+	}
+
+	r.Settings = settings
+	r.MaxPlayers = settings.MaxPlayers
+	return nil
 }
 
-func DeleteRoom(roomID uuid.UUID) {
-	for i, room := range OpenRooms {
-		if room.RoomID == roomID {
-			OpenRooms = append(OpenRooms[:i], OpenRooms[i+1:]...)
-			return
+func (r *Room) HasPlayer(playerID uuid.UUID) bool {
+	for _, p := range r.Players {
+		if p.ID == playerID {
+			return true
 		}
 	}
+	return false
 }
 
-func GetRoom(roomID uuid.UUID) *Room {
-	for _, room := range OpenRooms {
-		if room.RoomID == roomID {
-			return room
+// ChooseColor assigns a palette color to a player, rejecting colors already
+// taken by someone else in the room
+func (r *Room) ChooseColor(playerID uuid.UUID, color string) error {
+	if r.Started {
+		return ErrStarted
+	}
+
+	if !r.HasPlayer(playerID) {
+		return fmt.Errorf("player is not in the room")
+	}
+
+	if !player.IsValidColor(color) {
+		return fmt.Errorf("invalid color %s", color)
+	}
+
+	for id, c := range r.Colors {
+		if c == color && id != playerID {
+			return fmt.Errorf("color %s is already taken", color)
 		}
 	}
+
+	r.Colors[playerID] = color
 	return nil
 }
 
-func AddPlayerToRoom(roomID uuid.UUID, player *player.Player) {
-	for _, room := range OpenRooms {
-		if room.RoomID == roomID {
-			room.Players = append(room.Players, player)
-			break
+// AvailableColors returns the palette colors nobody in the room has picked
+func (r *Room) AvailableColors() []string {
+	available := make([]string, 0, len(player.Palette))
+	for _, color := range player.Palette {
+		taken := false
+		for _, c := range r.Colors {
+			if c == color {
+				taken = true
+				break
+			}
 		}
+		if !taken {
+			available = append(available, color)
+		}
+	}
+	return available
+}
+
+// ReorderSeats sets the seat order of the room, which is also the turn order
+// of the game. The new order must contain every player exactly once.
+func (r *Room) ReorderSeats(order []uuid.UUID) error {
+	if r.Started {
+		return ErrStarted
+	}
+
+	if len(order) != len(r.Players) {
+		return fmt.Errorf("seat order must contain all %d players", len(r.Players))
+	}
+
+	reordered := make([]*player.Player, 0, len(order))
+	for _, id := range order {
+		var seated *player.Player
+		for _, p := range r.Players {
+			if p.ID == id {
+				seated = p
+				break
+			}
+		}
+		if seated == nil || slices.Contains(reordered, seated) {
+			return fmt.Errorf("invalid seat order")
+		}
+		reordered = append(reordered, seated)
 	}
 
-	fmt.Println(OpenRooms)
+	r.Players = reordered
+	return nil
+}
+
+// BotsToAdd returns how many bots join a game started with humanCount players,
+// never exceeding the seat limit and always leaving at least two players
+func (r *Room) BotsToAdd(humanCount int) int {
+	bots := min(r.Settings.BotCount, r.Settings.MaxPlayers-humanCount)
+	if humanCount+bots < MinPlayerLimit {
+		bots = MinPlayerLimit - humanCount
+	}
+	return max(bots, 0)
 }
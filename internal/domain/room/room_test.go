@@ -1 +1,345 @@
 package room
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"es2.uff/war-server/internal/domain/player"
+	"github.com/google/uuid"
+)
+
+func TestDefaultSettings_Valid(t *testing.T) {
+	if err := DefaultSettings().Validate(); err != nil {
+		t.Errorf("DefaultSettings().Validate() error = %v, want nil", err)
+	}
+}
+
+func TestBotDifficulty_External(t *testing.T) {
+	known := externalBots
+	externalBots = nil
+	t.Cleanup(func() { externalBots = known })
+	AddExternalBot("refbot")
+	AddExternalBot("refbot")
+	if len(externalBots) != 1 {
+		t.Errorf("external bots = %v, want refbot once", externalBots)
+	}
+
+	tests := []struct {
+		difficulty BotDifficulty
+		wantName   string
+		wantValid  bool
+	}{
+		{BotHard, "", true},
+		{"external:refbot", "refbot", true},
+		{"external:other", "other", false},
+		{"external:", "", false},
+	}
+
+	for _, tt := range tests {
+		name, _ := tt.difficulty.External()
+		if name != tt.wantName || tt.difficulty.Valid() != tt.wantValid {
+			t.Errorf("%q: External() = %q, Valid() = %v, want %q and %v",
+				tt.difficulty, name, tt.difficulty.Valid(), tt.wantName, tt.wantValid)
+		}
+	}
+}
+
+func TestRoomSettings_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(s *RoomSettings)
+		wantErr bool
+	}{
+		{"Two players", func(s *RoomSettings) { s.MaxPlayers = 2; s.BotCount = 1 }, false},
+		{"Too few players", func(s *RoomSettings) { s.MaxPlayers = 1; s.BotCount = 0 }, true},
+		{"Too many players", func(s *RoomSettings) { s.MaxPlayers = 7 }, true},
+		{"Negative bots", func(s *RoomSettings) { s.BotCount = -1 }, true},
+		{"Bots fill every seat", func(s *RoomSettings) { s.MaxPlayers = 3; s.BotCount = 3 }, true},
+		{"Unknown difficulty", func(s *RoomSettings) { s.BotDifficulty = "impossible" }, true},
+		{"Difficulty per bot", func(s *RoomSettings) { s.PerBotDifficulty = []BotDifficulty{BotHard, BotEasy} }, false},
+		{"Unknown difficulty of a bot", func(s *RoomSettings) { s.PerBotDifficulty = []BotDifficulty{BotHard, "impossible"} }, true},
+		{"Bot difficulties past the seats", func(s *RoomSettings) { s.PerBotDifficulty = slices.Repeat([]BotDifficulty{BotHard}, 6) }, true},
+		{"Turn timer", func(s *RoomSettings) { s.TurnTimer = 90 }, false},
+		{"Turn timer too short", func(s *RoomSettings) { s.TurnTimer = 5 }, true},
+		{"Turn timer too long", func(s *RoomSettings) { s.TurnTimer = 3600 }, true},
+		{"Unknown map", func(s *RoomSettings) { s.Map = "moon" }, true},
+		{"World domination", func(s *RoomSettings) { s.RuleVariant = VariantWorldDomination }, false},
+		{"Unknown variant", func(s *RoomSettings) { s.RuleVariant = "capture_the_flag" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DefaultSettings()
+			tt.modify(&s)
+
+			err := s.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRoom_UpdateSettings(t *testing.T) {
+	r := &Room{
+		Players: []*player.Player{
+			{ID: uuid.New()}, {ID: uuid.New()}, {ID: uuid.New()},
+		},
+		Settings: DefaultSettings(),
+	}
+
+	s := DefaultSettings()
+	s.MaxPlayers = 2
+	s.BotCount = 0
+	if err := r.UpdateSettings(s); err == nil {
+		t.Error("UpdateSettings() below current player count should return error, got nil")
+	}
+
+	s.MaxPlayers = 4
+	if err := r.UpdateSettings(s); err != nil {
+		t.Errorf("UpdateSettings() error = %v, want nil", err)
+	}
+
+	if r.MaxPlayers != 4 {
+		t.Errorf("MaxPlayers = %d, want 4", r.MaxPlayers)
+	}
+}
+
+func TestRoom_BotsToAdd(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxPlayers int
+		botCount   int
+		humans     int
+		want       int
+	}{
+		{"Default fill", 6, 2, 1, 2},
+		{"Capped by seats", 4, 3, 3, 1},
+		{"Full room", 3, 2, 3, 0},
+		{"Never alone", 6, 0, 1, 1},
+		{"No bots needed", 6, 0, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Room{Settings: DefaultSettings()}
+			r.Settings.MaxPlayers = tt.maxPlayers
+			r.Settings.BotCount = tt.botCount
+
+			if got := r.BotsToAdd(tt.humans); got != tt.want {
+				t.Errorf("BotsToAdd(%d) = %d, want %d", tt.humans, got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestRoom(playerCount int) *Room {
+	r := &Room{
+		Settings: DefaultSettings(),
+		Colors:   make(map[uuid.UUID]string),
+		JoinedAt: make(map[uuid.UUID]time.Time),
+		Kicked:   make(map[uuid.UUID]bool),
+	}
+	for range playerCount {
+		r.Players = append(r.Players, &player.Player{ID: uuid.New()})
+	}
+	return r
+}
+
+func TestRoom_ChooseColor(t *testing.T) {
+	r := newTestRoom(2)
+	first, second := r.Players[0].ID, r.Players[1].ID
+
+	if err := r.ChooseColor(first, player.Palette[0]); err != nil {
+		t.Fatalf("ChooseColor() error = %v, want nil", err)
+	}
+
+	if err := r.ChooseColor(second, player.Palette[0]); err == nil {
+		t.Error("ChooseColor() with a taken color should return error, got nil")
+	}
+
+	if err := r.ChooseColor(second, "#123456"); err == nil {
+		t.Error("ChooseColor() with a color outside the palette should return error, got nil")
+	}
+
+	if err := r.ChooseColor(uuid.New(), player.Palette[1]); err == nil {
+		t.Error("ChooseColor() by a player outside the room should return error, got nil")
+	}
+
+	// Changing your own color frees the previous one
+	if err := r.ChooseColor(first, player.Palette[1]); err != nil {
+		t.Fatalf("ChooseColor() error = %v, want nil", err)
+	}
+	if err := r.ChooseColor(second, player.Palette[0]); err != nil {
+		t.Errorf("ChooseColor() with a released color error = %v, want nil", err)
+	}
+
+	available := r.AvailableColors()
+	if len(available) != len(player.Palette)-2 {
+		t.Errorf("AvailableColors() returned %d colors, want %d", len(available), len(player.Palette)-2)
+	}
+}
+
+func TestRoom_ReorderSeats(t *testing.T) {
+	r := newTestRoom(3)
+	a, b, c := r.Players[0].ID, r.Players[1].ID, r.Players[2].ID
+
+	if err := r.ReorderSeats([]uuid.UUID{c, a, b}); err != nil {
+		t.Fatalf("ReorderSeats() error = %v, want nil", err)
+	}
+
+	if r.Players[0].ID != c || r.Players[1].ID != a || r.Players[2].ID != b {
+		t.Error("ReorderSeats() did not apply the new order")
+	}
+
+	invalidOrders := [][]uuid.UUID{
+		{a, b},
+		{a, a, b},
+		{a, b, uuid.New()},
+	}
+	for _, order := range invalidOrders {
+		if err := r.ReorderSeats(order); err == nil {
+			t.Errorf("ReorderSeats(%v) should return error, got nil", order)
+		}
+	}
+}
+
+func TestRoom_Start(t *testing.T) {
+	r := newTestRoom(1)
+	if err := r.Start(); err != nil || !r.Started {
+		t.Fatalf("Start() error = %v, started = %v", err, r.Started)
+	}
+	if err := r.Start(); !errors.Is(err, ErrStarted) {
+		t.Errorf("Start() again error = %v, want %v", err, ErrStarted)
+	}
+}
+
+func TestRoom_LobbyChangesAfterStart(t *testing.T) {
+	r := newTestRoom(2)
+	r.Start()
+
+	if err := r.UpdateSettings(DefaultSettings()); !errors.Is(err, ErrStarted) {
+		t.Errorf("UpdateSettings() error = %v, want %v", err, ErrStarted)
+	}
+	if err := r.ChooseColor(r.Players[0].ID, player.Palette[0]); !errors.Is(err, ErrStarted) {
+		t.Errorf("ChooseColor() error = %v, want %v", err, ErrStarted)
+	}
+	order := []uuid.UUID{r.Players[1].ID, r.Players[0].ID}
+	if err := r.ReorderSeats(order); !errors.Is(err, ErrStarted) {
+		t.Errorf("ReorderSeats() error = %v, want %v", err, ErrStarted)
+	}
+}
+
+func TestRoom_CheckAccess(t *testing.T) {
+	r := newTestRoom(1)
+	r.MaxPlayers = 2
+	r.InviteCode = "ABC234"
+	joiner := uuid.New()
+
+	if err := r.CheckAccess(joiner, ""); err != nil {
+		t.Errorf("CheckAccess() on public room error = %v, want nil", err)
+	}
+
+	r.Private = true
+	if err := r.CheckAccess(joiner, ""); err == nil {
+		t.Error("CheckAccess() on private room without code should return error, got nil")
+	}
+	if err := r.CheckAccess(joiner, " abc234 "); err != nil {
+		t.Errorf("CheckAccess() with valid code error = %v, want nil", err)
+	}
+
+	r.RevokeInviteCode()
+	if err := r.CheckAccess(joiner, "ABC234"); err == nil {
+		t.Error("CheckAccess() with revoked code should return error, got nil")
+	}
+
+	r.Private = false
+	r.Players = append(r.Players, &player.Player{ID: uuid.New()})
+	if err := r.CheckAccess(joiner, ""); err == nil {
+		t.Error("CheckAccess() on full room should return error, got nil")
+	}
+}
+
+func TestRoom_CheckPassword(t *testing.T) {
+	r := newTestRoom(1)
+	if err := r.CheckPassword(""); err != nil {
+		t.Errorf("CheckPassword() without a password error = %v, want nil", err)
+	}
+
+	if err := r.SetPassword("secret"); err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+	if err := r.CheckPassword("wrong"); err == nil {
+		t.Error("CheckPassword() with wrong password should return error, got nil")
+	}
+	if err := r.CheckPassword("secret"); err != nil {
+		t.Errorf("CheckPassword() with right password error = %v, want nil", err)
+	}
+}
+
+func TestRoom_InviteCode(t *testing.T) {
+	r, err := NewRoom("Test Room", uuid.New(), "Owner", DefaultSettings())
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+
+	if len(r.InviteCode) != inviteCodeLength {
+		t.Errorf("InviteCode = %q, want %d characters", r.InviteCode, inviteCodeLength)
+	}
+
+	if strings.Trim(r.InviteCode, inviteCodeAlphabet) != "" {
+		t.Errorf("InviteCode = %q has characters outside the alphabet", r.InviteCode)
+	}
+
+	oldCode := r.InviteCode
+	if newCode := r.RegenerateInviteCode(); newCode == oldCode {
+		t.Error("RegenerateInviteCode() returned the previous code")
+	}
+
+	if NormalizeInviteCode(" abc234 ") != "ABC234" {
+		t.Errorf("NormalizeInviteCode() = %q, want ABC234", NormalizeInviteCode(" abc234 "))
+	}
+}
+
+func TestRoom_RemovePlayer_TransfersOwnership(t *testing.T) {
+	r := newTestRoom(0)
+
+	owner := &player.Player{ID: uuid.New(), Name: "Owner"}
+	oldest := &player.Player{ID: uuid.New(), Name: "Oldest"}
+	newest := &player.Player{ID: uuid.New(), Name: "Newest"}
+	r.OwnerID = owner.ID
+
+	for _, p := range []*player.Player{owner, oldest, newest} {
+		r.AddPlayer(p)
+	}
+	r.AddPlayer(oldest) // Reconnects do not duplicate seats
+
+	r.JoinedAt[oldest.ID] = time.Now().Add(-time.Hour)
+	_ = r.ReorderSeats([]uuid.UUID{owner.ID, newest.ID, oldest.ID})
+
+	if r.PlayerCount != 3 {
+		t.Errorf("PlayerCount = %d, want 3", r.PlayerCount)
+	}
+
+	if empty := r.RemovePlayer(owner.ID); empty {
+		t.Error("RemovePlayer() reported empty room with players left")
+	}
+
+	if r.OwnerID != oldest.ID || r.OwnerName != oldest.Name {
+		t.Errorf("OwnerName = %s, want %s", r.OwnerName, oldest.Name)
+	}
+
+	if empty := r.Kick(newest.ID); empty {
+		t.Error("Kick() reported empty room with players left")
+	}
+	if err := r.CheckAccess(newest.ID, ""); err == nil {
+		t.Error("CheckAccess() for a kicked player should return error, got nil")
+	}
+
+	if empty := r.RemovePlayer(oldest.ID); !empty {
+		t.Error("RemovePlayer() of the last player should report an empty room")
+	}
+}
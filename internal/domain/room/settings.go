@@ -0,0 +1,150 @@
+package room
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	MinPlayerLimit = 2
+	MaxPlayerLimit = 6
+
+	MinTurnTimer = 30  // seconds
+	MaxTurnTimer = 600 // seconds
+)
+
+type BotDifficulty string
+
+const (
+	BotEasy   BotDifficulty = "easy"
+	BotMedium BotDifficulty = "medium"
+	BotHard   BotDifficulty = "hard"
+	// Searches its attacks by simulating games, the slowest to decide
+	BotExpert BotDifficulty = "expert"
+)
+
+var BotDifficulties = []BotDifficulty{BotEasy, BotMedium, BotHard, BotExpert}
+
+// ExternalBotPrefix marks the difficulty of a bot played by an external
+// program, the rest of it names the program
+const ExternalBotPrefix = "external:"
+
+var (
+	externalBotsMu sync.RWMutex
+	externalBots   []string // Names of the external bots the server can run
+)
+
+// AddExternalBot lets rooms pick the external bot name
+func AddExternalBot(name string) {
+	externalBotsMu.Lock()
+	defer externalBotsMu.Unlock()
+
+	if !slices.Contains(externalBots, name) {
+		externalBots = append(externalBots, name)
+	}
+}
+
+// External returns the name of the external bot playing at d
+func (d BotDifficulty) External() (string, bool) {
+	name, ok := strings.CutPrefix(string(d), ExternalBotPrefix)
+	if !ok {
+		return "", false
+	}
+	return name, true
+}
+
+// Valid reports whether d is a built-in difficulty or a known external bot
+func (d BotDifficulty) Valid() bool {
+	if name, ok := d.External(); ok {
+		externalBotsMu.RLock()
+		defer externalBotsMu.RUnlock()
+		return slices.Contains(externalBots, name)
+	}
+	return slices.Contains(BotDifficulties, d)
+}
+
+type RuleVariant string
+
+const (
+	// Each player receives a secret objective card (default rules).
+	VariantObjectives RuleVariant = "objectives"
+
+	// No objective cards, the winner is whoever conquers every territory.
+	VariantWorldDomination RuleVariant = "world_domination"
+)
+
+var RuleVariants = []RuleVariant{VariantObjectives, VariantWorldDomination}
+
+const MapClassic = "classic"
+
+var Maps = []string{MapClassic}
+
+type RoomSettings struct {
+	MaxPlayers    int           `json:"max_players"`
+	BotCount      int           `json:"bot_count"`
+	BotDifficulty BotDifficulty `json:"bot_difficulty"`
+	// Difficulty of each bot in seat order, bots past its end use BotDifficulty
+	PerBotDifficulty []BotDifficulty `json:"per_bot_difficulty,omitempty"`
+	TurnTimer        int             `json:"turn_timer"` // Seconds per turn, 0 disables the timer
+	Map              string          `json:"map"`
+	RuleVariant      RuleVariant     `json:"rule_variant"`
+}
+
+func DefaultSettings() RoomSettings {
+	return RoomSettings{
+		MaxPlayers:    MaxPlayerLimit,
+		BotCount:      2,
+		BotDifficulty: BotEasy,
+		TurnTimer:     0,
+		Map:           MapClassic,
+		RuleVariant:   VariantObjectives,
+	}
+}
+
+// Validate checks that every setting is within the limits accepted by the server
+func (s RoomSettings) Validate() error {
+	if s.MaxPlayers < MinPlayerLimit || s.MaxPlayers > MaxPlayerLimit {
+		return fmt.Errorf("max players must be between %d and %d", MinPlayerLimit, MaxPlayerLimit)
+	}
+
+	if s.BotCount < 0 || s.BotCount > s.MaxPlayers-1 {
+		return fmt.Errorf("bot count must be between 0 and %d", s.MaxPlayers-1)
+	}
+
+	if !s.BotDifficulty.Valid() {
+		return fmt.Errorf("unknown bot difficulty %q", s.BotDifficulty)
+	}
+
+	if len(s.PerBotDifficulty) > s.MaxPlayers-1 {
+		return fmt.Errorf("at most %d bot difficulties can be set", s.MaxPlayers-1)
+	}
+	for _, d := range s.PerBotDifficulty {
+		if !d.Valid() {
+			return fmt.Errorf("unknown bot difficulty %q", d)
+		}
+	}
+
+	if s.TurnTimer != 0 && (s.TurnTimer < MinTurnTimer || s.TurnTimer > MaxTurnTimer) {
+		return fmt.Errorf("turn timer must be 0 or between %d and %d seconds", MinTurnTimer, MaxTurnTimer)
+	}
+
+	if !slices.Contains(Maps, s.Map) {
+		return fmt.Errorf("unknown map %q", s.Map)
+	}
+
+	if !slices.Contains(RuleVariants, s.RuleVariant) {
+		return fmt.Errorf("unknown rule variant %q", s.RuleVariant)
+	}
+
+	return nil
+}
+
+// DifficultyOf returns the difficulty of the i-th bot added to a game
+func (s RoomSettings) DifficultyOf(i int) BotDifficulty {
+	if i >= 0 && i < len(s.PerBotDifficulty) {
+		return s.PerBotDifficulty[i]
+	}
+	return s.BotDifficulty
+}
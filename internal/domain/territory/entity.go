@@ -12,3 +12,14 @@ type Territory struct {
 
 	ArmyQuantity int
 }
+
+// RegionSize returns how many territories make up a region
+func RegionSize(region Region) int {
+	size := 0
+	for _, r := range TerritoryRegionMap {
+		if r == region {
+			size++
+		}
+	}
+	return size
+}
@@ -307,3 +307,12 @@ var TerritoryNameMap = map[TerritoryID]string{
 	Alaska:     "Alasca",
 	Greenland:  "Groenlândia",
 }
+
+var RegionNameMap = map[Region]string{
+	Europe:       "Europa",
+	Asia:         "Ásia",
+	Africa:       "África",
+	Oceania:      "Oceania",
+	SouthAmerica: "América do Sul",
+	NorthAmerica: "América do Norte",
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"es2.uff/war-server/internal/auth"
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/repository"
+	"github.com/labstack/echo/v4"
+)
+
+type AccountRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type AccountHandler struct {
+	players  repository.PlayerRepository
+	accounts repository.AccountRepository
+	tokens   *auth.TokenManager
+}
+
+func NewAccountHandler(
+	players repository.PlayerRepository,
+	accounts repository.AccountRepository,
+	tokens *auth.TokenManager,
+) *AccountHandler {
+	return &AccountHandler{
+		players:  players,
+		accounts: accounts,
+		tokens:   tokens,
+	}
+}
+
+// Register creates a player with an account, named after its username
+func (ah *AccountHandler) Register(c echo.Context) error {
+	r := new(AccountRequest)
+	if err := c.Bind(r); err != nil {
+		return c.String(http.StatusBadRequest, "Invalid JSON format")
+	}
+
+	newPlayer, err := player.NewPlayer(r.Username)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	if status, err := ah.createAccount(newPlayer, r); err != nil {
+		return c.String(status, err.Error())
+	}
+
+	if err := ah.players.Create(newPlayer); err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	return ah.respondWithToken(c, newPlayer)
+}
+
+// Login issues a token for the player of an account
+func (ah *AccountHandler) Login(c echo.Context) error {
+	r := new(AccountRequest)
+	if err := c.Bind(r); err != nil {
+		return c.String(http.StatusBadRequest, "Invalid JSON format")
+	}
+
+	account, err := ah.accounts.GetByUsername(r.Username)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !account.CheckPassword(r.Password)) {
+		return c.String(http.StatusUnauthorized, "Invalid username or password")
+	}
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	// Players kept in memory are lost on restart, accounts are not
+	p, err := ah.players.Get(account.PlayerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		p = &player.Player{ID: account.PlayerID, Name: account.Username}
+		if err := ah.players.Create(p); err != nil && !errors.Is(err, repository.ErrAlreadyExists) {
+			return c.String(http.StatusInternalServerError, "Internal Error")
+		}
+	} else if err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	return ah.respondWithToken(c, p)
+}
+
+// Upgrade registers an account for the authenticated guest, keeping its
+// player so rooms, games and history stay theirs
+func (ah *AccountHandler) Upgrade(c echo.Context) error {
+	r := new(AccountRequest)
+	if err := c.Bind(r); err != nil {
+		return c.String(http.StatusBadRequest, "Invalid JSON format")
+	}
+
+	p, err := authenticatedPlayer(c, ah.players)
+	if err != nil {
+		return c.String(http.StatusNotFound, "Player not found")
+	}
+
+	if status, err := ah.createAccount(p, r); err != nil {
+		return c.String(status, err.Error())
+	}
+
+	return ah.respondWithToken(c, p)
+}
+
+// createAccount returns the status to answer with when the account cannot
+// be created
+func (ah *AccountHandler) createAccount(p *player.Player, r *AccountRequest) (int, error) {
+	account, err := player.NewAccount(p.ID, r.Username, r.Password)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	err = ah.accounts.Create(account)
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return http.StatusConflict, errors.New("Username taken or player already registered")
+	}
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Internal Error")
+	}
+
+	return http.StatusOK, nil
+}
+
+func (ah *AccountHandler) respondWithToken(c echo.Context, p *player.Player) error {
+	token, expiresAt, err := ah.tokens.Issue(p.ID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	return c.JSON(http.StatusOK, CreatePlayerResponse{
+		PlayerID:   p.ID.String(),
+		PlayerName: p.Name,
+		Token:      token,
+		ExpiresAt:  expiresAt,
+	})
+}
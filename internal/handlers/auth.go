@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"es2.uff/war-server/internal/auth"
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// playerIDKey holds the authenticated player's ID in the request context
+const playerIDKey = "player_id"
+
+type TokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type AuthHandler struct {
+	tokens *auth.TokenManager
+}
+
+func NewAuthHandler(tokens *auth.TokenManager) *AuthHandler {
+	return &AuthHandler{tokens: tokens}
+}
+
+// requestToken reads the token from the Authorization header, or from the
+// token query parameter since browsers cannot set headers on websockets
+func requestToken(c echo.Context) string {
+	if bearer, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer "); ok {
+		return bearer
+	}
+	return c.QueryParam("token")
+}
+
+// RequireAuth rejects requests without a valid token and keeps the player
+// the token belongs to for the handler
+func (ah *AuthHandler) RequireAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		playerID, err := ah.tokens.Verify(requestToken(c))
+		if errors.Is(err, auth.ErrExpiredToken) {
+			return c.String(http.StatusUnauthorized, "Token expired")
+		}
+		if err != nil {
+			return c.String(http.StatusUnauthorized, "Invalid token")
+		}
+
+		c.Set(playerIDKey, playerID)
+		return next(c)
+	}
+}
+
+// RefreshToken exchanges a token, even a recently expired one, for a new one
+func (ah *AuthHandler) RefreshToken(c echo.Context) error {
+	token, expiresAt, err := ah.tokens.Refresh(requestToken(c))
+	if errors.Is(err, auth.ErrExpiredToken) {
+		return c.String(http.StatusUnauthorized, "Token expired")
+	}
+	if err != nil {
+		return c.String(http.StatusUnauthorized, "Invalid token")
+	}
+
+	return c.JSON(http.StatusOK, TokenResponse{Token: token, ExpiresAt: expiresAt})
+}
+
+// authenticatedPlayer returns the player of a request that went through
+// RequireAuth
+func authenticatedPlayer(c echo.Context, players repository.PlayerRepository) (*player.Player, error) {
+	playerID, ok := c.Get(playerIDKey).(uuid.UUID)
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return players.Get(playerID)
+}
@@ -1,28 +1,53 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 
+	"es2.uff/war-server/internal/repository"
 	"es2.uff/war-server/internal/ws"
 	"github.com/labstack/echo/v4"
 )
 
+type ImportGameResponse struct {
+	GameID string `json:"game_id"`
+}
+
 type GameHandler struct {
 	gameManager *ws.GameManager
+	players     repository.PlayerRepository
 }
 
-func NewGameHandler(gameManager *ws.GameManager) *GameHandler {
+func NewGameHandler(gameManager *ws.GameManager, players repository.PlayerRepository) *GameHandler {
 	return &GameHandler{
 		gameManager: gameManager,
+		players:     players,
 	}
 }
 
 func (gh *GameHandler) HandleGameWebSocket(c echo.Context) error {
 	roomID := c.QueryParam("room_id")
-	userID := c.QueryParam("user_id")
 
-	game := gh.gameManager.GetOrCreateGame(roomID)
-	err := ws.ServeWs(game, c.Response(), c.Request(), userID)
+	p, err := authenticatedPlayer(c, gh.players)
+	if err != nil {
+		return c.String(http.StatusNotFound, "Player not found")
+	}
+
+	game, err := gh.gameManager.GetOrCreateGame(roomID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.String(http.StatusNotFound, "Game not found")
+	}
+	if errors.Is(err, ws.ErrGameOver) {
+		return c.String(http.StatusGone, err.Error())
+	}
+	if err != nil {
+		log.Printf("Error opening game %s: %v", roomID, err)
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	err = ws.ServeWs(game, c.Response(), c.Request(), p)
 
 	if err != nil {
 		return c.String(http.StatusBadRequest, "Error HandleWebSocket")
@@ -30,3 +55,105 @@ func (gh *GameHandler) HandleGameWebSocket(c echo.Context) error {
 
 	return nil
 }
+
+// GetEvents returns the event stream of a finished game
+func (gh *GameHandler) GetEvents(c echo.Context) error {
+	events, err := gh.gameManager.GameEvents(c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.String(http.StatusNotFound, "Game not found")
+	}
+	if errors.Is(err, ws.ErrGameInProgress) {
+		return c.String(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	return c.JSON(http.StatusOK, events)
+}
+
+// HandleReplayWebSocket streams a finished game to a spectator, speed sets
+// how much faster than the original game it plays
+func (gh *GameHandler) HandleReplayWebSocket(c echo.Context) error {
+	p, err := authenticatedPlayer(c, gh.players)
+	if err != nil {
+		return c.String(http.StatusNotFound, "Player not found")
+	}
+
+	gameID := c.Param("id")
+	events, err := gh.gameManager.GameEvents(gameID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.String(http.StatusNotFound, "Game not found")
+	}
+	if errors.Is(err, ws.ErrGameInProgress) {
+		return c.String(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	speed := 1.0
+	if raw := c.QueryParam("speed"); raw != "" {
+		if speed, err = strconv.ParseFloat(raw, 64); err != nil || !ws.ValidReplaySpeed(speed) {
+			return c.String(http.StatusBadRequest, "Invalid speed")
+		}
+	}
+
+	hub := ws.NewReplayHub(gameID, events, speed)
+	if err := ws.ServeReplayWs(hub, c.Response(), c.Request(), p); err != nil {
+		return c.String(http.StatusBadRequest, "Error HandleWebSocket")
+	}
+
+	return nil
+}
+
+// ExportGame saves the current position of a game as a document that
+// ImportGame can load. An export holds the dice, deck, hands and objectives,
+// so whoever has it can foresee the rest of the game: running games are
+// exported only for their owner, and only while paused or played against
+// bots alone, at the cost of not saving a live game between humans.
+func (gh *GameHandler) ExportGame(c echo.Context) error {
+	gameID := c.Param("id")
+
+	p, err := authenticatedPlayer(c, gh.players)
+	if err != nil {
+		return c.String(http.StatusNotFound, "Player not found")
+	}
+
+	export, err := gh.gameManager.ExportGame(gameID, p.ID.String())
+	if errors.Is(err, repository.ErrNotFound) {
+		return c.String(http.StatusNotFound, "Game not found")
+	}
+	if errors.Is(err, ws.ErrExportForbidden) {
+		return c.String(http.StatusForbidden, err.Error())
+	}
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="game-`+gameID+`.json"`)
+	return c.JSON(http.StatusOK, export)
+}
+
+// ImportGame starts a new game from an exported position
+func (gh *GameHandler) ImportGame(c echo.Context) error {
+	export := new(ws.GameExport)
+	if err := c.Bind(export); err != nil {
+		return c.String(http.StatusBadRequest, "Invalid JSON format")
+	}
+
+	p, err := authenticatedPlayer(c, gh.players)
+	if err != nil {
+		return c.String(http.StatusNotFound, "Player not found")
+	}
+
+	gameID, err := gh.gameManager.ImportGame(export, p.ID.String())
+	if errors.Is(err, ws.ErrInvalidExport) {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	return c.JSON(http.StatusOK, ImportGameResponse{GameID: gameID})
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"es2.uff/war-server/internal/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// recentGamesLimit is how many finished games a profile lists
+const recentGamesLimit = 10
+
+type ObjectiveStatsResponse struct {
+	ObjectiveID int     `json:"objective_id"`
+	Objective   string  `json:"objective"`
+	GamesPlayed int     `json:"games_played"`
+	Wins        int     `json:"wins"`
+	WinRate     float64 `json:"win_rate"`
+}
+
+type RecentGameResponse struct {
+	GameID          string    `json:"game_id"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationSeconds int       `json:"duration_seconds"`
+	Won             bool      `json:"won"`
+	WinnerName      string    `json:"winner_name"`
+	Objective       string    `json:"objective"`
+	Players         []string  `json:"players"`
+	repository.MatchStats
+}
+
+type PlayerProfileResponse struct {
+	PlayerID           string                   `json:"player_id"`
+	Name               string                   `json:"name"`
+	Color              string                   `json:"color,omitempty"`
+	GamesPlayed        int                      `json:"games_played"`
+	Wins               int                      `json:"wins"`
+	Losses             int                      `json:"losses"`
+	WinRate            float64                  `json:"win_rate"`
+	WinRateByObjective []ObjectiveStatsResponse `json:"win_rate_by_objective"`
+	FavoriteContinent  string                   `json:"favorite_continent,omitempty"`
+	repository.MatchStats
+	RecentGames []RecentGameResponse `json:"recent_games"`
+}
+
+type PlayerHandler struct {
+	players repository.PlayerRepository
+	history repository.HistoryRepository
+}
+
+func NewPlayerHandler(players repository.PlayerRepository, history repository.HistoryRepository) *PlayerHandler {
+	return &PlayerHandler{
+		players: players,
+		history: history,
+	}
+}
+
+// GetProfile returns a player's statistics over their finished games. A
+// player who has not finished a game yet gets an empty profile.
+func (ph *PlayerHandler) GetProfile(c echo.Context) error {
+	id := c.Param("id")
+
+	response := PlayerProfileResponse{
+		PlayerID:           id,
+		WinRateByObjective: []ObjectiveStatsResponse{},
+		RecentGames:        []RecentGameResponse{},
+	}
+
+	found := false
+	if playerID, err := uuid.Parse(id); err == nil {
+		p, err := ph.players.Get(playerID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return c.String(http.StatusInternalServerError, "Internal Error")
+		}
+		if err == nil {
+			found = true
+			response.Name = p.Name
+			response.Color = p.Color
+		}
+	}
+
+	profile, err := ph.history.GetProfile(id)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	// Guests kept in memory are gone after a restart while their history stays
+	if err == nil {
+		if !found {
+			response.Name = profile.Name
+		}
+		found = true
+
+		response.GamesPlayed = profile.GamesPlayed
+		response.Wins = profile.Wins
+		response.Losses = profile.Losses
+		response.WinRate = profile.WinRate()
+		response.FavoriteContinent = profile.FavoriteContinent
+		response.MatchStats = profile.MatchStats
+
+		for _, o := range profile.Objectives {
+			response.WinRateByObjective = append(response.WinRateByObjective, ObjectiveStatsResponse{
+				ObjectiveID: o.ObjectiveID,
+				Objective:   o.Objective,
+				GamesPlayed: o.GamesPlayed,
+				Wins:        o.Wins,
+				WinRate:     o.WinRate(),
+			})
+		}
+	}
+
+	if !found {
+		return c.String(http.StatusNotFound, "Player not found")
+	}
+
+	matches, err := ph.history.ListPlayerMatches(id, recentGamesLimit)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	for _, m := range matches {
+		game := RecentGameResponse{
+			GameID:          m.ID,
+			StartedAt:       m.StartedAt,
+			FinishedAt:      m.FinishedAt,
+			DurationSeconds: int(m.Duration().Seconds()),
+			Players:         []string{},
+		}
+
+		for _, p := range m.Participants {
+			game.Players = append(game.Players, p.Name)
+			if p.PlayerID == m.WinnerID {
+				game.WinnerName = p.Name
+			}
+			if p.PlayerID == id {
+				game.Won = p.Won
+				game.Objective = p.Objective
+				game.MatchStats = p.MatchStats
+			}
+		}
+
+		response.RecentGames = append(response.RecentGames, game)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
+	"es2.uff/war-server/internal/auth"
 	"es2.uff/war-server/internal/domain/player"
 	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/repository"
 	"es2.uff/war-server/internal/ws"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -16,13 +20,18 @@ type CreatePlayerRequest struct {
 }
 
 type CreatePlayerResponse struct {
-	PlayerID   string `json:"player_id"`
-	PlayerName string `json:"player_name"`
+	PlayerID   string    `json:"player_id"`
+	PlayerName string    `json:"player_name"`
+	Token      string    `json:"token"`
+	ExpiresAt  time.Time `json:"expires_at"`
 }
 
+// CreateRoomRequest creates a room owned by the authenticated player
 type CreateRoomRequest struct {
-	RoomName string    `json:"room_name"`
-	OwnerID  uuid.UUID `json:"owner_id"`
+	RoomName string            `json:"room_name"`
+	Settings room.RoomSettings `json:"settings"`
+	Private  bool              `json:"private"`
+	Password string            `json:"password"`
 }
 
 type JoinRoomRequest struct {
@@ -31,21 +40,36 @@ type JoinRoomRequest struct {
 }
 
 type RoomResponse struct {
-	RoomID      string `json:"room_id"`
-	RoomName    string `json:"room_name"`
-	OwnerID     string `json:"owner_id"`
-	OwnerName   string `json:"owner_name"`
-	PlayerCount int    `json:"player_count"`
-	MaxPlayers  int    `json:"max_players"`
+	RoomID      string             `json:"room_id"`
+	RoomName    string             `json:"room_name"`
+	OwnerID     string             `json:"owner_id"`
+	OwnerName   string             `json:"owner_name"`
+	PlayerCount int                `json:"player_count"`
+	MaxPlayers  int                `json:"max_players"`
+	Settings    *room.RoomSettings `json:"settings,omitempty"`
+	Private     bool               `json:"private"`
+	HasPassword bool               `json:"has_password"`
+	InviteCode  string             `json:"invite_code,omitempty"`
 }
 
 type RoomHandler struct {
 	roomServer *ws.RoomServer
+	players    repository.PlayerRepository
+	rooms      repository.RoomRepository
+	tokens     *auth.TokenManager
 }
 
-func NewRoomHandler(roomServer *ws.RoomServer) *RoomHandler {
+func NewRoomHandler(
+	roomServer *ws.RoomServer,
+	players repository.PlayerRepository,
+	rooms repository.RoomRepository,
+	tokens *auth.TokenManager,
+) *RoomHandler {
 	return &RoomHandler{
 		roomServer: roomServer,
+		players:    players,
+		rooms:      rooms,
+		tokens:     tokens,
 	}
 }
 
@@ -61,9 +85,20 @@ func (rh *RoomHandler) CreatePlayer(c echo.Context) error {
 		return c.String(http.StatusInternalServerError, "Internal Error")
 	}
 
+	if err := rh.players.Create(newPlayer); err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
+	token, expiresAt, err := rh.tokens.Issue(newPlayer.ID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
 	response := CreatePlayerResponse{
 		PlayerID:   newPlayer.ID.String(),
 		PlayerName: newPlayer.Name,
+		Token:      token,
+		ExpiresAt:  expiresAt,
 	}
 
 	log.Printf("New player %s created with ID %s", newPlayer.Name, newPlayer.ID)
@@ -71,34 +106,61 @@ func (rh *RoomHandler) CreatePlayer(c echo.Context) error {
 }
 
 func (rh *RoomHandler) CreateNewRoom(c echo.Context) error {
-	r := new(CreateRoomRequest)
+	// Settings omitted from the request body keep their default values
+	r := &CreateRoomRequest{Settings: room.DefaultSettings()}
 
 	if err := c.Bind(r); err != nil {
 		return c.String(http.StatusBadRequest, "Invalid JSON format")
 	}
 
-	owner := player.GetPlayer(r.OwnerID)
+	if err := r.Settings.Validate(); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	owner, err := authenticatedPlayer(c, rh.players)
+	if err != nil {
+		return c.String(http.StatusNotFound, "Player not found")
+	}
 
-	nr, err := room.NewRoom(r.RoomName, owner.ID, owner.Name)
+	nr, err := room.NewRoom(r.RoomName, owner.ID, owner.Name, r.Settings)
 	if err != nil {
 		return c.String(http.StatusInternalServerError, "Internal Error")
 	}
 
+	nr.Private = r.Private
+	if err := nr.SetPassword(r.Password); err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
+
 	// Add the owner to the room's player list
-	nr.Players = append(nr.Players, owner)
-	nr.PlayerCount = 1
+	nr.AddPlayer(owner)
+
+	err = rh.rooms.Create(nr)
+	for attempt := 0; errors.Is(err, repository.ErrInviteCodeConflict) && attempt < 3; attempt++ {
+		nr.RegenerateInviteCode()
+		err = rh.rooms.Create(nr)
+	}
+	if err != nil {
+		return c.String(http.StatusInternalServerError, "Internal Error")
+	}
 
 	response := RoomResponse{
-		RoomID: nr.RoomID.String(),
+		RoomID:      nr.RoomID.String(),
+		Settings:    &nr.Settings,
+		Private:     nr.Private,
+		HasPassword: nr.HasPassword(),
+		InviteCode:  nr.InviteCode,
 	}
 
+	rh.roomServer.NotifyRoomCreated(nr)
+
 	log.Printf("New room %s created successfully by %s (owner added to players)", nr.RoomID, nr.OwnerName)
 	return c.JSON(http.StatusOK, response)
 }
 
 func (rh *RoomHandler) ListRooms(c echo.Context) error {
 	resp := []RoomResponse{}
-	l, err := room.ListRooms()
+	l, err := repository.ListPublicRooms(rh.rooms)
 
 	if err != nil {
 		return c.String(http.StatusInternalServerError, "Internal Error")
@@ -111,19 +173,74 @@ func (rh *RoomHandler) ListRooms(c echo.Context) error {
 			OwnerName:   e.OwnerName,
 			PlayerCount: e.PlayerCount,
 			MaxPlayers:  e.MaxPlayers,
+			Settings:    &e.Settings,
+			HasPassword: e.HasPassword(),
 		})
 	}
 
 	return c.JSON(http.StatusOK, resp)
 }
 
+// ResolveInviteCode returns the room an invite code points to, including
+// private rooms hidden from the listing
+func (rh *RoomHandler) ResolveInviteCode(c echo.Context) error {
+	r, err := rh.rooms.GetByInviteCode(c.Param("code"))
+	if err != nil {
+		return c.String(http.StatusNotFound, "Room not found")
+	}
+
+	return c.JSON(http.StatusOK, RoomResponse{
+		RoomID:      r.RoomID.String(),
+		RoomName:    r.Name,
+		OwnerName:   r.OwnerName,
+		PlayerCount: r.PlayerCount,
+		MaxPlayers:  r.MaxPlayers,
+		Settings:    &r.Settings,
+		Private:     r.Private,
+		HasPassword: r.HasPassword(),
+	})
+}
+
 func (rh *RoomHandler) HandleRoomWebSocket(c echo.Context) error {
 	roomID := c.QueryParam("room_id")
-	userID := c.QueryParam("user_id")
+	inviteCode := c.QueryParam("code")
+	password := c.QueryParam("password")
+
+	// Rooms can also be joined through their invite code alone
+	if roomID == "" {
+		if r, err := rh.rooms.GetByInviteCode(inviteCode); err == nil {
+			roomID = r.RoomID.String()
+		}
+	}
+
+	p, err := authenticatedPlayer(c, rh.players)
+	if err != nil {
+		return c.String(http.StatusNotFound, "Player not found")
+	}
+
+	hub, err := rh.roomServer.GetOrCreateHub(roomID, p.ID.String(), inviteCode, password)
+	if err != nil {
+		return c.String(http.StatusForbidden, err.Error())
+	}
+
+	err = ws.ServeWs(hub, c.Response(), c.Request(), p)
+
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Error HandleWebSocket")
+	}
+
+	return nil
+}
+
+func (rh *RoomHandler) HandleLobbyWebSocket(c echo.Context) error {
+	p, err := authenticatedPlayer(c, rh.players)
+	if err != nil {
+		return c.String(http.StatusNotFound, "Player not found")
+	}
 
-	hub := rh.roomServer.GetOrCreateHub(roomID, userID)
-	err := ws.ServeWs(hub, c.Response(), c.Request(), userID)
+	filter := ws.NewLobbyFilter(c.Request())
 
+	err = ws.ServeLobbyWs(rh.roomServer.Lobby(), c.Response(), c.Request(), p, filter)
 	if err != nil {
 		return c.String(http.StatusBadRequest, "Error HandleWebSocket")
 	}
@@ -2,14 +2,32 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
 )
 
+// inviteCodeLimiter slows down guessing invite codes, each address may look up
+// a few codes and then one every 6 seconds
+func inviteCodeLimiter() echo.MiddlewareFunc {
+	return middleware.RateLimiter(middleware.NewRateLimiterMemoryStoreWithConfig(
+		middleware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Every(6 * time.Second),
+			Burst:     5,
+			ExpiresIn: 5 * time.Minute,
+		},
+	))
+}
+
 func SetupRoutes(
 	e *echo.Echo,
 	rh *RoomHandler,
 	gh *GameHandler,
+	ah *AuthHandler,
+	acc *AccountHandler,
+	ph *PlayerHandler,
 ) {
 
 	apiRoutes := e.Group("/api/v1")
@@ -21,14 +39,25 @@ func SetupRoutes(
 	// Player routes
 	playerGroup := apiRoutes.Group("/players")
 	playerGroup.POST("/new", rh.CreatePlayer)
+	playerGroup.POST("/refresh", ah.RefreshToken)
+	playerGroup.POST("/register", acc.Register)
+	playerGroup.POST("/login", acc.Login)
+	playerGroup.POST("/upgrade", acc.Upgrade, ah.RequireAuth)
+	playerGroup.GET("/:id", ph.GetProfile)
 
 	// Room routes
 	roomGroup := apiRoutes.Group("/rooms")
 	roomGroup.GET("/all", rh.ListRooms)
-	roomGroup.POST("/new", rh.CreateNewRoom)
-	roomGroup.GET("/ws", rh.HandleRoomWebSocket)
+	roomGroup.POST("/new", rh.CreateNewRoom, ah.RequireAuth)
+	roomGroup.GET("/code/:code", rh.ResolveInviteCode, ah.RequireAuth, inviteCodeLimiter())
+	roomGroup.GET("/ws", rh.HandleRoomWebSocket, ah.RequireAuth)
+	roomGroup.GET("/lobby/ws", rh.HandleLobbyWebSocket, ah.RequireAuth)
 
-	// WebSocket endpoint
+	// Game routes
 	gameGroup := apiRoutes.Group("/games")
-	gameGroup.GET("/ws", gh.HandleGameWebSocket)
+	gameGroup.GET("/ws", gh.HandleGameWebSocket, ah.RequireAuth)
+	gameGroup.GET("/:id/events", gh.GetEvents)
+	gameGroup.GET("/:id/replay/ws", gh.HandleReplayWebSocket, ah.RequireAuth)
+	gameGroup.GET("/:id/export", gh.ExportGame, ah.RequireAuth)
+	gameGroup.POST("/import", gh.ImportGame, ah.RequireAuth)
 }
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"time"
+)
+
+// MatchStats counts what a player did in matches
+type MatchStats struct {
+	ArmiesDestroyed int `json:"armies_destroyed"`
+	ArmiesLost      int `json:"armies_lost"`
+	Conquests       int `json:"conquests"`
+	Eliminations    int `json:"eliminations"`
+	Trades          int `json:"trades"`
+}
+
+func (s *MatchStats) add(other MatchStats) {
+	s.ArmiesDestroyed += other.ArmiesDestroyed
+	s.ArmiesLost += other.ArmiesLost
+	s.Conquests += other.Conquests
+	s.Eliminations += other.Eliminations
+	s.Trades += other.Trades
+}
+
+// MatchParticipant is a player of a finished match, bots included
+type MatchParticipant struct {
+	PlayerID    string `json:"player_id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	IsBot       bool   `json:"is_bot"`
+	ObjectiveID int    `json:"objective_id"`
+	Objective   string `json:"objective"`
+	Won         bool   `json:"won"`
+	MatchStats
+	RegionConquests map[string]int `json:"region_conquests,omitempty"` // Conquests by continent name
+}
+
+type MatchLogEntry struct {
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// MatchRecord is a finished game, participants are kept in seat order.
+// Events holds the game's event stream as encoded by the game server.
+type MatchRecord struct {
+	ID           string             `json:"id"`
+	RoomID       string             `json:"room_id"`
+	WinnerID     string             `json:"winner_id"`
+	StartedAt    time.Time          `json:"started_at"`
+	FinishedAt   time.Time          `json:"finished_at"`
+	Participants []MatchParticipant `json:"participants"`
+	Log          []MatchLogEntry    `json:"log"`
+	Events       json.RawMessage    `json:"events,omitempty"`
+}
+
+func (m *MatchRecord) Duration() time.Duration {
+	return m.FinishedAt.Sub(m.StartedAt)
+}
+
+func (m *MatchRecord) clone() *MatchRecord {
+	c := *m
+	c.Participants = slices.Clone(m.Participants)
+	for i := range c.Participants {
+		c.Participants[i].RegionConquests = maps.Clone(m.Participants[i].RegionConquests)
+	}
+	c.Log = slices.Clone(m.Log)
+	c.Events = slices.Clone(m.Events)
+	return &c
+}
+
+// ObjectiveRecord is how a player fared with one objective
+type ObjectiveRecord struct {
+	ObjectiveID int    `json:"objective_id"`
+	Objective   string `json:"objective"`
+	GamesPlayed int    `json:"games_played"`
+	Wins        int    `json:"wins"`
+}
+
+func (o ObjectiveRecord) WinRate() float64 {
+	if o.GamesPlayed == 0 {
+		return 0
+	}
+	return float64(o.Wins) / float64(o.GamesPlayed)
+}
+
+// PlayerProfile sums up the finished matches of a human player. Objectives
+// are ordered by ID and the favorite continent is the one they conquered
+// the most territories in.
+type PlayerProfile struct {
+	PlayerID    string `json:"player_id"`
+	Name        string `json:"name"`
+	GamesPlayed int    `json:"games_played"`
+	Wins        int    `json:"wins"`
+	Losses      int    `json:"losses"`
+	MatchStats
+	Objectives        []ObjectiveRecord `json:"objectives"`
+	FavoriteContinent string            `json:"favorite_continent,omitempty"`
+}
+
+func (p *PlayerProfile) WinRate() float64 {
+	if p.GamesPlayed == 0 {
+		return 0
+	}
+	return float64(p.Wins) / float64(p.GamesPlayed)
+}
+
+// favoriteRegion picks the continent with the most conquests, ties going to
+// the first name in alphabetical order
+func favoriteRegion(conquests map[string]int) string {
+	favorite := ""
+	for _, name := range slices.Sorted(maps.Keys(conquests)) {
+		if favorite == "" || conquests[name] > conquests[favorite] {
+			favorite = name
+		}
+	}
+	return favorite
+}
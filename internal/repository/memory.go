@@ -0,0 +1,441 @@
+package repository
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
+	"github.com/google/uuid"
+)
+
+type MemoryPlayerRepository struct {
+	sync.RWMutex
+	players map[uuid.UUID]*player.Player
+}
+
+func NewMemoryPlayerRepository() *MemoryPlayerRepository {
+	return &MemoryPlayerRepository{
+		players: make(map[uuid.UUID]*player.Player),
+	}
+}
+
+func (pr *MemoryPlayerRepository) Create(p *player.Player) error {
+	pr.Lock()
+	defer pr.Unlock()
+
+	if _, exists := pr.players[p.ID]; exists {
+		return ErrAlreadyExists
+	}
+
+	stored := *p
+	pr.players[p.ID] = &stored
+	return nil
+}
+
+func (pr *MemoryPlayerRepository) Get(id uuid.UUID) (*player.Player, error) {
+	pr.RLock()
+	defer pr.RUnlock()
+
+	p, exists := pr.players[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	cp := *p
+	return &cp, nil
+}
+
+func (pr *MemoryPlayerRepository) Update(id uuid.UUID, fn func(p *player.Player) error) error {
+	pr.Lock()
+	defer pr.Unlock()
+
+	p, exists := pr.players[id]
+	if !exists {
+		return ErrNotFound
+	}
+
+	updated := *p
+	if err := fn(&updated); err != nil {
+		return err
+	}
+
+	pr.players[id] = &updated
+	return nil
+}
+
+func (pr *MemoryPlayerRepository) Delete(id uuid.UUID) error {
+	pr.Lock()
+	defer pr.Unlock()
+
+	if _, exists := pr.players[id]; !exists {
+		return ErrNotFound
+	}
+
+	delete(pr.players, id)
+	return nil
+}
+
+type MemoryRoomRepository struct {
+	sync.RWMutex
+	rooms map[uuid.UUID]*room.Room
+	codes map[string]uuid.UUID
+	order []uuid.UUID
+}
+
+func NewMemoryRoomRepository() *MemoryRoomRepository {
+	return &MemoryRoomRepository{
+		rooms: make(map[uuid.UUID]*room.Room),
+		codes: make(map[string]uuid.UUID),
+	}
+}
+
+func (rr *MemoryRoomRepository) Create(r *room.Room) error {
+	rr.Lock()
+	defer rr.Unlock()
+
+	if _, exists := rr.rooms[r.RoomID]; exists {
+		return ErrAlreadyExists
+	}
+
+	if r.InviteCode != "" {
+		if _, taken := rr.codes[r.InviteCode]; taken {
+			return ErrInviteCodeConflict
+		}
+		rr.codes[r.InviteCode] = r.RoomID
+	}
+
+	rr.rooms[r.RoomID] = r.Clone()
+	rr.order = append(rr.order, r.RoomID)
+	return nil
+}
+
+func (rr *MemoryRoomRepository) Get(id uuid.UUID) (*room.Room, error) {
+	rr.RLock()
+	defer rr.RUnlock()
+
+	r, exists := rr.rooms[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	return r.Clone(), nil
+}
+
+func (rr *MemoryRoomRepository) GetByInviteCode(code string) (*room.Room, error) {
+	rr.RLock()
+	defer rr.RUnlock()
+
+	id, exists := rr.codes[room.NormalizeInviteCode(code)]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	return rr.rooms[id].Clone(), nil
+}
+
+func (rr *MemoryRoomRepository) List() ([]*room.Room, error) {
+	rr.RLock()
+	defer rr.RUnlock()
+
+	rooms := make([]*room.Room, 0, len(rr.order))
+	for _, id := range rr.order {
+		rooms = append(rooms, rr.rooms[id].Clone())
+	}
+	return rooms, nil
+}
+
+func (rr *MemoryRoomRepository) Update(id uuid.UUID, fn func(r *room.Room) error) error {
+	rr.Lock()
+	defer rr.Unlock()
+
+	r, exists := rr.rooms[id]
+	if !exists {
+		return ErrNotFound
+	}
+
+	updated := r.Clone()
+	if err := fn(updated); err != nil {
+		return err
+	}
+
+	if updated.InviteCode != r.InviteCode {
+		if owner, taken := rr.codes[updated.InviteCode]; taken && owner != id {
+			return ErrInviteCodeConflict
+		}
+		delete(rr.codes, r.InviteCode)
+		if updated.InviteCode != "" {
+			rr.codes[updated.InviteCode] = id
+		}
+	}
+
+	rr.rooms[id] = updated
+	return nil
+}
+
+func (rr *MemoryRoomRepository) Delete(id uuid.UUID) error {
+	rr.Lock()
+	defer rr.Unlock()
+
+	r, exists := rr.rooms[id]
+	if !exists {
+		return ErrNotFound
+	}
+
+	delete(rr.codes, r.InviteCode)
+	delete(rr.rooms, id)
+	rr.order = slices.DeleteFunc(rr.order, func(roomID uuid.UUID) bool {
+		return roomID == id
+	})
+	return nil
+}
+
+type MemoryGameRepository struct {
+	sync.RWMutex
+	games  map[string][]byte
+	events map[string][][]byte
+}
+
+func NewMemoryGameRepository() *MemoryGameRepository {
+	return &MemoryGameRepository{
+		games:  make(map[string][]byte),
+		events: make(map[string][][]byte),
+	}
+}
+
+func (gr *MemoryGameRepository) SaveGame(id string, snapshot []byte, eventsFrom int, events ...[]byte) error {
+	gr.Lock()
+	defer gr.Unlock()
+
+	gr.games[id] = slices.Clone(snapshot)
+
+	stored := gr.events[id]
+	stored = stored[:min(eventsFrom, len(stored)):min(eventsFrom, len(stored))]
+	for _, e := range events {
+		stored = append(stored, slices.Clone(e))
+	}
+	gr.events[id] = stored
+	return nil
+}
+
+func (gr *MemoryGameRepository) GetEvents(id string) ([][]byte, error) {
+	gr.RLock()
+	defer gr.RUnlock()
+
+	events := make([][]byte, len(gr.events[id]))
+	for i, e := range gr.events[id] {
+		events[i] = slices.Clone(e)
+	}
+	return events, nil
+}
+
+func (gr *MemoryGameRepository) GetGame(id string) ([]byte, error) {
+	gr.RLock()
+	defer gr.RUnlock()
+
+	snapshot, exists := gr.games[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return slices.Clone(snapshot), nil
+}
+
+func (gr *MemoryGameRepository) ListGames() ([]string, error) {
+	gr.RLock()
+	defer gr.RUnlock()
+
+	ids := slices.Collect(maps.Keys(gr.games))
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (gr *MemoryGameRepository) DeleteGame(id string) error {
+	gr.Lock()
+	defer gr.Unlock()
+
+	delete(gr.games, id)
+	delete(gr.events, id)
+	return nil
+}
+
+type MemoryHistoryRepository struct {
+	sync.RWMutex
+	matches  map[string]*MatchRecord
+	order    []string
+	profiles map[string]*memoryProfile
+}
+
+// memoryProfile keeps the per objective and per continent tallies a profile
+// is built from
+type memoryProfile struct {
+	PlayerProfile
+	objectives map[int]*ObjectiveRecord
+	regions    map[string]int
+}
+
+func NewMemoryHistoryRepository() *MemoryHistoryRepository {
+	return &MemoryHistoryRepository{
+		matches:  make(map[string]*MatchRecord),
+		profiles: make(map[string]*memoryProfile),
+	}
+}
+
+func (hr *MemoryHistoryRepository) RecordMatch(m *MatchRecord) error {
+	hr.Lock()
+	defer hr.Unlock()
+
+	if _, exists := hr.matches[m.ID]; exists {
+		return ErrAlreadyExists
+	}
+
+	hr.matches[m.ID] = m.clone()
+	hr.order = append(hr.order, m.ID)
+
+	for _, p := range m.Participants {
+		if p.IsBot {
+			continue
+		}
+
+		profile, exists := hr.profiles[p.PlayerID]
+		if !exists {
+			profile = &memoryProfile{
+				PlayerProfile: PlayerProfile{PlayerID: p.PlayerID},
+				objectives:    make(map[int]*ObjectiveRecord),
+				regions:       make(map[string]int),
+			}
+			hr.profiles[p.PlayerID] = profile
+		}
+
+		profile.Name = p.Name
+		profile.GamesPlayed++
+		if p.Won {
+			profile.Wins++
+		} else {
+			profile.Losses++
+		}
+		profile.MatchStats.add(p.MatchStats)
+
+		objective, exists := profile.objectives[p.ObjectiveID]
+		if !exists {
+			objective = &ObjectiveRecord{ObjectiveID: p.ObjectiveID}
+			profile.objectives[p.ObjectiveID] = objective
+		}
+		objective.Objective = p.Objective
+		objective.GamesPlayed++
+		if p.Won {
+			objective.Wins++
+		}
+
+		for region, conquests := range p.RegionConquests {
+			profile.regions[region] += conquests
+		}
+	}
+
+	return nil
+}
+
+func (hr *MemoryHistoryRepository) GetMatch(id string) (*MatchRecord, error) {
+	hr.RLock()
+	defer hr.RUnlock()
+
+	m, exists := hr.matches[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return m.clone(), nil
+}
+
+func (hr *MemoryHistoryRepository) ListPlayerMatches(playerID string, limit int) ([]*MatchRecord, error) {
+	hr.RLock()
+	defer hr.RUnlock()
+
+	matches := []*MatchRecord{}
+	for _, id := range slices.Backward(hr.order) {
+		m := hr.matches[id]
+		if !slices.ContainsFunc(m.Participants, func(p MatchParticipant) bool { return p.PlayerID == playerID }) {
+			continue
+		}
+
+		matches = append(matches, m.clone())
+		if limit > 0 && len(matches) == limit {
+			break
+		}
+	}
+	return matches, nil
+}
+
+func (hr *MemoryHistoryRepository) GetProfile(playerID string) (*PlayerProfile, error) {
+	hr.RLock()
+	defer hr.RUnlock()
+
+	profile, exists := hr.profiles[playerID]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	c := profile.PlayerProfile
+	c.Objectives = make([]ObjectiveRecord, 0, len(profile.objectives))
+	for _, id := range slices.Sorted(maps.Keys(profile.objectives)) {
+		c.Objectives = append(c.Objectives, *profile.objectives[id])
+	}
+	c.FavoriteContinent = favoriteRegion(profile.regions)
+	return &c, nil
+}
+
+type MemoryAccountRepository struct {
+	sync.RWMutex
+	byUsername map[string]*player.Account
+	byPlayer   map[uuid.UUID]*player.Account
+}
+
+func NewMemoryAccountRepository() *MemoryAccountRepository {
+	return &MemoryAccountRepository{
+		byUsername: make(map[string]*player.Account),
+		byPlayer:   make(map[uuid.UUID]*player.Account),
+	}
+}
+
+func (ar *MemoryAccountRepository) Create(a *player.Account) error {
+	ar.Lock()
+	defer ar.Unlock()
+
+	key := player.NormalizeUsername(a.Username)
+	if _, exists := ar.byUsername[key]; exists {
+		return ErrAlreadyExists
+	}
+	if _, exists := ar.byPlayer[a.PlayerID]; exists {
+		return ErrAlreadyExists
+	}
+
+	stored := *a
+	ar.byUsername[key] = &stored
+	ar.byPlayer[a.PlayerID] = &stored
+	return nil
+}
+
+func (ar *MemoryAccountRepository) GetByUsername(username string) (*player.Account, error) {
+	ar.RLock()
+	defer ar.RUnlock()
+
+	a, exists := ar.byUsername[player.NormalizeUsername(username)]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	c := *a
+	return &c, nil
+}
+
+func (ar *MemoryAccountRepository) GetByPlayer(playerID uuid.UUID) (*player.Account, error) {
+	ar.RLock()
+	defer ar.RUnlock()
+
+	a, exists := ar.byPlayer[playerID]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	c := *a
+	return &c, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
+)
+
+func TestMemoryPlayerRepository(t *testing.T) {
+	testPlayerRepository(t, NewMemoryPlayerRepository())
+}
+
+func TestMemoryRoomRepository_InviteCodes(t *testing.T) {
+	testRoomRepositoryInviteCodes(t, NewMemoryRoomRepository())
+}
+
+func TestMemoryRoomRepository_ListAndDelete(t *testing.T) {
+	testRoomRepositoryListAndDelete(t, NewMemoryRoomRepository())
+}
+
+func TestMemoryGameRepository(t *testing.T) {
+	testGameRepository(t, NewMemoryGameRepository())
+}
+
+// Run with -race: rooms are joined, read and listed from many goroutines
+func TestMemoryRoomRepository_Concurrent(t *testing.T) {
+	repo := NewMemoryRoomRepository()
+	r := newTestRoom(t, "Busy Room")
+	if err := repo.Create(r); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			p, _ := player.NewPlayer("Player")
+			_ = repo.Update(r.RoomID, func(r *room.Room) error {
+				r.AddPlayer(p)
+				return nil
+			})
+
+			if got, err := repo.Get(r.RoomID); err == nil {
+				_ = got.AvailableColors()
+			}
+			_, _ = repo.List()
+		}()
+	}
+	wg.Wait()
+
+	got, _ := repo.Get(r.RoomID)
+	if got.PlayerCount != 50 {
+		t.Errorf("PlayerCount = %d, want 50", got.PlayerCount)
+	}
+}
+
+func TestMemoryHistoryRepository(t *testing.T) {
+	testHistoryRepository(t, NewMemoryHistoryRepository())
+}
+
+func TestMemoryAccountRepository(t *testing.T) {
+	testAccountRepository(t, NewMemoryAccountRepository())
+}
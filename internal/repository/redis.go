@@ -0,0 +1,433 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Optimistic transactions are retried this many times when a watched key
+// changes before they commit
+const redisTxRetries = 10
+
+// NewRedisClient connects to the address in REDIS_URL, which can be a plain
+// host:port or a redis:// URL
+func NewRedisClient(redisURL string) (*redis.Client, error) {
+	var opts *redis.Options
+	if strings.Contains(redisURL, "://") {
+		parsed, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, err
+		}
+		opts = parsed
+	} else {
+		opts = &redis.Options{Addr: redisURL}
+	}
+
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("connecting to redis at %s: %w", opts.Addr, err)
+	}
+
+	return client, nil
+}
+
+// RedisStore builds the Redis repositories, every key is namespaced by prefix
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisStore) Players() *RedisPlayerRepository {
+	return &RedisPlayerRepository{store: s}
+}
+
+func (s *RedisStore) Rooms() *RedisRoomRepository {
+	return &RedisRoomRepository{store: s}
+}
+
+func (s *RedisStore) Games() *RedisGameRepository {
+	return &RedisGameRepository{store: s}
+}
+
+func (s *RedisStore) key(parts ...string) string {
+	return s.prefix + strings.Join(parts, ":")
+}
+
+// update runs fn inside an optimistic transaction watching keys, retrying
+// when another writer changes them first
+func (s *RedisStore) update(ctx context.Context, fn func(tx *redis.Tx) error, keys ...string) error {
+	for range redisTxRetries {
+		err := s.client.Watch(ctx, fn, keys...)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return err
+	}
+	return fmt.Errorf("redis transaction on %v kept conflicting", keys)
+}
+
+func getJSON(ctx context.Context, cmd redis.Cmdable, key string, v any) error {
+	data, err := cmd.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+type RedisPlayerRepository struct {
+	store *RedisStore
+}
+
+func (pr *RedisPlayerRepository) playerKey(id uuid.UUID) string {
+	return pr.store.key("player", id.String())
+}
+
+func (pr *RedisPlayerRepository) Create(p *player.Player) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	created, err := pr.store.client.SetNX(context.Background(), pr.playerKey(p.ID), data, 0).Result()
+	if err != nil {
+		return err
+	}
+	if !created {
+		return ErrAlreadyExists
+	}
+	return nil
+}
+
+func (pr *RedisPlayerRepository) Get(id uuid.UUID) (*player.Player, error) {
+	p := new(player.Player)
+	if err := getJSON(context.Background(), pr.store.client, pr.playerKey(id), p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (pr *RedisPlayerRepository) Update(id uuid.UUID, fn func(p *player.Player) error) error {
+	ctx := context.Background()
+	key := pr.playerKey(id)
+
+	return pr.store.update(ctx, func(tx *redis.Tx) error {
+		p := new(player.Player)
+		if err := getJSON(ctx, tx, key, p); err != nil {
+			return err
+		}
+
+		if err := fn(p); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+			return nil
+		})
+		return err
+	}, key)
+}
+
+func (pr *RedisPlayerRepository) Delete(id uuid.UUID) error {
+	deleted, err := pr.store.client.Del(context.Background(), pr.playerKey(id)).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RedisRoomRepository stores each room as JSON, with a sorted set keeping the
+// creation order and one key per invite code pointing to its room
+type RedisRoomRepository struct {
+	store *RedisStore
+}
+
+func (rr *RedisRoomRepository) roomKey(id uuid.UUID) string {
+	return rr.store.key("room", id.String())
+}
+
+func (rr *RedisRoomRepository) codeKey(code string) string {
+	return rr.store.key("room", "code", code)
+}
+
+func (rr *RedisRoomRepository) orderKey() string {
+	return rr.store.key("rooms")
+}
+
+func (rr *RedisRoomRepository) Create(r *room.Room) error {
+	ctx := context.Background()
+	key := rr.roomKey(r.RoomID)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	watched := []string{key}
+	if r.InviteCode != "" {
+		watched = append(watched, rr.codeKey(r.InviteCode))
+	}
+
+	return rr.store.update(ctx, func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, watched...).Result()
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			if tx.Exists(ctx, key).Val() > 0 {
+				return ErrAlreadyExists
+			}
+			return ErrInviteCodeConflict
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+			if r.InviteCode != "" {
+				pipe.Set(ctx, rr.codeKey(r.InviteCode), r.RoomID.String(), 0)
+			}
+			pipe.ZAdd(ctx, rr.orderKey(), redis.Z{
+				Score:  float64(time.Now().UnixNano()),
+				Member: r.RoomID.String(),
+			})
+			return nil
+		})
+		return err
+	}, watched...)
+}
+
+func (rr *RedisRoomRepository) Get(id uuid.UUID) (*room.Room, error) {
+	r := new(room.Room)
+	if err := getJSON(context.Background(), rr.store.client, rr.roomKey(id), r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (rr *RedisRoomRepository) GetByInviteCode(code string) (*room.Room, error) {
+	code = room.NormalizeInviteCode(code)
+	if code == "" {
+		return nil, ErrNotFound
+	}
+
+	rawID, err := rr.store.client.Get(context.Background(), rr.codeKey(code)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return rr.Get(id)
+}
+
+func (rr *RedisRoomRepository) List() ([]*room.Room, error) {
+	ctx := context.Background()
+
+	ids, err := rr.store.client.ZRange(ctx, rr.orderKey(), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rooms := make([]*room.Room, 0, len(ids))
+	for _, rawID := range ids {
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			continue
+		}
+
+		r, err := rr.Get(id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, r)
+	}
+	return rooms, nil
+}
+
+func (rr *RedisRoomRepository) Update(id uuid.UUID, fn func(r *room.Room) error) error {
+	ctx := context.Background()
+	key := rr.roomKey(id)
+
+	return rr.store.update(ctx, func(tx *redis.Tx) error {
+		r := new(room.Room)
+		if err := getJSON(ctx, tx, key, r); err != nil {
+			return err
+		}
+
+		oldCode := r.InviteCode
+		if err := fn(r); err != nil {
+			return err
+		}
+
+		codeChanged := r.InviteCode != oldCode
+		if codeChanged && r.InviteCode != "" {
+			newCodeKey := rr.codeKey(r.InviteCode)
+			if err := tx.Watch(ctx, newCodeKey).Err(); err != nil {
+				return err
+			}
+			if tx.Exists(ctx, newCodeKey).Val() > 0 {
+				return ErrInviteCodeConflict
+			}
+		}
+
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+			if codeChanged {
+				if oldCode != "" {
+					pipe.Del(ctx, rr.codeKey(oldCode))
+				}
+				if r.InviteCode != "" {
+					pipe.Set(ctx, rr.codeKey(r.InviteCode), id.String(), 0)
+				}
+			}
+			return nil
+		})
+		return err
+	}, key)
+}
+
+func (rr *RedisRoomRepository) Delete(id uuid.UUID) error {
+	ctx := context.Background()
+
+	r, err := rr.Get(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = rr.store.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, rr.roomKey(id))
+		if r.InviteCode != "" {
+			pipe.Del(ctx, rr.codeKey(r.InviteCode))
+		}
+		pipe.ZRem(ctx, rr.orderKey(), id.String())
+		return nil
+	})
+	return err
+}
+
+type RedisGameRepository struct {
+	store *RedisStore
+}
+
+func (gr *RedisGameRepository) gameKey(id string) string {
+	return gr.store.key("game", id)
+}
+
+func (gr *RedisGameRepository) eventsKey(id string) string {
+	return gr.store.key("game", id, "events")
+}
+
+func (gr *RedisGameRepository) indexKey() string {
+	return gr.store.key("games")
+}
+
+// SaveGame keeps the event log in a list, new events are pushed to it
+func (gr *RedisGameRepository) SaveGame(id string, snapshot []byte, eventsFrom int, events ...[]byte) error {
+	ctx := context.Background()
+
+	_, err := gr.store.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, gr.gameKey(id), snapshot, 0)
+		pipe.SAdd(ctx, gr.indexKey(), id)
+
+		if eventsFrom <= 0 {
+			pipe.Del(ctx, gr.eventsKey(id))
+		} else {
+			pipe.LTrim(ctx, gr.eventsKey(id), 0, int64(eventsFrom)-1)
+		}
+		if len(events) > 0 {
+			values := make([]any, len(events))
+			for i, e := range events {
+				values[i] = e
+			}
+			pipe.RPush(ctx, gr.eventsKey(id), values...)
+		}
+		return nil
+	})
+	return err
+}
+
+func (gr *RedisGameRepository) GetEvents(id string) ([][]byte, error) {
+	stored, err := gr.store.client.LRange(context.Background(), gr.eventsKey(id), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([][]byte, len(stored))
+	for i, e := range stored {
+		events[i] = []byte(e)
+	}
+	return events, nil
+}
+
+func (gr *RedisGameRepository) GetGame(id string) ([]byte, error) {
+	snapshot, err := gr.store.client.Get(context.Background(), gr.gameKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	return snapshot, err
+}
+
+func (gr *RedisGameRepository) ListGames() ([]string, error) {
+	ids, err := gr.store.client.SMembers(context.Background(), gr.indexKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (gr *RedisGameRepository) DeleteGame(id string) error {
+	ctx := context.Background()
+
+	_, err := gr.store.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, gr.gameKey(id), gr.eventsKey(id))
+		pipe.SRem(ctx, gr.indexKey(), id)
+		return nil
+	})
+	return err
+}
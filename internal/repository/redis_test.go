@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisStore uses the Redis at REDIS_HOST when it is set, as in CI,
+// and an in-process stand-in otherwise
+func newTestRedisStore(t *testing.T) *RedisStore {
+	t.Helper()
+
+	host := os.Getenv("REDIS_HOST")
+	if host == "" {
+		client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+		t.Cleanup(func() { client.Close() })
+		return NewRedisStore(client, "war:")
+	}
+
+	port := os.Getenv("REDIS_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	client, err := NewRedisClient(host + ":" + port)
+	if err != nil {
+		t.Fatalf("NewRedisClient() error = %v", err)
+	}
+
+	// Each test gets its own namespace so runs never see each other's keys
+	prefix := "war-test:" + uuid.NewString() + ":"
+	t.Cleanup(func() {
+		ctx := context.Background()
+		keys, _ := client.Keys(ctx, prefix+"*").Result()
+		if len(keys) > 0 {
+			client.Del(ctx, keys...)
+		}
+		client.Close()
+	})
+
+	return NewRedisStore(client, prefix)
+}
+
+func TestRedisPlayerRepository(t *testing.T) {
+	testPlayerRepository(t, newTestRedisStore(t).Players())
+}
+
+func TestRedisRoomRepository_InviteCodes(t *testing.T) {
+	testRoomRepositoryInviteCodes(t, newTestRedisStore(t).Rooms())
+}
+
+func TestRedisRoomRepository_ListAndDelete(t *testing.T) {
+	testRoomRepositoryListAndDelete(t, newTestRedisStore(t).Rooms())
+}
+
+func TestRedisGameRepository(t *testing.T) {
+	testGameRepository(t, newTestRedisStore(t).Games())
+}
+
+func TestRedisRoomRepository_RoundTrip(t *testing.T) {
+	repo := newTestRedisStore(t).Rooms()
+
+	r := newTestRoom(t, "Stored Room")
+	p, _ := player.NewPlayer("Alice")
+	r.AddPlayer(p)
+	if err := r.ChooseColor(p.ID, player.Palette[2]); err != nil {
+		t.Fatalf("ChooseColor() error = %v", err)
+	}
+	if err := r.SetPassword("secret"); err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+
+	if err := repo.Create(r); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := repo.Get(r.RoomID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if !got.HasPlayer(p.ID) || got.Colors[p.ID] != player.Palette[2] {
+		t.Errorf("stored room lost its players or colors: %+v", got)
+	}
+	if err := got.CheckAccess(uuid.New(), got.InviteCode); err != nil {
+		t.Errorf("CheckAccess() on the stored room error = %v", err)
+	}
+	if err := got.CheckPassword("secret"); err != nil {
+		t.Errorf("CheckPassword() on the stored room error = %v", err)
+	}
+}
+
+// Concurrent joins go through WATCH transactions and must not lose updates
+func TestRedisRoomRepository_ConcurrentUpdates(t *testing.T) {
+	repo := newTestRedisStore(t).Rooms()
+
+	r := newTestRoom(t, "Busy Room")
+	if err := repo.Create(r); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			p, _ := player.NewPlayer("Player")
+			if err := repo.Update(r.RoomID, func(r *room.Room) error {
+				r.AddPlayer(p)
+				return nil
+			}); err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, _ := repo.Get(r.RoomID)
+	if got.PlayerCount != 5 {
+		t.Errorf("PlayerCount = %d, want 5", got.PlayerCount)
+	}
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"errors"
+
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrAlreadyExists      = errors.New("already exists")
+	ErrInviteCodeConflict = errors.New("invite code already in use")
+)
+
+// PlayerRepository stores players. Implementations must be safe for
+// concurrent use and never hand out pointers to their stored values.
+type PlayerRepository interface {
+	Create(p *player.Player) error
+	Get(id uuid.UUID) (*player.Player, error)
+	// Update applies fn to the stored player atomically, nothing is saved if fn fails
+	Update(id uuid.UUID, fn func(p *player.Player) error) error
+	Delete(id uuid.UUID) error
+}
+
+// RoomRepository stores rooms. Implementations must be safe for concurrent
+// use and never hand out pointers to their stored values.
+type RoomRepository interface {
+	Create(r *room.Room) error
+	Get(id uuid.UUID) (*room.Room, error)
+	GetByInviteCode(code string) (*room.Room, error)
+	// List returns every room in creation order
+	List() ([]*room.Room, error)
+	// Update applies fn to the stored room atomically, nothing is saved if fn fails
+	Update(id uuid.UUID, fn func(r *room.Room) error) error
+	Delete(id uuid.UUID) error
+}
+
+// GameRepository stores serialized snapshots of running games, keyed by
+// their room ID
+type GameRepository interface {
+	// SaveGame replaces the snapshot of a game and updates its event log,
+	// keeping the first eventsFrom stored events and appending events after
+	// them, so each save only writes the events that are new
+	SaveGame(id string, snapshot []byte, eventsFrom int, events ...[]byte) error
+	GetGame(id string) ([]byte, error)
+	// GetEvents returns the event log of a game, empty when it has none
+	GetEvents(id string) ([][]byte, error)
+	ListGames() ([]string, error)
+	DeleteGame(id string) error
+}
+
+// HistoryRepository keeps finished matches and the profiles of the human
+// players who took part in them
+type HistoryRepository interface {
+	// RecordMatch stores a match and updates the profile of every human participant
+	RecordMatch(m *MatchRecord) error
+	GetMatch(id string) (*MatchRecord, error)
+	// ListPlayerMatches returns the matches a player took part in, most recent
+	// first. A limit of zero or less returns all of them.
+	ListPlayerMatches(playerID string, limit int) ([]*MatchRecord, error)
+	GetProfile(playerID string) (*PlayerProfile, error)
+}
+
+// AccountRepository stores the logins of registered players. Usernames are
+// unique regardless of case and each player has at most one account.
+type AccountRepository interface {
+	// Create returns ErrAlreadyExists when the username or the player is taken
+	Create(a *player.Account) error
+	GetByUsername(username string) (*player.Account, error)
+	GetByPlayer(playerID uuid.UUID) (*player.Account, error)
+}
+
+// ListPublicRooms returns the rooms visible in the lobby listing
+func ListPublicRooms(rooms RoomRepository) ([]*room.Room, error) {
+	all, err := rooms.List()
+	if err != nil {
+		return nil, err
+	}
+
+	public := make([]*room.Room, 0, len(all))
+	for _, r := range all {
+		if !r.Private {
+			public = append(public, r)
+		}
+	}
+	return public, nil
+}
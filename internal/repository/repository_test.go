@@ -0,0 +1,358 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
+	"github.com/google/uuid"
+)
+
+func newTestRoom(t *testing.T, name string) *room.Room {
+	t.Helper()
+
+	r, err := room.NewRoom(name, uuid.New(), "Owner", room.DefaultSettings())
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	return r
+}
+
+func testPlayerRepository(t *testing.T, repo PlayerRepository) {
+	p, _ := player.NewPlayer("Alice")
+
+	if err := repo.Create(p); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Create(p); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Create() twice error = %v, want %v", err, ErrAlreadyExists)
+	}
+
+	got, err := repo.Get(p.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	// Changing a returned player must not change the stored one
+	got.Name = "Mallory"
+	if stored, _ := repo.Get(p.ID); stored.Name != "Alice" {
+		t.Errorf("stored Name = %s after modifying a copy, want Alice", stored.Name)
+	}
+
+	failing := repo.Update(p.ID, func(p *player.Player) error {
+		p.Name = "Bob"
+		return fmt.Errorf("rejected")
+	})
+	if failing == nil {
+		t.Error("Update() should return the error of fn")
+	}
+	if stored, _ := repo.Get(p.ID); stored.Name != "Alice" {
+		t.Errorf("stored Name = %s after failed update, want Alice", stored.Name)
+	}
+
+	if err := repo.Delete(p.ID); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if _, err := repo.Get(p.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testRoomRepositoryInviteCodes(t *testing.T, repo RoomRepository) {
+	first := newTestRoom(t, "First")
+	second := newTestRoom(t, "Second")
+
+	if err := repo.Create(first); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	second.InviteCode = first.InviteCode
+	if err := repo.Create(second); !errors.Is(err, ErrInviteCodeConflict) {
+		t.Errorf("Create() with a used code error = %v, want %v", err, ErrInviteCodeConflict)
+	}
+
+	second.RegenerateInviteCode()
+	if err := repo.Create(second); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := repo.GetByInviteCode(" " + first.InviteCode + " ")
+	if err != nil || got.RoomID != first.RoomID {
+		t.Errorf("GetByInviteCode() = %v, %v, want room %s", got, err, first.RoomID)
+	}
+
+	var newCode string
+	err = repo.Update(first.RoomID, func(r *room.Room) error {
+		newCode = r.RegenerateInviteCode()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if _, err := repo.GetByInviteCode(first.InviteCode); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByInviteCode() with a replaced code error = %v, want %v", err, ErrNotFound)
+	}
+	if got, _ := repo.GetByInviteCode(newCode); got == nil || got.RoomID != first.RoomID {
+		t.Error("GetByInviteCode() did not resolve the regenerated code")
+	}
+
+	err = repo.Update(first.RoomID, func(r *room.Room) error {
+		r.InviteCode = second.InviteCode
+		return nil
+	})
+	if !errors.Is(err, ErrInviteCodeConflict) {
+		t.Errorf("Update() to a used code error = %v, want %v", err, ErrInviteCodeConflict)
+	}
+}
+
+func testRoomRepositoryListAndDelete(t *testing.T, repo RoomRepository) {
+	names := []string{"A", "B", "C"}
+	ids := make([]uuid.UUID, 0, len(names))
+	for _, name := range names {
+		r := newTestRoom(t, name)
+		r.Private = name == "B"
+		if err := repo.Create(r); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		ids = append(ids, r.RoomID)
+	}
+
+	public, _ := ListPublicRooms(repo)
+	if len(public) != 2 || public[0].Name != "A" || public[1].Name != "C" {
+		t.Errorf("ListPublicRooms() returned %d rooms, want A and C in order", len(public))
+	}
+
+	if err := repo.Delete(ids[0]); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	all, _ := repo.List()
+	if len(all) != 2 || all[0].Name != "B" {
+		t.Errorf("List() after Delete() returned %d rooms, want B and C", len(all))
+	}
+
+	if err := repo.Update(ids[0], func(r *room.Room) error { return nil }); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() of a deleted room error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testGameRepository(t *testing.T, repo GameRepository) {
+	if _, err := repo.GetGame("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetGame() of a missing game error = %v, want %v", err, ErrNotFound)
+	}
+
+	for _, id := range []string{"b", "a"} {
+		if err := repo.SaveGame(id, []byte(`{"room_id":"`+id+`"}`), 0); err != nil {
+			t.Fatalf("SaveGame() error = %v", err)
+		}
+	}
+
+	if err := repo.SaveGame("a", []byte(`{"turn_number":2}`), 0, []byte("1"), []byte("2")); err != nil {
+		t.Fatalf("SaveGame() error = %v", err)
+	}
+
+	snapshot, err := repo.GetGame("a")
+	if err != nil || string(snapshot) != `{"turn_number":2}` {
+		t.Errorf("GetGame() = %s, %v, want the latest snapshot", snapshot, err)
+	}
+
+	// Saves append to the event log, and replace the events after eventsFrom
+	repo.SaveGame("a", []byte(`{"turn_number":3}`), 2, []byte("3"))
+	repo.SaveGame("a", []byte(`{"turn_number":3}`), 3)
+	if events, err := repo.GetEvents("a"); err != nil || string(bytes.Join(events, nil)) != "123" {
+		t.Errorf("GetEvents() = %q, %v, want [1 2 3]", events, err)
+	}
+	repo.SaveGame("a", []byte(`{"turn_number":3}`), 1, []byte("4"))
+	if events, _ := repo.GetEvents("a"); string(bytes.Join(events, nil)) != "14" {
+		t.Errorf("GetEvents() = %q, want [1 4]", events)
+	}
+	repo.SaveGame("a", []byte(`{"turn_number":1}`), 0)
+	if events, _ := repo.GetEvents("a"); len(events) != 0 {
+		t.Errorf("GetEvents() = %q after saving from 0, want none", events)
+	}
+	if events, err := repo.GetEvents("missing"); err != nil || len(events) != 0 {
+		t.Errorf("GetEvents() of a missing game = %q, %v, want none", events, err)
+	}
+
+	ids, _ := repo.ListGames()
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("ListGames() = %v, want [a b]", ids)
+	}
+
+	repo.SaveGame("a", []byte(`{"turn_number":2}`), 0, []byte("1"))
+	if err := repo.DeleteGame("a"); err != nil {
+		t.Fatalf("DeleteGame() error = %v", err)
+	}
+	if events, _ := repo.GetEvents("a"); len(events) != 0 {
+		t.Errorf("GetEvents() after DeleteGame() = %q, want none", events)
+	}
+	if ids, _ := repo.ListGames(); len(ids) != 1 {
+		t.Errorf("ListGames() after DeleteGame() = %v, want [b]", ids)
+	}
+}
+
+func newTestMatch(id string, finishedAt time.Time, winner string, players ...string) *MatchRecord {
+	m := &MatchRecord{
+		ID:         id,
+		RoomID:     "room-" + id,
+		WinnerID:   winner,
+		StartedAt:  finishedAt.Add(-30 * time.Minute),
+		FinishedAt: finishedAt,
+		Log: []MatchLogEntry{
+			{Message: "Partida iniciada.", Timestamp: finishedAt.Add(-30 * time.Minute)},
+			{Message: "Fim de jogo.", Timestamp: finishedAt},
+		},
+		Events: json.RawMessage(`[{"seq":1,"type":"game_started"}]`),
+	}
+
+	for _, p := range players {
+		m.Participants = append(m.Participants, MatchParticipant{
+			PlayerID:    p,
+			Name:        "Player " + p,
+			Color:       player.Palette[len(m.Participants)],
+			ObjectiveID: 6,
+			Objective:   "Conquistar 24 TERRITÓRIOS à sua escolha.",
+			Won:         p == winner,
+		})
+	}
+	m.Participants = append(m.Participants, MatchParticipant{PlayerID: "bot-" + id, Name: "Bot 1", IsBot: true})
+
+	return m
+}
+
+func testHistoryRepository(t *testing.T, repo HistoryRepository) {
+	now := time.Now().Truncate(time.Millisecond)
+
+	first := newTestMatch("m1", now.Add(-2*time.Hour), "alice", "alice", "bob")
+	second := newTestMatch("m2", now.Add(-time.Hour), "bob", "alice", "bob")
+	third := newTestMatch("m3", now, "carol", "bob", "carol")
+
+	// bob wins m2 with another objective
+	second.Participants[1].ObjectiveID = 1
+	second.Participants[1].Objective = "Conquistar na totalidade a ÁSIA e a AMÉRICA DO SUL."
+	second.Participants[1].MatchStats = MatchStats{ArmiesDestroyed: 5, ArmiesLost: 3, Conquests: 2, Eliminations: 1, Trades: 1}
+	second.Participants[1].RegionConquests = map[string]int{"Ásia": 2}
+	third.Participants[0].MatchStats = MatchStats{ArmiesDestroyed: 1, ArmiesLost: 4, Conquests: 1}
+	third.Participants[0].RegionConquests = map[string]int{"Europa": 1}
+
+	for _, m := range []*MatchRecord{first, second, third} {
+		if err := repo.RecordMatch(m); err != nil {
+			t.Fatalf("RecordMatch(%s) error = %v", m.ID, err)
+		}
+	}
+
+	if err := repo.RecordMatch(first); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("RecordMatch() twice error = %v, want %v", err, ErrAlreadyExists)
+	}
+
+	got, err := repo.GetMatch("m1")
+	if err != nil {
+		t.Fatalf("GetMatch() error = %v", err)
+	}
+	if got.WinnerID != "alice" || got.Duration() != 30*time.Minute || !got.FinishedAt.Equal(first.FinishedAt) {
+		t.Errorf("GetMatch() = %+v, want the recorded match", got)
+	}
+	if len(got.Participants) != 3 || got.Participants[0].PlayerID != "alice" || !got.Participants[2].IsBot {
+		t.Errorf("GetMatch() participants = %+v, want alice, bob and a bot in seat order", got.Participants)
+	}
+	if len(got.Log) != 2 || got.Log[1].Message != "Fim de jogo." {
+		t.Errorf("GetMatch() log = %+v, want the recorded log", got.Log)
+	}
+	if string(got.Events) != string(first.Events) {
+		t.Errorf("GetMatch() events = %s, want %s", got.Events, first.Events)
+	}
+
+	if got, _ := repo.GetMatch("m2"); got == nil ||
+		got.Participants[1].MatchStats != second.Participants[1].MatchStats ||
+		got.Participants[1].RegionConquests["Ásia"] != 2 || got.Participants[0].RegionConquests != nil {
+		t.Errorf("GetMatch() did not keep the participants' stats: %+v", got)
+	}
+
+	if _, err := repo.GetMatch("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetMatch() of a missing match error = %v, want %v", err, ErrNotFound)
+	}
+
+	matches, _ := repo.ListPlayerMatches("bob", 0)
+	if len(matches) != 3 || matches[0].ID != "m3" || matches[2].ID != "m1" {
+		t.Errorf("ListPlayerMatches() returned %d matches, want m3, m2, m1", len(matches))
+	}
+	if matches, _ := repo.ListPlayerMatches("alice", 1); len(matches) != 1 || matches[0].ID != "m2" {
+		t.Errorf("ListPlayerMatches() with limit 1 = %v, want only m2", matches)
+	}
+
+	profile, err := repo.GetProfile("bob")
+	if err != nil {
+		t.Fatalf("GetProfile() error = %v", err)
+	}
+	if profile.GamesPlayed != 3 || profile.Wins != 1 || profile.Losses != 2 || profile.Name != "Player bob" {
+		t.Errorf("GetProfile() = %+v, want 3 games, 1 win and 2 losses", profile)
+	}
+
+	wantStats := MatchStats{ArmiesDestroyed: 6, ArmiesLost: 7, Conquests: 3, Eliminations: 1, Trades: 1}
+	if profile.MatchStats != wantStats {
+		t.Errorf("GetProfile() stats = %+v, want %+v", profile.MatchStats, wantStats)
+	}
+	if profile.FavoriteContinent != "Ásia" {
+		t.Errorf("GetProfile() favorite continent = %q, want Ásia", profile.FavoriteContinent)
+	}
+
+	wantObjectives := []ObjectiveRecord{
+		{ObjectiveID: 1, Objective: second.Participants[1].Objective, GamesPlayed: 1, Wins: 1},
+		{ObjectiveID: 6, Objective: first.Participants[1].Objective, GamesPlayed: 2, Wins: 0},
+	}
+	if !slices.Equal(profile.Objectives, wantObjectives) {
+		t.Errorf("GetProfile() objectives = %+v, want %+v", profile.Objectives, wantObjectives)
+	}
+
+	// Bots never get a profile
+	if _, err := repo.GetProfile("bot-m1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetProfile() of a bot error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testAccountRepository(t *testing.T, repo AccountRepository) {
+	a, err := player.NewAccount(uuid.New(), "Alice", "correct horse")
+	if err != nil {
+		t.Fatalf("NewAccount() error = %v", err)
+	}
+
+	if err := repo.Create(a); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := repo.GetByUsername("ALICE")
+	if err != nil {
+		t.Fatalf("GetByUsername() error = %v", err)
+	}
+	if got.PlayerID != a.PlayerID || got.Username != "Alice" || !got.CheckPassword("correct horse") {
+		t.Errorf("GetByUsername() = %+v, want %+v", got, a)
+	}
+
+	if got, err := repo.GetByPlayer(a.PlayerID); err != nil || got.Username != "Alice" {
+		t.Errorf("GetByPlayer() = %+v, %v, want Alice", got, err)
+	}
+
+	sameName, _ := player.NewAccount(uuid.New(), "alice", "correct horse")
+	if err := repo.Create(sameName); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Create() with a taken username error = %v, want %v", err, ErrAlreadyExists)
+	}
+
+	samePlayer, _ := player.NewAccount(a.PlayerID, "bob", "correct horse")
+	if err := repo.Create(samePlayer); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Create() for a player with an account error = %v, want %v", err, ErrAlreadyExists)
+	}
+
+	if _, err := repo.GetByUsername("bob"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByUsername() of a missing account error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := repo.GetByPlayer(uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByPlayer() of a missing account error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,470 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"es2.uff/war-server/internal/domain/player"
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// migrations are applied in order on boot, each exactly once. Schema changes
+// are appended as new entries, never edited in place.
+var migrations = []string{
+	`CREATE TABLE matches (
+		id          TEXT PRIMARY KEY,
+		room_id     TEXT NOT NULL,
+		winner_id   TEXT NOT NULL,
+		started_at  INTEGER NOT NULL,
+		finished_at INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL
+	);
+
+	CREATE TABLE match_participants (
+		match_id     TEXT NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+		seat         INTEGER NOT NULL,
+		player_id    TEXT NOT NULL,
+		name         TEXT NOT NULL,
+		color        TEXT NOT NULL,
+		is_bot       INTEGER NOT NULL,
+		objective_id INTEGER NOT NULL,
+		objective    TEXT NOT NULL,
+		won          INTEGER NOT NULL,
+		PRIMARY KEY (match_id, player_id)
+	);
+
+	CREATE INDEX match_participants_player ON match_participants(player_id);
+
+	CREATE TABLE match_log (
+		match_id   TEXT NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+		seq        INTEGER NOT NULL,
+		message    TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		PRIMARY KEY (match_id, seq)
+	);
+
+	CREATE TABLE player_profiles (
+		player_id    TEXT PRIMARY KEY,
+		name         TEXT NOT NULL,
+		games_played INTEGER NOT NULL DEFAULT 0,
+		wins         INTEGER NOT NULL DEFAULT 0,
+		losses       INTEGER NOT NULL DEFAULT 0
+	);`,
+
+	`ALTER TABLE matches ADD COLUMN events TEXT NOT NULL DEFAULT '[]';`,
+
+	`CREATE TABLE accounts (
+		player_id     TEXT PRIMARY KEY,
+		username      TEXT NOT NULL,
+		username_key  TEXT NOT NULL UNIQUE,
+		password_hash BLOB NOT NULL,
+		created_at    INTEGER NOT NULL
+	);`,
+
+	`ALTER TABLE match_participants ADD COLUMN armies_destroyed INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE match_participants ADD COLUMN armies_lost INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE match_participants ADD COLUMN conquests INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE match_participants ADD COLUMN eliminations INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE match_participants ADD COLUMN trades INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE match_participants ADD COLUMN region_conquests TEXT NOT NULL DEFAULT '{}';`,
+}
+
+// OpenSQLite opens the database file at path, creating it if needed, and
+// brings its schema up to date
+func OpenSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer, sharing one connection avoids busy errors
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for i := current; i < len(migrations); i++ {
+		version := i + 1
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("applying migration %d: %w", version, err)
+		}
+
+		if _, err := tx.Exec(
+			`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+			version, time.Now().UnixMilli(),
+		); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type SQLiteHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteHistoryRepository(db *sql.DB) *SQLiteHistoryRepository {
+	return &SQLiteHistoryRepository{db: db}
+}
+
+func (hr *SQLiteHistoryRepository) RecordMatch(m *MatchRecord) error {
+	tx, err := hr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM matches WHERE id = ?`, m.ID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrAlreadyExists
+	}
+
+	events := "[]"
+	if len(m.Events) > 0 {
+		events = string(m.Events)
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO matches (id, room_id, winner_id, started_at, finished_at, duration_ms, events)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		m.ID, m.RoomID, m.WinnerID,
+		m.StartedAt.UnixMilli(), m.FinishedAt.UnixMilli(), m.Duration().Milliseconds(), events,
+	); err != nil {
+		return err
+	}
+
+	for seat, p := range m.Participants {
+		regions, err := json.Marshal(p.RegionConquests)
+		if err != nil {
+			return err
+		}
+		if p.RegionConquests == nil {
+			regions = []byte("{}")
+		}
+
+		if _, err := tx.Exec(
+			`INSERT INTO match_participants
+			(match_id, seat, player_id, name, color, is_bot, objective_id, objective, won,
+			armies_destroyed, armies_lost, conquests, eliminations, trades, region_conquests)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			m.ID, seat, p.PlayerID, p.Name, p.Color, p.IsBot, p.ObjectiveID, p.Objective, p.Won,
+			p.ArmiesDestroyed, p.ArmiesLost, p.Conquests, p.Eliminations, p.Trades, string(regions),
+		); err != nil {
+			return err
+		}
+
+		if p.IsBot {
+			continue
+		}
+
+		wins, losses := 0, 1
+		if p.Won {
+			wins, losses = 1, 0
+		}
+
+		if _, err := tx.Exec(
+			`INSERT INTO player_profiles (player_id, name, games_played, wins, losses)
+			VALUES (?, ?, 1, ?, ?)
+			ON CONFLICT (player_id) DO UPDATE SET
+				name = excluded.name,
+				games_played = games_played + 1,
+				wins = wins + excluded.wins,
+				losses = losses + excluded.losses`,
+			p.PlayerID, p.Name, wins, losses,
+		); err != nil {
+			return err
+		}
+	}
+
+	for seq, entry := range m.Log {
+		if _, err := tx.Exec(
+			`INSERT INTO match_log (match_id, seq, message, created_at) VALUES (?, ?, ?, ?)`,
+			m.ID, seq, entry.Message, entry.Timestamp.UnixMilli(),
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (hr *SQLiteHistoryRepository) GetMatch(id string) (*MatchRecord, error) {
+	m := &MatchRecord{
+		Participants: []MatchParticipant{},
+		Log:          []MatchLogEntry{},
+	}
+
+	var startedAt, finishedAt int64
+	var events string
+	err := hr.db.QueryRow(
+		`SELECT id, room_id, winner_id, started_at, finished_at, events FROM matches WHERE id = ?`, id,
+	).Scan(&m.ID, &m.RoomID, &m.WinnerID, &startedAt, &finishedAt, &events)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.StartedAt = time.UnixMilli(startedAt)
+	m.FinishedAt = time.UnixMilli(finishedAt)
+	m.Events = json.RawMessage(events)
+
+	participants, err := hr.db.Query(
+		`SELECT player_id, name, color, is_bot, objective_id, objective, won,
+		armies_destroyed, armies_lost, conquests, eliminations, trades, region_conquests
+		FROM match_participants WHERE match_id = ? ORDER BY seat`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows are closed before the next query since there is one connection
+	for participants.Next() {
+		var p MatchParticipant
+		var regions string
+		if err := participants.Scan(
+			&p.PlayerID, &p.Name, &p.Color, &p.IsBot, &p.ObjectiveID, &p.Objective, &p.Won,
+			&p.ArmiesDestroyed, &p.ArmiesLost, &p.Conquests, &p.Eliminations, &p.Trades, &regions,
+		); err != nil {
+			participants.Close()
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(regions), &p.RegionConquests); err != nil {
+			participants.Close()
+			return nil, err
+		}
+		if len(p.RegionConquests) == 0 {
+			p.RegionConquests = nil
+		}
+		m.Participants = append(m.Participants, p)
+	}
+	participants.Close()
+	if err := participants.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := hr.db.Query(
+		`SELECT message, created_at FROM match_log WHERE match_id = ? ORDER BY seq`, id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer entries.Close()
+
+	for entries.Next() {
+		var entry MatchLogEntry
+		var createdAt int64
+		if err := entries.Scan(&entry.Message, &createdAt); err != nil {
+			return nil, err
+		}
+		entry.Timestamp = time.UnixMilli(createdAt)
+		m.Log = append(m.Log, entry)
+	}
+
+	return m, entries.Err()
+}
+
+func (hr *SQLiteHistoryRepository) ListPlayerMatches(playerID string, limit int) ([]*MatchRecord, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := hr.db.Query(
+		`SELECT m.id FROM matches m
+		JOIN match_participants p ON p.match_id = m.id
+		WHERE p.player_id = ?
+		ORDER BY m.finished_at DESC, m.rowid DESC
+		LIMIT ?`, playerID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	matches := make([]*MatchRecord, 0, len(ids))
+	for _, id := range ids {
+		m, err := hr.GetMatch(id)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+func (hr *SQLiteHistoryRepository) GetProfile(playerID string) (*PlayerProfile, error) {
+	p := new(PlayerProfile)
+	err := hr.db.QueryRow(
+		`SELECT player_id, name, games_played, wins, losses FROM player_profiles WHERE player_id = ?`,
+		playerID,
+	).Scan(&p.PlayerID, &p.Name, &p.GamesPlayed, &p.Wins, &p.Losses)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Bots have no profile, so only human participants are summed here
+	err = hr.db.QueryRow(
+		`SELECT COALESCE(SUM(armies_destroyed), 0), COALESCE(SUM(armies_lost), 0),
+		COALESCE(SUM(conquests), 0), COALESCE(SUM(eliminations), 0), COALESCE(SUM(trades), 0)
+		FROM match_participants WHERE player_id = ?`, playerID,
+	).Scan(&p.ArmiesDestroyed, &p.ArmiesLost, &p.Conquests, &p.Eliminations, &p.Trades)
+	if err != nil {
+		return nil, err
+	}
+
+	objectives, err := hr.db.Query(
+		`SELECT objective_id, MAX(objective), COUNT(*), SUM(won)
+		FROM match_participants WHERE player_id = ?
+		GROUP BY objective_id ORDER BY objective_id`, playerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Objectives = []ObjectiveRecord{}
+	for objectives.Next() {
+		var o ObjectiveRecord
+		if err := objectives.Scan(&o.ObjectiveID, &o.Objective, &o.GamesPlayed, &o.Wins); err != nil {
+			objectives.Close()
+			return nil, err
+		}
+		p.Objectives = append(p.Objectives, o)
+	}
+	objectives.Close()
+	if err := objectives.Err(); err != nil {
+		return nil, err
+	}
+
+	regions, err := hr.db.Query(
+		`SELECT r.key, SUM(r.value)
+		FROM match_participants p, json_each(p.region_conquests) r
+		WHERE p.player_id = ? GROUP BY r.key`, playerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer regions.Close()
+
+	conquests := make(map[string]int)
+	for regions.Next() {
+		var name string
+		var count int
+		if err := regions.Scan(&name, &count); err != nil {
+			return nil, err
+		}
+		conquests[name] = count
+	}
+	p.FavoriteContinent = favoriteRegion(conquests)
+
+	return p, regions.Err()
+}
+
+type SQLiteAccountRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteAccountRepository(db *sql.DB) *SQLiteAccountRepository {
+	return &SQLiteAccountRepository{db: db}
+}
+
+func (ar *SQLiteAccountRepository) Create(a *player.Account) error {
+	_, err := ar.db.Exec(
+		`INSERT INTO accounts (player_id, username, username_key, password_hash, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		a.PlayerID.String(), a.Username, player.NormalizeUsername(a.Username),
+		a.PasswordHash, a.CreatedAt.UnixMilli(),
+	)
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return ErrAlreadyExists
+		}
+	}
+	return err
+}
+
+func (ar *SQLiteAccountRepository) GetByUsername(username string) (*player.Account, error) {
+	return ar.get(`WHERE username_key = ?`, player.NormalizeUsername(username))
+}
+
+func (ar *SQLiteAccountRepository) GetByPlayer(playerID uuid.UUID) (*player.Account, error) {
+	return ar.get(`WHERE player_id = ?`, playerID.String())
+}
+
+func (ar *SQLiteAccountRepository) get(where string, arg any) (*player.Account, error) {
+	a := new(player.Account)
+	var playerID string
+	var createdAt int64
+
+	err := ar.db.QueryRow(
+		`SELECT player_id, username, password_hash, created_at FROM accounts `+where, arg,
+	).Scan(&playerID, &a.Username, &a.PasswordHash, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if a.PlayerID, err = uuid.Parse(playerID); err != nil {
+		return nil, err
+	}
+	a.CreatedAt = time.UnixMilli(createdAt)
+	return a, nil
+}
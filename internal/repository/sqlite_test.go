@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestSQLite(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "war.db")
+	db, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db, path
+}
+
+func TestSQLiteHistoryRepository(t *testing.T) {
+	db, _ := newTestSQLite(t)
+	testHistoryRepository(t, NewSQLiteHistoryRepository(db))
+}
+
+func TestSQLiteAccountRepository(t *testing.T) {
+	db, _ := newTestSQLite(t)
+	testAccountRepository(t, NewSQLiteAccountRepository(db))
+}
+
+func TestOpenSQLite_MigratesOnce(t *testing.T) {
+	db, path := newTestSQLite(t)
+
+	if err := NewSQLiteHistoryRepository(db).RecordMatch(newTestMatch("m1", time.Now(), "alice", "alice")); err != nil {
+		t.Fatalf("RecordMatch() error = %v", err)
+	}
+	db.Close()
+
+	// Opening an up to date database again keeps its data and versions
+	reopened, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() again error = %v", err)
+	}
+	defer reopened.Close()
+
+	var applied int
+	if err := reopened.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatalf("reading schema_migrations: %v", err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("schema_migrations has %d rows, want %d", applied, len(migrations))
+	}
+
+	if _, err := NewSQLiteHistoryRepository(reopened).GetMatch("m1"); err != nil {
+		t.Errorf("GetMatch() after reopening error = %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package simulate
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"es2.uff/war-server/internal/domain/room"
+)
+
+// WriteMarkdown writes the report as a Markdown document
+func (r Report) WriteMarkdown(w io.Writer) {
+	fmt.Fprintf(w, "# Tournament\n\n")
+	fmt.Fprintf(w, "Format: %s. Rounds: %d. Games: %d, seeded from %d.\n\n", r.Format, r.Rounds, r.Summary.Games, r.Seed)
+
+	fmt.Fprintf(w, "## Standings\n\n")
+	fmt.Fprintf(w, "| # | Entrant | Rating | Points | Wins | Games | Win rate |\n")
+	fmt.Fprintf(w, "|---|---------|-------:|-------:|-----:|------:|---------:|\n")
+	for i, s := range r.Standings {
+		rate := Rate{Wins: s.Wins, Games: s.Games}
+		fmt.Fprintf(w, "| %d | %s | %.0f | %d | %d | %d | %.1f%% |\n", i+1, s.Entrant, s.Rating, s.Points, s.Wins, s.Games, rate.Percent())
+	}
+
+	fmt.Fprintf(w, "\n## Matches\n\n")
+	fmt.Fprintf(w, "| Round | Entrants | Wins | Undecided |\n")
+	fmt.Fprintf(w, "|------:|----------|------|----------:|\n")
+	for _, m := range r.Matches {
+		wins := make([]string, len(m.Wins))
+		for i, n := range m.Wins {
+			wins[i] = fmt.Sprint(n)
+		}
+		fmt.Fprintf(w, "| %d | %s | %s | %d |\n", m.Round, joinEntrants(m.Entrants), strings.Join(wins, " - "), m.Undecided)
+	}
+
+	fmt.Fprintf(w, "\n## Games\n\n```\n")
+	r.Summary.Write(w)
+	fmt.Fprintf(w, "```\n")
+}
+
+func joinEntrants(entrants []room.BotDifficulty) string {
+	names := make([]string, len(entrants))
+	for i, e := range entrants {
+		names[i] = string(e)
+	}
+	return strings.Join(names, " vs ")
+}
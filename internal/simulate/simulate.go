@@ -0,0 +1,142 @@
+// Package simulate plays whole games between bots in process, on the rules
+// engine alone with no server or websockets, to measure the strategies
+// against each other.
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/ws"
+)
+
+// DefaultMaxTurns ends the games no one wins, counting the turns of every
+// player
+const DefaultMaxTurns = 1000
+
+// Config describes a batch of games
+type Config struct {
+	Games int
+	Seed  uint64 // Game i is seeded with Seed+i
+	// Strategy of each seat in turn order, for the first game
+	Bots     []room.BotDifficulty
+	Variant  room.RuleVariant
+	MaxTurns int
+	// Rotate shifts the seats by one each game, so every strategy plays
+	// from every seat
+	Rotate  bool
+	Workers int // Games played at once, zero for one per CPU
+}
+
+// Result is the outcome of one game
+type Result struct {
+	Seed       uint64               `json:"seed"`
+	Seats      []room.BotDifficulty `json:"seats"`      // Strategy of each seat in turn order
+	Objectives []int                `json:"objectives"` // Objective of each seat, -1 without objectives
+	Winner     int                  `json:"winner"`     // Seat of the winner, -1 when no one won
+	Turns      int                  `json:"turns"`
+}
+
+// Play plays one game between the seats to its end or maxTurns. The same
+// seed and seats play the same game.
+func Play(seed uint64, seats []room.BotDifficulty, variant room.RuleVariant, maxTurns int) (Result, error) {
+	if len(seats) < room.MinPlayerLimit || len(seats) > room.MaxPlayerLimit {
+		return Result{}, fmt.Errorf("games need %d to %d players, got %d", room.MinPlayerLimit, room.MaxPlayerLimit, len(seats))
+	}
+	if maxTurns <= 0 {
+		maxTurns = DefaultMaxTurns
+	}
+
+	settings := room.DefaultSettings()
+	settings.MaxPlayers = len(seats)
+	if variant != "" {
+		settings.RuleVariant = variant
+	}
+	state := ws.NewSeededGame(seed, settings, seats)
+
+	// The bots decide with their own source, so they do not move the dice
+	rng := rand.New(rand.NewPCG(seed, ^seed))
+	for state.Winner() == "" && state.TurnNumber <= maxTurns {
+		strategy := ws.NewBotStrategy(room.BotDifficulty(state.Players[state.CurrentTurn].BotDifficulty), rng)
+		if err := ws.PlayBotTurn(state, strategy); err != nil {
+			return Result{}, fmt.Errorf("game %d, turn %d: %w", seed, state.TurnNumber, err)
+		}
+	}
+
+	result := Result{Seed: seed, Seats: seats, Winner: -1, Turns: min(state.TurnNumber, maxTurns)}
+	for i, id := range state.TurnOrder {
+		result.Objectives = append(result.Objectives, state.Players[id].ObjectiveID)
+		if id == state.WinnerID {
+			result.Winner = i
+		}
+	}
+	return result, nil
+}
+
+// Run plays the games of cfg in parallel and returns their results in order
+func Run(cfg Config) ([]Result, error) {
+	if cfg.Games <= 0 {
+		return nil, errors.New("no games to play")
+	}
+
+	games := make([]game, cfg.Games)
+	for i := range games {
+		games[i] = game{seed: cfg.Seed + uint64(i), seats: cfg.seats(i)}
+	}
+	return playAll(games, cfg.Variant, cfg.MaxTurns, cfg.Workers)
+}
+
+// game is a game to play, with the strategy of each seat
+type game struct {
+	seed  uint64
+	seats []room.BotDifficulty
+}
+
+// playAll plays the games on workers at once, zero for one per CPU, and
+// returns their results in order
+func playAll(games []game, variant room.RuleVariant, maxTurns, workers int) ([]Result, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	results := make([]Result, len(games))
+	errs := make([]error, len(games))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(games)) {
+		wg.Go(func() {
+			for i := range next {
+				results[i], errs[i] = Play(games[i].seed, games[i].seats, variant, maxTurns)
+			}
+		})
+	}
+	for i := range games {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
+
+// seats returns the strategy of each seat in game i
+func (cfg Config) seats(i int) []room.BotDifficulty {
+	if !cfg.Rotate {
+		return rotate(cfg.Bots, 0)
+	}
+	return rotate(cfg.Bots, i)
+}
+
+// rotate returns the seats shifted by n, so seat n plays first
+func rotate(seats []room.BotDifficulty, n int) []room.BotDifficulty {
+	rotated := make([]room.BotDifficulty, len(seats))
+	for seat := range rotated {
+		rotated[seat] = seats[(seat+n)%len(seats)]
+	}
+	return rotated
+}
@@ -0,0 +1,76 @@
+package simulate
+
+import (
+	"reflect"
+	"testing"
+
+	"es2.uff/war-server/internal/domain/room"
+)
+
+func TestPlay_RepeatsForASeed(t *testing.T) {
+	seats := []room.BotDifficulty{room.BotHard, room.BotMedium, room.BotEasy}
+
+	first, err := Play(7, seats, room.VariantObjectives, 0)
+	if err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if first.Winner < 0 {
+		t.Fatalf("no winner in %d turns", first.Turns)
+	}
+
+	again, _ := Play(7, seats, room.VariantObjectives, 0)
+	if !reflect.DeepEqual(first, again) {
+		t.Errorf("seed 7 played %+v, then %+v", first, again)
+	}
+}
+
+func TestPlay_StopsAtMaxTurns(t *testing.T) {
+	result, err := Play(1, []room.BotDifficulty{room.BotEasy, room.BotEasy}, room.VariantWorldDomination, 3)
+	if err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if result.Winner != -1 || result.Turns != 3 {
+		t.Errorf("result = %+v, want no winner after 3 turns", result)
+	}
+	if result.Objectives[0] != -1 {
+		t.Errorf("objectives = %v, want none in world domination", result.Objectives)
+	}
+}
+
+func TestRun(t *testing.T) {
+	results, err := Run(Config{Games: 4, Seed: 1, Bots: []room.BotDifficulty{room.BotHard, room.BotEasy}, Rotate: true})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	for i, r := range results {
+		if r.Seed != 1+uint64(i) {
+			t.Errorf("game %d has seed %d", i, r.Seed)
+		}
+		if first := []room.BotDifficulty{room.BotHard, room.BotEasy}[i%2]; r.Seats[0] != first {
+			t.Errorf("game %d seats = %v, want %s first", i, r.Seats, first)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []Result{
+		{Seats: []room.BotDifficulty{room.BotHard, room.BotEasy}, Objectives: []int{1, 2}, Winner: 0, Turns: 10},
+		{Seats: []room.BotDifficulty{room.BotEasy, room.BotHard}, Objectives: []int{2, 3}, Winner: 1, Turns: 20},
+		{Seats: []room.BotDifficulty{room.BotHard, room.BotEasy}, Objectives: []int{1, 3}, Winner: -1, Turns: 50},
+	}
+
+	s := Summarize(results)
+	if s.Games != 3 || s.Unfinished != 1 || s.AvgTurns != 15 || s.AvgRounds != 7.5 {
+		t.Errorf("summary = %+v, want 3 games, 1 unfinished, 15 turns and 7.5 rounds", s)
+	}
+	if got := *s.Strategies[room.BotHard]; got != (Rate{Wins: 2, Games: 3}) {
+		t.Errorf("hard = %+v, want 2 wins in 3 games", got)
+	}
+	if got := *s.Objectives[2]; got != (Rate{Wins: 0, Games: 2}) {
+		t.Errorf("objective 2 = %+v, want 0 wins in 2 games", got)
+	}
+	if want := []Rate{{Wins: 1, Games: 3}, {Wins: 1, Games: 3}}; !reflect.DeepEqual(s.Seats, want) {
+		t.Errorf("seats = %+v, want %+v", s.Seats, want)
+	}
+}
@@ -0,0 +1,127 @@
+package simulate
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+
+	"es2.uff/war-server/internal/domain/objective"
+	"es2.uff/war-server/internal/domain/room"
+)
+
+// Rate counts the wins out of the games played
+type Rate struct {
+	Wins  int `json:"wins"`
+	Games int `json:"games"`
+}
+
+func (r Rate) Percent() float64 {
+	if r.Games == 0 {
+		return 0
+	}
+	return 100 * float64(r.Wins) / float64(r.Games)
+}
+
+func (r *Rate) add(won bool) {
+	r.Games++
+	if won {
+		r.Wins++
+	}
+}
+
+// Summary aggregates the results of a batch of games
+type Summary struct {
+	Games      int `json:"games"`
+	Unfinished int `json:"unfinished"` // Games that hit the turn limit
+	// Turns and rounds of the games with a winner, a round is a turn of
+	// every player
+	AvgTurns   float64                      `json:"avg_turns"`
+	AvgRounds  float64                      `json:"avg_rounds"`
+	Strategies map[room.BotDifficulty]*Rate `json:"strategies"` // One game per seat played
+	Objectives map[int]*Rate                `json:"objectives"`
+	// Seats holds the rate of each position in turn order, the first
+	// player's advantage is Seats[0] against an even share
+	Seats []Rate `json:"seats"`
+}
+
+func Summarize(results []Result) Summary {
+	s := Summary{
+		Games:      len(results),
+		Strategies: make(map[room.BotDifficulty]*Rate),
+		Objectives: make(map[int]*Rate),
+	}
+
+	finished := 0
+	for _, r := range results {
+		if r.Winner < 0 {
+			s.Unfinished++
+		} else {
+			finished++
+			s.AvgTurns += float64(r.Turns)
+			s.AvgRounds += float64(r.Turns) / float64(len(r.Seats))
+		}
+
+		for seat, d := range r.Seats {
+			won := seat == r.Winner
+			if s.Strategies[d] == nil {
+				s.Strategies[d] = &Rate{}
+			}
+			s.Strategies[d].add(won)
+
+			if seat < len(r.Objectives) && r.Objectives[seat] >= 0 {
+				id := r.Objectives[seat]
+				if s.Objectives[id] == nil {
+					s.Objectives[id] = &Rate{}
+				}
+				s.Objectives[id].add(won)
+			}
+
+			for len(s.Seats) <= seat {
+				s.Seats = append(s.Seats, Rate{})
+			}
+			s.Seats[seat].add(won)
+		}
+	}
+
+	if finished > 0 {
+		s.AvgTurns /= float64(finished)
+		s.AvgRounds /= float64(finished)
+	}
+	return s
+}
+
+// Write prints the summary as plain text tables
+func (s Summary) Write(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Games: %d (%d without a winner)\n", s.Games, s.Unfinished)
+	fmt.Fprintf(w, "Average length: %.1f turns, %.1f rounds\n", s.AvgTurns, s.AvgRounds)
+
+	fmt.Fprintln(w, "\nWin rate per strategy:")
+	for _, d := range slices.Sorted(maps.Keys(s.Strategies)) {
+		writeRate(w, string(d), *s.Strategies[d])
+	}
+
+	if len(s.Objectives) > 0 {
+		fmt.Fprintln(w, "\nWin rate per objective:")
+		for _, id := range slices.Sorted(maps.Keys(s.Objectives)) {
+			writeRate(w, objective.ObjectiveDetails[objective.ObjectiveID(id)].Description, *s.Objectives[id])
+		}
+	}
+
+	fmt.Fprintln(w, "\nWin rate per seat:")
+	for seat, rate := range s.Seats {
+		writeRate(w, fmt.Sprintf("Seat %d", seat+1), rate)
+	}
+	if won := s.Games - s.Unfinished; won > 0 && len(s.Seats) > 0 {
+		share, even := 100*float64(s.Seats[0].Wins)/float64(won), 100/float64(len(s.Seats))
+		fmt.Fprintf(w, "First player advantage: won %.1f%% of the decided games, %+.1f points over an even share\n", share, share-even)
+	}
+}
+
+func writeRate(w io.Writer, name string, r Rate) {
+	fmt.Fprintf(w, "  %s\t%5.1f%%\t(%d/%d)\n", name, r.Percent(), r.Wins, r.Games)
+}
@@ -0,0 +1,270 @@
+package simulate
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+
+	"es2.uff/war-server/internal/domain/room"
+)
+
+// Format is how a tournament picks who meets whom
+type Format string
+
+const (
+	// Every table of entrants meets once each round
+	RoundRobin Format = "round_robin"
+	// Entrants with close scores meet each round, avoiding rematches
+	Swiss Format = "swiss"
+)
+
+var Formats = []Format{RoundRobin, Swiss}
+
+const (
+	// InitialRating is the Elo rating of every entrant before its first game
+	InitialRating = 1500
+	// eloK is the most a rating moves in one game
+	eloK = 24
+)
+
+// TournamentConfig describes a tournament between strategies
+type TournamentConfig struct {
+	Entrants  []room.BotDifficulty
+	Format    Format
+	TableSize int // Players at each game, 2 when zero
+	// Rounds played, zero for one round robin or enough Swiss rounds to
+	// tell the entrants apart
+	Rounds int
+	// Games of each match, the seats rotate between them so every entrant
+	// plays from every seat. TableSize when zero.
+	Games    int
+	Seed     uint64 // The games are seeded from Seed on, in the order they are scheduled
+	Variant  room.RuleVariant
+	MaxTurns int
+	Workers  int
+}
+
+// Standing is how an entrant did in a tournament
+type Standing struct {
+	Entrant room.BotDifficulty `json:"entrant"`
+	Rating  float64            `json:"rating"`
+	Points  int                `json:"points"` // Games won plus byes
+	Wins    int                `json:"wins"`
+	Games   int                `json:"games"`
+	Byes    int                `json:"byes,omitempty"` // Swiss rounds sat out for lack of a table
+}
+
+// Match is the games a table of entrants played in a round
+type Match struct {
+	Round     int                  `json:"round"`
+	Entrants  []room.BotDifficulty `json:"entrants"`
+	Wins      []int                `json:"wins"`      // Games won by each entrant
+	Undecided int                  `json:"undecided"` // Games that hit the turn limit
+	Seeds     []uint64             `json:"seeds"`
+}
+
+// Report is the outcome of a tournament
+type Report struct {
+	Format    Format     `json:"format"`
+	Seed      uint64     `json:"seed"`
+	Rounds    int        `json:"rounds"`
+	Standings []Standing `json:"standings"` // Best rated first
+	Matches   []Match    `json:"matches"`
+	Summary   Summary    `json:"summary"` // Of every game played
+}
+
+// RunTournament plays a tournament between the entrants and rates them
+func RunTournament(cfg TournamentConfig) (Report, error) {
+	if cfg.TableSize == 0 {
+		cfg.TableSize = 2
+	}
+	if cfg.Games == 0 {
+		cfg.Games = cfg.TableSize
+	}
+	if cfg.Format == "" {
+		cfg.Format = RoundRobin
+	}
+	if !slices.Contains(Formats, cfg.Format) {
+		return Report{}, fmt.Errorf("unknown format %q", cfg.Format)
+	}
+	if len(cfg.Entrants) < cfg.TableSize {
+		return Report{}, fmt.Errorf("%d entrants cannot fill a table of %d", len(cfg.Entrants), cfg.TableSize)
+	}
+	if cfg.Rounds == 0 {
+		cfg.Rounds = 1
+		if cfg.Format == Swiss {
+			cfg.Rounds = max(1, bits.Len(uint(len(cfg.Entrants)-1)))
+		}
+	}
+
+	t := &tournament{cfg: cfg, standings: make(map[room.BotDifficulty]*Standing), met: make(map[[2]room.BotDifficulty]bool)}
+	for _, e := range cfg.Entrants {
+		if t.standings[e] != nil {
+			return Report{}, fmt.Errorf("%s entered twice", e)
+		}
+		t.standings[e] = &Standing{Entrant: e, Rating: InitialRating}
+	}
+
+	var results []Result
+	for round := 1; round <= cfg.Rounds; round++ {
+		tables := t.tables()
+		if len(tables) == 0 {
+			return Report{}, errors.New("no tables to play")
+		}
+
+		var games []game
+		for _, table := range tables {
+			for i := range cfg.Games {
+				games = append(games, game{seed: t.nextSeed(), seats: rotate(table, i)})
+			}
+		}
+		played, err := playAll(games, cfg.Variant, cfg.MaxTurns, cfg.Workers)
+		if err != nil {
+			return Report{}, err
+		}
+		results = append(results, played...)
+
+		for i, table := range tables {
+			t.record(round, table, played[i*cfg.Games:(i+1)*cfg.Games])
+		}
+	}
+
+	report := Report{Format: cfg.Format, Seed: cfg.Seed, Rounds: cfg.Rounds, Matches: t.matches, Summary: Summarize(results)}
+	for _, e := range cfg.Entrants {
+		report.Standings = append(report.Standings, *t.standings[e])
+	}
+	slices.SortStableFunc(report.Standings, func(a, b Standing) int { return cmp.Compare(b.Rating, a.Rating) })
+	return report, nil
+}
+
+type tournament struct {
+	cfg       TournamentConfig
+	standings map[room.BotDifficulty]*Standing
+	met       map[[2]room.BotDifficulty]bool // Pairs of entrants who shared a table
+	matches   []Match
+	games     int
+}
+
+func (t *tournament) nextSeed() uint64 {
+	t.games++
+	return t.cfg.Seed + uint64(t.games-1)
+}
+
+// tables returns the tables of the next round, and gives a bye to the Swiss
+// entrants left without one
+func (t *tournament) tables() [][]room.BotDifficulty {
+	if t.cfg.Format == RoundRobin {
+		return combinations(t.cfg.Entrants, t.cfg.TableSize)
+	}
+
+	// Leaders first, the entrants keep their order until they have played
+	order := slices.Clone(t.cfg.Entrants)
+	slices.SortStableFunc(order, func(a, b room.BotDifficulty) int {
+		sa, sb := t.standings[a], t.standings[b]
+		return cmp.Or(cmp.Compare(sb.Points, sa.Points), cmp.Compare(sb.Rating, sa.Rating))
+	})
+
+	var tables [][]room.BotDifficulty
+	for len(order) >= t.cfg.TableSize {
+		table := []room.BotDifficulty{order[0]}
+		order = order[1:]
+
+		// The closest entrants not met yet, then the closest ones
+		for _, rematch := range []bool{false, true} {
+			for i := 0; i < len(order) && len(table) < t.cfg.TableSize; {
+				if rematch || !slices.ContainsFunc(table, func(e room.BotDifficulty) bool { return t.met[pair(e, order[i])] }) {
+					table = append(table, order[i])
+					order = slices.Delete(order, i, i+1)
+					continue
+				}
+				i++
+			}
+		}
+		tables = append(tables, table)
+	}
+
+	for _, e := range order {
+		t.standings[e].Byes++
+		t.standings[e].Points++
+	}
+	return tables
+}
+
+// record scores the games of a table and rates its entrants after each
+func (t *tournament) record(round int, table []room.BotDifficulty, results []Result) {
+	match := Match{Round: round, Entrants: table, Wins: make([]int, len(table))}
+	for i, a := range table {
+		for _, b := range table[i+1:] {
+			t.met[pair(a, b)] = true
+		}
+	}
+
+	for _, r := range results {
+		match.Seeds = append(match.Seeds, r.Seed)
+
+		winner := room.BotDifficulty("")
+		if r.Winner >= 0 {
+			winner = r.Seats[r.Winner]
+			match.Wins[slices.Index(table, winner)]++
+			t.standings[winner].Wins++
+			t.standings[winner].Points++
+		} else {
+			match.Undecided++
+		}
+		for _, e := range table {
+			t.standings[e].Games++
+		}
+		t.rate(table, winner)
+	}
+
+	t.matches = append(t.matches, match)
+}
+
+// rate moves the Elo ratings of a table after a game, as if each pair of
+// entrants had played: the winner beat everyone else and the rest drew
+func (t *tournament) rate(table []room.BotDifficulty, winner room.BotDifficulty) {
+	deltas := make([]float64, len(table))
+	for i, a := range table {
+		for _, b := range table {
+			if a == b {
+				continue
+			}
+			score := 0.5
+			if a == winner {
+				score = 1
+			} else if b == winner {
+				score = 0
+			}
+			expected := 1 / (1 + math.Pow(10, (t.standings[b].Rating-t.standings[a].Rating)/400))
+			deltas[i] += eloK * (score - expected) / float64(len(table)-1)
+		}
+	}
+	for i, e := range table {
+		t.standings[e].Rating += deltas[i]
+	}
+}
+
+// pair is the key of two entrants in either order
+func pair(a, b room.BotDifficulty) [2]room.BotDifficulty {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]room.BotDifficulty{a, b}
+}
+
+// combinations returns every set of size of the entrants, in their order
+func combinations(entrants []room.BotDifficulty, size int) [][]room.BotDifficulty {
+	if size == 0 {
+		return [][]room.BotDifficulty{nil}
+	}
+	var sets [][]room.BotDifficulty
+	for i := 0; i+size <= len(entrants); i++ {
+		for _, rest := range combinations(entrants[i+1:], size-1) {
+			sets = append(sets, append([]room.BotDifficulty{entrants[i]}, rest...))
+		}
+	}
+	return sets
+}
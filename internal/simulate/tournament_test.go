@@ -0,0 +1,101 @@
+package simulate
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"es2.uff/war-server/internal/domain/room"
+)
+
+func TestCombinations(t *testing.T) {
+	got := combinations([]room.BotDifficulty{"a", "b", "c"}, 2)
+	want := [][]room.BotDifficulty{{"a", "b"}, {"a", "c"}, {"b", "c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("combinations() = %v, want %v", got, want)
+	}
+}
+
+func TestTournament_Rate(t *testing.T) {
+	tr := &tournament{standings: map[room.BotDifficulty]*Standing{
+		"a": {Rating: InitialRating}, "b": {Rating: InitialRating}, "c": {Rating: InitialRating},
+	}}
+
+	tr.rate([]room.BotDifficulty{"a", "b", "c"}, "a")
+	a, b, c := tr.standings["a"].Rating, tr.standings["b"].Rating, tr.standings["c"].Rating
+	if a != InitialRating+eloK/2 || b != c || a+b+c != 3*InitialRating {
+		t.Errorf("ratings after a won = %v, %v, %v", a, b, c)
+	}
+
+	// No winner is a draw between all
+	tr.rate([]room.BotDifficulty{"b", "c"}, "")
+	if tr.standings["b"].Rating != b {
+		t.Errorf("a draw between equals moved the rating to %v", tr.standings["b"].Rating)
+	}
+}
+
+func TestTournament_SwissAvoidsRematches(t *testing.T) {
+	tr := &tournament{
+		cfg:       TournamentConfig{Entrants: []room.BotDifficulty{"a", "b", "c", "d", "e"}, Format: Swiss, TableSize: 2},
+		standings: make(map[room.BotDifficulty]*Standing),
+		met:       map[[2]room.BotDifficulty]bool{pair("a", "b"): true},
+	}
+	for i, e := range tr.cfg.Entrants {
+		tr.standings[e] = &Standing{Entrant: e, Points: 5 - i}
+	}
+
+	tables := tr.tables()
+	want := [][]room.BotDifficulty{{"a", "c"}, {"b", "d"}}
+	if !reflect.DeepEqual(tables, want) {
+		t.Errorf("tables() = %v, want %v", tables, want)
+	}
+	if tr.standings["e"].Byes != 1 || tr.standings["e"].Points != 2 {
+		t.Errorf("e = %+v, want a bye worth a point", *tr.standings["e"])
+	}
+}
+
+func TestRunTournament(t *testing.T) {
+	cfg := TournamentConfig{Entrants: []room.BotDifficulty{room.BotEasy, room.BotHard}, Games: 4, Seed: 3}
+
+	report, err := RunTournament(cfg)
+	if err != nil {
+		t.Fatalf("RunTournament() error = %v", err)
+	}
+	if len(report.Matches) != 1 || len(report.Matches[0].Seeds) != 4 {
+		t.Fatalf("matches = %+v, want one of 4 games", report.Matches)
+	}
+	if best := report.Standings[0]; best.Entrant != room.BotHard || best.Rating <= InitialRating {
+		t.Errorf("standings = %+v, want hard rated first", report.Standings)
+	}
+
+	again, _ := RunTournament(cfg)
+	if !reflect.DeepEqual(report, again) {
+		t.Error("the same tournament rated the entrants differently")
+	}
+
+	var md bytes.Buffer
+	report.WriteMarkdown(&md)
+	if !strings.Contains(md.String(), "| 1 | hard |") {
+		t.Errorf("Markdown report does not rank hard first:\n%s", md.String())
+	}
+}
+
+func TestRunTournament_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  TournamentConfig
+	}{
+		{"Too few entrants", TournamentConfig{Entrants: []room.BotDifficulty{room.BotEasy}}},
+		{"Entered twice", TournamentConfig{Entrants: []room.BotDifficulty{room.BotEasy, room.BotEasy}}},
+		{"Unknown format", TournamentConfig{Entrants: []room.BotDifficulty{room.BotEasy, room.BotHard}, Format: "knockout"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := RunTournament(tt.cfg); err == nil {
+				t.Error("RunTournament() error = nil")
+			}
+		})
+	}
+}
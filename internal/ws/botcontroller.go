@@ -1,149 +1,311 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
 	"log"
-	"math/rand"
+	"math/rand/v2"
 	"time"
+
+	"es2.uff/war-server/internal/domain/room"
 )
 
-func (g *Game) executeBotTurn(botID string) {
-	time.Sleep(1 * time.Second)
+// MaxBotDecisions bounds the attacks and moves of a bot turn, so a strategy
+// that keeps finding actions cannot stall the game
+const MaxBotDecisions = 50
 
-	g.botDeployPhase(botID)
-	time.Sleep(500 * time.Millisecond)
+// BotPacing spaces out the actions of bots so players can follow them
+type BotPacing struct {
+	Load   time.Duration // Before the turn of a game just loaded, for clients to connect
+	Start  time.Duration // Before the first action of a turn
+	Action time.Duration // After each trade and deploy
+	Phase  time.Duration // After each attack and move, and between phases
+}
 
-	g.botAttackPhase(botID)
-	time.Sleep(500 * time.Millisecond)
+// DefaultBotPacing is the pacing of the bots of new games
+var DefaultBotPacing = BotPacing{
+	Load:   2 * time.Second,
+	Start:  1 * time.Second,
+	Action: 100 * time.Millisecond,
+	Phase:  500 * time.Millisecond,
+}
 
-	g.botMovePhase(botID)
-	time.Sleep(500 * time.Millisecond)
+// Scaled returns the pacing with every delay multiplied by f, 0 lets bots
+// play as fast as they can
+func (p BotPacing) Scaled(f float64) BotPacing {
+	scale := func(d time.Duration) time.Duration { return time.Duration(float64(d) * f) }
+	return BotPacing{Load: scale(p.Load), Start: scale(p.Start), Action: scale(p.Action), Phase: scale(p.Phase)}
+}
 
-	g.botFinishTurn(botID)
+// botActionTimeout is how long a bot waits for the game to apply an action
+// before it decides on the next one anyway
+const botActionTimeout = 5 * time.Second
+
+// botTurn is a bot turn being played in the background
+type botTurn struct {
+	botID  string
+	state  *GameState // Of the game when the turn started, a lease takeover restores a new one
+	ctx    context.Context
+	cancel context.CancelFunc
+	acks   chan int64 // Sequence numbers of the bot's actions applied by the game
 }
 
-func (g *Game) botDeployPhase(botID string) {
-	for {
-		g.GameState.RLock()
-		bot := g.GameState.Players[botID]
-		if bot == nil || bot.Armies <= 0 {
-			g.GameState.RUnlock()
-			break
-		}
-		g.GameState.RUnlock()
+// startBotTurn plays the bot's turn in the background after delay, cancelling
+// the bot turn still running. It runs on the game's loop.
+func (g *Game) startBotTurn(botID string, delay time.Duration) {
+	g.stopBotTurn()
 
-		ownedTerritories := g.getBotOwnedTerritories(botID)
-		if len(ownedTerritories) == 0 {
-			break
+	ctx, cancel := context.WithCancel(g.ctx)
+	turn := &botTurn{botID: botID, state: g.GameState, ctx: ctx, cancel: cancel, acks: make(chan int64, 4)}
+	g.botTurn = turn
+
+	go func() {
+		defer cancel()
+
+		if wait(turn.ctx, delay) {
+			g.executeBotTurn(turn)
 		}
+	}()
+}
 
-		territory := ownedTerritories[rand.Intn(len(ownedTerritories))]
+// stopBotTurn cancels the running bot turn, which stops at its next action
+func (g *Game) stopBotTurn() {
+	if g.botTurn != nil {
+		g.botTurn.cancel()
+		g.botTurn = nil
+	}
+}
 
-		g.sendBotAction("troop_assign", botID, map[string]any{
-			"territory_id": territory.ID,
-		})
+// syncBotTurn stops the bot turn once it is no longer the bot's turn, as when
+// the turn was passed by the timer. It runs on the game's loop after every
+// action.
+func (g *Game) syncBotTurn() {
+	if g.botTurn == nil {
+		return
+	}
+
+	g.GameState.RLock()
+	current := g.GameState.Players[g.GameState.CurrentTurn]
+	g.GameState.RUnlock()
 
-		time.Sleep(100 * time.Millisecond)
+	if current == nil || !current.IsBot || current.ID != g.botTurn.botID {
+		g.stopBotTurn()
 	}
 }
 
-func (g *Game) botAttackPhase(botID string) {
-	attackOptions := g.getBotAttackOptions(botID)
-	if len(attackOptions) == 0 {
+// ackBotAction tells the running bot turn its action seq was applied
+func (g *Game) ackBotAction(seq int64) {
+	if g.botTurn == nil {
 		return
 	}
+	select {
+	case g.botTurn.acks <- seq:
+	default:
+	}
+}
 
-	numAttacks := rand.Intn(3) + 1
-	for i := 0; i < numAttacks && i < len(attackOptions); i++ {
-		attackIdx := rand.Intn(len(attackOptions))
-		attack := attackOptions[attackIdx]
+// wait sleeps for d, reporting false when ctx is cancelled first
+func wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-		g.GameState.RLock()
-		fromTerritory := g.getTerritoryByIDLocked(attack.from.ID)
-		toTerritory := g.getTerritoryByIDLocked(attack.to.ID)
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
 
-		if fromTerritory == nil || fromTerritory.Owner != botID {
-			g.GameState.RUnlock()
-			continue
-		}
+func (g *Game) executeBotTurn(turn *botTurn) {
+	strategy := g.botStrategy(turn)
+	if !wait(turn.ctx, g.pacing.Start) {
+		return
+	}
 
-		if toTerritory == nil || toTerritory.Owner == botID {
-			g.GameState.RUnlock()
-			continue
-		}
+	g.botTradePhase(turn, strategy)
+	g.botDeployPhase(turn, strategy)
+	if !wait(turn.ctx, g.pacing.Phase) {
+		return
+	}
 
-		maxArmies := fromTerritory.Armies - 1
-		g.GameState.RUnlock()
+	g.botAttackPhase(turn, strategy)
+	if !wait(turn.ctx, g.pacing.Phase) {
+		return
+	}
 
-		if maxArmies < 1 {
-			continue
-		}
-		attackingArmies := rand.Intn(min(maxArmies, 3)) + 1
+	g.botMovePhase(turn, strategy)
+	if !wait(turn.ctx, g.pacing.Phase) {
+		return
+	}
 
-		g.sendBotAction("attack", botID, map[string]any{
-			"from":             attack.from.ID,
-			"to":               attack.to.ID,
-			"attacking_armies": attackingArmies,
-		})
+	g.botFinishTurn(turn)
+}
 
-		time.Sleep(500 * time.Millisecond)
+// botStrategy returns a new strategy for the difficulty the bot was given.
+// External bots stop waiting for their decisions when the turn is cancelled.
+func (g *Game) botStrategy(turn *botTurn) BotStrategy {
+	turn.state.RLock()
+	difficulty := room.BotEasy
+	if bot := turn.state.Players[turn.botID]; bot != nil {
+		difficulty = room.BotDifficulty(bot.BotDifficulty)
+	}
+	turn.state.RUnlock()
 
-		attackOptions = g.getBotAttackOptions(botID)
+	strategy := NewBotStrategy(difficulty, rand.New(newRNG()))
+	if external, ok := strategy.(*externalStrategy); ok {
+		external.ctx = turn.ctx
 	}
+	return strategy
 }
 
-func (g *Game) botMovePhase(botID string) {
-	ownedTerritories := g.getBotOwnedTerritories(botID)
-	if len(ownedTerritories) < 2 {
-		return
+// botView returns a copy of the state for the bot to decide on, or nil once
+// the bot can no longer act: its turn was cancelled or is over, or the game
+// is paused or won
+func (g *Game) botView(turn *botTurn) *BotView {
+	if turn.ctx.Err() != nil {
+		return nil
 	}
 
-	for _, from := range ownedTerritories {
-		if from.Armies <= 1 {
-			continue
+	gs := turn.state
+	gs.RLock()
+	defer gs.RUnlock()
+
+	bot := gs.Players[turn.botID]
+	if bot == nil || !bot.IsBot || gs.CurrentTurn != turn.botID || gs.WinnerID != "" || gs.Paused {
+		return nil
+	}
+	return newBotView(gs, turn.botID)
+}
+
+// botTradePhase trades sets until the strategy keeps its cards, the bonus
+// armies are then placed in the deploy phase
+func (g *Game) botTradePhase(turn *botTurn, strategy BotStrategy) {
+	var last [3]string
+	for range MaxBotDecisions {
+		view := g.botView(turn)
+		if view == nil {
+			return
 		}
 
-		for _, adjID := range from.Adjacent {
-			g.GameState.RLock()
-			fromFresh := g.getTerritoryByIDLocked(from.ID)
-			toFresh := g.getTerritoryByIDLocked(adjID)
+		cards, ok := strategy.Trade(view)
+		// The same set again means the last trade was refused
+		if !ok || cards == last {
+			return
+		}
+		last = cards
 
-			if fromFresh == nil || fromFresh.Owner != botID || fromFresh.Armies <= 1 {
-				g.GameState.RUnlock()
-				continue
-			}
-			if toFresh == nil || toFresh.Owner != botID {
-				g.GameState.RUnlock()
-				continue
-			}
+		sent := g.sendBotAction(turn, "trade", map[string]any{
+			"card_1": cards[0],
+			"card_2": cards[1],
+			"card_3": cards[2],
+		})
+		if !sent || !wait(turn.ctx, g.pacing.Action) {
+			return
+		}
+	}
+}
 
-			movingArmies := (fromFresh.Armies - 1) / 2
-			g.GameState.RUnlock()
+func (g *Game) botDeployPhase(turn *botTurn, strategy BotStrategy) {
+	// A deploy that was refused leaves the armies as they were
+	for left := -1; ; {
+		view := g.botView(turn)
+		if view == nil || view.Armies <= 0 || view.Armies == left {
+			break
+		}
+		left = view.Armies
 
-			if movingArmies < 1 {
-				continue
-			}
+		territoryID := strategy.Deploy(view)
+		if territoryID == "" {
+			break
+		}
+
+		sent := g.sendBotAction(turn, "troop_assign", map[string]any{
+			"territory_id": territoryID,
+		})
+		if !sent || !wait(turn.ctx, g.pacing.Action) {
+			return
+		}
+	}
+}
+
+func (g *Game) botAttackPhase(turn *botTurn, strategy BotStrategy) {
+	for range MaxBotDecisions {
+		view := g.botView(turn)
+		if view == nil {
+			return
+		}
 
-			g.sendBotAction("troop_move", botID, map[string]any{
-				"from":          from.ID,
-				"to":            adjID,
-				"moving_armies": movingArmies,
+		attack, ok := strategy.Attack(view)
+		if !ok {
+			return
+		}
+
+		sent := g.sendBotAction(turn, "attack", map[string]any{
+			"from":             attack.From,
+			"to":               attack.To,
+			"attacking_armies": attack.Armies,
+		})
+		if !sent || !wait(turn.ctx, g.pacing.Phase) {
+			return
+		}
+
+		view = g.botView(turn)
+		if view == nil || view.Territory(attack.To).Owner != turn.botID {
+			continue
+		}
+
+		if armies := strategy.Occupy(view, attack); armies > 0 {
+			sent := g.sendBotAction(turn, "troop_move", map[string]any{
+				"from":          attack.From,
+				"to":            attack.To,
+				"moving_armies": armies,
 			})
+			if !sent || !wait(turn.ctx, g.pacing.Phase) {
+				return
+			}
+		}
+	}
+}
 
-			time.Sleep(500 * time.Millisecond)
+func (g *Game) botMovePhase(turn *botTurn, strategy BotStrategy) {
+	for range MaxBotDecisions {
+		view := g.botView(turn)
+		if view == nil {
+			return
+		}
+
+		move, ok := strategy.Fortify(view)
+		if !ok {
+			return
+		}
+
+		sent := g.sendBotAction(turn, "troop_move", map[string]any{
+			"from":          move.From,
+			"to":            move.To,
+			"moving_armies": move.Armies,
+		})
+		if !sent || !wait(turn.ctx, g.pacing.Phase) {
 			return
 		}
 	}
 }
 
-func (g *Game) botFinishTurn(botID string) {
-	g.sendBotAction("finish_turn", botID, nil)
+func (g *Game) botFinishTurn(turn *botTurn) {
+	if g.botView(turn) != nil {
+		g.sendBotAction(turn, "finish_turn", nil)
+	}
 }
 
-func (g *Game) sendBotAction(actionType string, botID string, params map[string]any) {
+// sendBotAction queues the action on the game's loop like a player's
+// message and waits for the game to apply it, so the next decision sees its
+// outcome. It reports false when the turn was cancelled first.
+func (g *Game) sendBotAction(turn *botTurn, actionType string, params map[string]any) bool {
+	seq := g.botSeq.Add(1)
 	msg := map[string]any{
 		"type":      actionType,
-		"player_id": botID,
+		"player_id": turn.botID,
+		"bot_seq":   seq,
 	}
 
 	for k, v := range params {
@@ -153,58 +315,28 @@ func (g *Game) sendBotAction(actionType string, botID string, params map[string]
 	jsonMsg, err := json.Marshal(msg)
 	if err != nil {
 		log.Printf("Error marshaling bot action: %v", err)
-		return
+		return false
 	}
 
-	g.broadcast <- jsonMsg
-}
-
-func (g *Game) getBotOwnedTerritories(botID string) []*Territory {
-	g.GameState.RLock()
-	defer g.GameState.RUnlock()
-
-	owned := make([]*Territory, 0)
-	for _, t := range g.GameState.Territories {
-		if t.Owner == botID {
-			owned = append(owned, t)
-		}
+	select {
+	case g.broadcast <- jsonMsg:
+	case <-turn.ctx.Done():
+		return false
 	}
-	return owned
-}
-
-type attackOption struct {
-	from *Territory
-	to   *Territory
-}
 
-func (g *Game) getBotAttackOptions(botID string) []attackOption {
-	g.GameState.RLock()
-	defer g.GameState.RUnlock()
-
-	options := make([]attackOption, 0)
-	for _, t := range g.GameState.Territories {
-		if t.Owner != botID || t.Armies <= 1 {
-			continue
-		}
-
-		for _, adjID := range t.Adjacent {
-			adjTerritory := g.getTerritoryByIDLocked(adjID)
-			if adjTerritory != nil && adjTerritory.Owner != botID {
-				options = append(options, attackOption{
-					from: t,
-					to:   adjTerritory,
-				})
+	timeout := time.NewTimer(botActionTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case applied := <-turn.acks:
+			if applied >= seq {
+				return true
 			}
+		case <-timeout.C:
+			log.Printf("Action %s of bot %s was not applied in time", actionType, turn.botID)
+			return true
+		case <-turn.ctx.Done():
+			return false
 		}
 	}
-	return options
-}
-
-func (g *Game) getTerritoryByIDLocked(territoryID string) *Territory {
-	for _, t := range g.GameState.Territories {
-		if t.ID == territoryID {
-			return t
-		}
-	}
-	return nil
 }
@@ -0,0 +1,210 @@
+package ws
+
+import (
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/repository"
+	"github.com/google/uuid"
+)
+
+// botGoroutines counts the goroutines playing a bot turn
+func botGoroutines() int {
+	return countGoroutines("ws.(*Game).startBotTurn.func")
+}
+
+// gameGoroutines counts the loops and lease holders of games
+func gameGoroutines() int {
+	return countGoroutines("ws.(*Game).Run(") + countGoroutines("ws.(*Game).holdLease(")
+}
+
+func countGoroutines(function string) int {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	return strings.Count(string(buf), function)
+}
+
+// waitForNoBots fails unless every bot turn has stopped within timeout
+func waitForNoBots(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	waitForBots(t, 0, timeout)
+}
+
+// waitForBots fails unless exactly n bot turns are running within timeout
+func waitForBots(t *testing.T, n int, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for botGoroutines() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d bot turns running after %v, want %d", botGoroutines(), timeout, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// newBotTurnGame starts a game where a bot plays first against its human
+// owner. Nothing runs the game's loop: the messages of the bot are read and
+// dropped, so it waits on its first action until its turn is stopped. sent
+// is signalled once the bot has sent that action.
+func newBotTurnGame(t *testing.T) (game *Game, botID string, sent <-chan struct{}) {
+	t.Helper()
+
+	ownerID, botID := uuid.NewString(), uuid.NewString()
+	state := NewGameState("room-1")
+	state.Settings = room.DefaultSettings()
+	state.OwnerID = ownerID
+	state.Players[ownerID] = &Player{ID: ownerID, Username: "Owner"}
+	state.Players[botID] = &Player{ID: botID, Username: "Bot 1", IsBot: true, BotDifficulty: string(room.BotEasy)}
+	state.TurnOrder = []string{botID, ownerID}
+	state.StartGame()
+
+	manager := NewGameManager(repository.NewMemoryRoomRepository(), repository.NewMemoryGameRepository(), nil, NewLocalCluster())
+	game = manager.newGame("room-1", state)
+	game.pacing = BotPacing{}
+
+	stop := make(chan struct{})
+	actions := make(chan struct{}, 1)
+	t.Cleanup(func() { close(stop) })
+	go func() {
+		for {
+			select {
+			case <-game.broadcast:
+				select {
+				case actions <- struct{}{}:
+				default:
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return game, botID, actions
+}
+
+func TestGame_BotTurnStops(t *testing.T) {
+	tests := []struct {
+		name string
+		stop func(g *Game, botID string)
+	}{
+		{"Game over", func(g *Game, botID string) {
+			g.GameState.Lock()
+			g.GameState.WinnerID = botID
+			g.GameState.Unlock()
+			g.checkGameOver()
+		}},
+		{"Paused", func(g *Game, botID string) {
+			g.handleMessage([]byte(`{"type":"pause","player_id":"` + g.GameState.OwnerID + `"}`))
+		}},
+		{"Turn passed by the timer", func(g *Game, botID string) {
+			g.finishTurn(botID, "%s esgotou o tempo do turno.")
+		}},
+		{"Lease lost", func(g *Game, botID string) {
+			g.owner = true
+			g.setOwner(false)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game, botID, sent := newBotTurnGame(t)
+
+			game.startBotTurn(botID, 0)
+			select {
+			case <-sent:
+			case <-time.After(time.Second):
+				t.Fatal("bot sent no action within 1s")
+			}
+			if botGoroutines() != 1 {
+				t.Fatalf("%d bot turns running, want 1", botGoroutines())
+			}
+
+			tt.stop(game, botID)
+			waitForNoBots(t, time.Second)
+		})
+	}
+}
+
+func TestGame_StartBotTurnCancelsThePrevious(t *testing.T) {
+	game, botID, _ := newBotTurnGame(t)
+	game.pacing.Start = time.Minute
+
+	game.startBotTurn(botID, 0)
+	game.startBotTurn(botID, 0)
+	waitForBots(t, 1, time.Second)
+
+	game.stopBotTurn()
+	waitForNoBots(t, time.Second)
+}
+
+// Bots playing through the game's loop leave no turn behind once the game
+// moves to another instance
+func TestGame_BotsDoNotLeak(t *testing.T) {
+	pacing := DefaultBotPacing
+	DefaultBotPacing = BotPacing{}
+	t.Cleanup(func() { DefaultBotPacing = pacing })
+
+	state := newBotGame(room.BotHard, room.BotMedium, room.BotEasy)
+	state.RoomID = uuid.NewString()
+	running := gameGoroutines()
+
+	archive := repository.NewMemoryGameRepository()
+	manager := NewGameManager(repository.NewMemoryRoomRepository(), archive, nil, NewLocalCluster())
+	manager.newGame(state.RoomID, state).persist()
+
+	game, err := manager.GetOrCreateGame(state.RoomID)
+	if err != nil {
+		t.Fatalf("GetOrCreateGame() error = %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		game.GameState.RLock()
+		turn, winner := game.GameState.TurnNumber, game.GameState.WinnerID
+		game.GameState.RUnlock()
+		if turn > 6 || winner != "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("bots played %d turns in 10s, want 6", turn-1)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	game.ownership <- false
+	waitForNoBots(t, time.Second)
+
+	// Taking the lease back resumes the bots, and ending the game then stops
+	// everything it runs. The loop takes the lease before the client, so the
+	// restored state is in place once the client is registered.
+	game.ownership <- true
+	game.register <- &Client{id: state.TurnOrder[0], send: make(chan []byte, 256)}
+
+	game.GameState.Lock()
+	game.GameState.WinnerID = game.GameState.TurnOrder[0]
+	game.GameState.Unlock()
+
+	sync, _ := json.Marshal(map[string]any{"type": "sync"})
+	select {
+	case game.broadcast <- sync:
+	case <-game.Done():
+	}
+
+	select {
+	case <-game.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("game still running after it ended")
+	}
+	waitForNoBots(t, time.Second)
+
+	for deadline := time.Now().Add(time.Second); gameGoroutines() > running; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d game loops and lease holders still running, want %d", gameGoroutines(), running)
+		}
+	}
+}
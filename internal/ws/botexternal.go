@@ -0,0 +1,345 @@
+package ws
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"es2.uff/war-server/internal/domain/room"
+)
+
+// ExternalBotTimeout is how long an external bot has for each decision
+// before the built-in strategy decides instead
+var ExternalBotTimeout = 2 * time.Second
+
+// BotTransport carries the requests of the server to an external bot and
+// brings back its choices
+type BotTransport interface {
+	Decide(ctx context.Context, req BotRequest) (BotAction, error)
+}
+
+// NewExternalBot returns the transport to an external bot: an HTTP callback
+// for http and https URLs, otherwise a command line to run as a subprocess
+func NewExternalBot(target string) (BotTransport, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return NewHTTPBot(target), nil
+	}
+
+	command := strings.Fields(target)
+	if len(command) == 0 {
+		return nil, fmt.Errorf("empty bot command")
+	}
+	return NewProcessBot(command[0], command[1:]...), nil
+}
+
+var (
+	externalBotsMu sync.RWMutex
+	externalBots   = make(map[string]BotTransport)
+)
+
+// RegisterExternalBot makes the bot available to rooms, which pick it with
+// the difficulty room.ExternalBotPrefix followed by name
+func RegisterExternalBot(name string, transport BotTransport) {
+	externalBotsMu.Lock()
+	defer externalBotsMu.Unlock()
+
+	externalBots[name] = transport
+	room.AddExternalBot(name)
+}
+
+// RegisterExternalBots registers the bots of a list of name=command or
+// name=URL separated by semicolons, empty entries are skipped
+func RegisterExternalBots(list string) error {
+	for _, spec := range strings.Split(list, ";") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		name, target, found := strings.Cut(spec, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("external bot %q is not name=command or name=URL", spec)
+		}
+		name = strings.TrimSpace(name)
+		transport, err := NewExternalBot(target)
+		if err != nil {
+			return fmt.Errorf("external bot %q: %w", name, err)
+		}
+		RegisterExternalBot(name, transport)
+	}
+	return nil
+}
+
+func externalBot(name string) BotTransport {
+	externalBotsMu.RLock()
+	defer externalBotsMu.RUnlock()
+	return externalBots[name]
+}
+
+// externalStrategy asks an external bot for every decision and checks it
+// against the legal actions. A bot that fails, is too slow or picks an
+// illegal action is replaced by the fallback for that decision.
+type externalStrategy struct {
+	transport BotTransport
+	fallback  BotStrategy
+	timeout   time.Duration
+	ctx       context.Context // Of the bot's turn, nil outside of games
+}
+
+// ask returns the external bot's choice, false when the fallback decides
+func (s *externalStrategy) ask(decision BotDecision, v *BotView, attack *BotAttack) (BotAction, bool) {
+	actions := LegalActions(decision, v, attack)
+	if len(actions) == 0 {
+		return BotAction{}, false
+	}
+
+	parent := s.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, s.timeout)
+	defer cancel()
+
+	choice, err := s.transport.Decide(ctx, BotRequest{Decision: decision, State: v, Actions: actions, Attack: attack})
+	if err == nil {
+		err = CheckAction(decision, actions, choice)
+	}
+	if err != nil {
+		log.Printf("External bot %s failed to decide %s: %v", v.BotID, decision, err)
+		return BotAction{}, false
+	}
+	return choice, true
+}
+
+func (s *externalStrategy) Trade(v *BotView) ([3]string, bool) {
+	choice, ok := s.ask(DecideTrade, v, nil)
+	if !ok {
+		return s.fallback.Trade(v)
+	}
+	if choice.Pass {
+		return [3]string{}, false
+	}
+	return [3]string(choice.Cards), true
+}
+
+func (s *externalStrategy) Deploy(v *BotView) string {
+	choice, ok := s.ask(DecideDeploy, v, nil)
+	if !ok {
+		return s.fallback.Deploy(v)
+	}
+	return choice.TerritoryID
+}
+
+func (s *externalStrategy) Attack(v *BotView) (BotAttack, bool) {
+	choice, ok := s.ask(DecideAttack, v, nil)
+	if !ok {
+		return s.fallback.Attack(v)
+	}
+	if choice.Pass {
+		return BotAttack{}, false
+	}
+	return BotAttack{From: choice.From, To: choice.To, Armies: choice.Armies}, true
+}
+
+func (s *externalStrategy) Occupy(v *BotView, attack BotAttack) int {
+	choice, ok := s.ask(DecideOccupy, v, &attack)
+	if !ok {
+		return s.fallback.Occupy(v, attack)
+	}
+	return choice.Armies
+}
+
+func (s *externalStrategy) Fortify(v *BotView) (BotMove, bool) {
+	choice, ok := s.ask(DecideFortify, v, nil)
+	if !ok {
+		return s.fallback.Fortify(v)
+	}
+	if choice.Pass {
+		return BotMove{}, false
+	}
+	return BotMove{From: choice.From, To: choice.To, Armies: choice.Armies}, true
+}
+
+// ProcessBot runs an external bot as a subprocess speaking JSON lines over
+// its stdin and stdout. The process is started on the first request and
+// again after it exits. Games share it, their requests are sent as they come
+// and the replies are matched to them by seq.
+type ProcessBot struct {
+	name string
+	args []string
+
+	mu      sync.Mutex
+	seq     int
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	pending map[int]chan BotReply // Requests waiting for their reply, closed when the process stops
+}
+
+func NewProcessBot(name string, args ...string) *ProcessBot {
+	return &ProcessBot{name: name, args: args}
+}
+
+func (b *ProcessBot) Decide(ctx context.Context, req BotRequest) (BotAction, error) {
+	seq, reply, err := b.send(req)
+	if err != nil {
+		return BotAction{}, err
+	}
+
+	select {
+	case r, ok := <-reply:
+		if !ok {
+			return BotAction{}, errors.New("bot process exited")
+		}
+		return r.BotAction, nil
+	case <-ctx.Done():
+		// A late reply finds no request and is dropped
+		b.mu.Lock()
+		delete(b.pending, seq)
+		b.mu.Unlock()
+		return BotAction{}, ctx.Err()
+	}
+}
+
+// send writes the request to the process and returns the channel its reply
+// comes on
+func (b *ProcessBot) send(req BotRequest) (int, chan BotReply, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cmd == nil {
+		if err := b.startLocked(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	b.seq++
+	req.Seq = b.seq
+	line, err := json.Marshal(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, err := b.stdin.Write(append(line, '\n')); err != nil {
+		b.stopLocked()
+		return 0, nil, err
+	}
+
+	reply := make(chan BotReply, 1)
+	b.pending[req.Seq] = reply
+	return req.Seq, reply, nil
+}
+
+// Close stops the process, a later request starts it again
+func (b *ProcessBot) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopLocked()
+}
+
+func (b *ProcessBot) startLocked() error {
+	cmd := exec.Command(b.name, b.args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting bot %s: %w", b.name, err)
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			var reply BotReply
+			if err := json.Unmarshal(scanner.Bytes(), &reply); err != nil {
+				log.Printf("Invalid reply from bot %s: %v", b.name, err)
+				continue
+			}
+
+			b.mu.Lock()
+			if ch, ok := b.pending[reply.Seq]; ok && b.cmd == cmd {
+				ch <- reply
+				delete(b.pending, reply.Seq)
+			}
+			b.mu.Unlock()
+		}
+		// Wait once stdout is read to the end, as it closes the pipe
+		cmd.Wait()
+
+		b.mu.Lock()
+		if b.cmd == cmd {
+			b.stopLocked()
+		}
+		b.mu.Unlock()
+	}()
+
+	b.cmd, b.stdin, b.pending = cmd, stdin, make(map[int]chan BotReply)
+	return nil
+}
+
+func (b *ProcessBot) stopLocked() {
+	if b.cmd == nil {
+		return
+	}
+	for _, reply := range b.pending {
+		close(reply)
+	}
+	b.stdin.Close()
+	b.cmd.Process.Kill()
+	b.cmd, b.stdin, b.pending = nil, nil, nil
+}
+
+// HTTPBot POSTs each request to an external bot's URL and reads its choice
+// from the response
+type HTTPBot struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPBot(url string) *HTTPBot {
+	return &HTTPBot{url: url, client: &http.Client{}}
+}
+
+func (b *HTTPBot) Decide(ctx context.Context, req BotRequest) (BotAction, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return BotAction{}, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return BotAction{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		return BotAction{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return BotAction{}, fmt.Errorf("bot responded %s", resp.Status)
+	}
+
+	var reply BotReply
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return BotAction{}, fmt.Errorf("invalid reply: %w", err)
+	}
+	return reply.BotAction, nil
+}
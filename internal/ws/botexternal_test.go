@@ -0,0 +1,165 @@
+package ws
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"es2.uff/war-server/internal/domain/room"
+)
+
+// TestRefBotProcess is not a test: run by the process tests with WAR_REFBOT
+// set, it is the reference bot answering on stdin and stdout
+func TestRefBotProcess(t *testing.T) {
+	if os.Getenv("WAR_REFBOT") != "1" {
+		t.Skip("only runs as a bot process")
+	}
+	ServeBot(os.Stdin, os.Stdout, &mediumStrategy{})
+	os.Exit(0)
+}
+
+// stubTransport replies with a fixed action, or waits for the request to
+// time out
+type stubTransport struct {
+	reply BotAction
+	hang  bool
+}
+
+func (s *stubTransport) Decide(ctx context.Context, req BotRequest) (BotAction, error) {
+	if s.hang {
+		<-ctx.Done()
+		return BotAction{}, ctx.Err()
+	}
+	return s.reply, nil
+}
+
+// checkExternalAttack checks that the external bot behind transport attacks
+// like the medium strategy
+func checkExternalAttack(t *testing.T, transport BotTransport) {
+	t.Helper()
+
+	v := newTestBotView()
+	v.Territory("a3").Armies = 8
+	s := &externalStrategy{transport: transport, fallback: &easyStrategy{}, timeout: 10 * time.Second}
+
+	attack, ok := s.Attack(v)
+	if !ok || attack.From != "a3" || attack.To != "e1" || attack.Armies != 3 {
+		t.Errorf("Attack() = %+v, %v, want a3 on e1 with 3 armies", attack, ok)
+	}
+	if got := s.Deploy(v); got != "a2" {
+		t.Errorf("Deploy() = %s, want a2", got)
+	}
+}
+
+func TestProcessBot(t *testing.T) {
+	t.Setenv("WAR_REFBOT", "1")
+	bot := NewProcessBot(os.Args[0], "-test.run=^TestRefBotProcess$")
+	t.Cleanup(bot.Close)
+
+	checkExternalAttack(t, bot)
+
+	// A bot that was stopped starts again on the next request
+	bot.Close()
+	checkExternalAttack(t, bot)
+}
+
+func TestProcessBot_Concurrent(t *testing.T) {
+	t.Setenv("WAR_REFBOT", "1")
+	bot := NewProcessBot(os.Args[0], "-test.run=^TestRefBotProcess$")
+	t.Cleanup(bot.Close)
+
+	// A request given up on leaves its reply to be dropped
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bot.Decide(ctx, BotRequest{Decision: DecideAttack, State: newTestBotView()})
+
+	// Games share the process and each gets its own reply
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() { checkExternalAttack(t, bot) })
+	}
+	wg.Wait()
+}
+
+func TestHTTPBot(t *testing.T) {
+	server := httptest.NewServer(NewBotHandler(&mediumStrategy{}))
+	t.Cleanup(server.Close)
+
+	checkExternalAttack(t, NewHTTPBot(server.URL))
+}
+
+func TestExternalStrategy_FallsBack(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport *stubTransport
+	}{
+		{"Too slow", &stubTransport{hang: true}},
+		{"Illegal attack", &stubTransport{reply: BotAction{From: "a1", To: "e1", Armies: 3}}},
+		{"Too many armies", &stubTransport{reply: BotAction{From: "a3", To: "e1", Armies: 7}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestBotView()
+			v.Territory("a3").Armies = 8
+			s := &externalStrategy{transport: tt.transport, fallback: &mediumStrategy{}, timeout: 20 * time.Millisecond}
+
+			attack, ok := s.Attack(v)
+			if !ok || attack != (BotAttack{From: "a3", To: "e1", Armies: 3}) {
+				t.Errorf("Attack() = %+v, %v, want the medium strategy's a3 on e1", attack, ok)
+			}
+		})
+	}
+}
+
+func TestExternalStrategy_StopsWithTheTurn(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	v := newTestBotView()
+	v.Territory("a3").Armies = 8
+	s := &externalStrategy{transport: &stubTransport{hang: true}, fallback: &mediumStrategy{}, timeout: time.Minute, ctx: ctx}
+
+	start := time.Now()
+	if _, ok := s.Attack(v); !ok {
+		t.Error("Attack() = false, want the medium strategy's attack")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Attack() took %v after the turn was cancelled", elapsed)
+	}
+}
+
+func TestNewBotStrategy_External(t *testing.T) {
+	RegisterExternalBot("stub", &stubTransport{})
+	if _, ok := NewBotStrategy("external:stub", nil).(*externalStrategy); !ok {
+		t.Error("registered external bot did not get the external strategy")
+	}
+	if _, ok := NewBotStrategy("external:missing", nil).(*objectiveStrategy); !ok {
+		t.Error("unknown external bot did not fall back to the hard strategy")
+	}
+}
+
+func TestRegisterExternalBots(t *testing.T) {
+	tests := []struct {
+		list    string
+		wantErr bool
+	}{
+		{"", false},
+		{" listed = ./bot --fast ; ", false},
+		{"refbot", true},
+		{"=./bot", true},
+		{"empty=", true},
+	}
+
+	for _, tt := range tests {
+		if err := RegisterExternalBots(tt.list); (err != nil) != tt.wantErr {
+			t.Errorf("RegisterExternalBots(%q) error = %v, want error %v", tt.list, err, tt.wantErr)
+		}
+	}
+	if !room.BotDifficulty("external:listed").Valid() {
+		t.Error("external:listed is not a valid difficulty once registered")
+	}
+}
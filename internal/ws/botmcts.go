@@ -0,0 +1,272 @@
+package ws
+
+import (
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"es2.uff/war-server/internal/domain/card"
+	"es2.uff/war-server/internal/domain/objective"
+	"es2.uff/war-server/internal/domain/room"
+)
+
+// MCTSBudget bounds the search behind each attack of the expert bot, the
+// search stops at whichever limit comes first
+type MCTSBudget struct {
+	Iterations int
+	Time       time.Duration // Zero leaves the search to Iterations, which repeats for a seed
+	Rounds     int           // Rounds played on after the bot's turn before scoring
+}
+
+// DefaultMCTSBudget is the budget of the expert bots in games
+var DefaultMCTSBudget = MCTSBudget{Iterations: 400, Time: 300 * time.Millisecond, Rounds: 2}
+
+const (
+	// mctsAttacks is how many of the best scored attacks a node considers
+	mctsAttacks = 8
+	// mctsExploration weighs trying attacks seen less against the best ones
+	mctsExploration = 1.4
+)
+
+// mctsAction is a choice of a search node, an attack or ending the attacks
+type mctsAction struct {
+	attack BotAttack
+	stop   bool
+}
+
+type mctsNode struct {
+	action   mctsAction
+	expanded bool
+	untried  []mctsAction
+	children []*mctsNode
+	visits   int
+	reward   float64
+}
+
+// selectChild returns the child with the best upper confidence bound
+func (n *mctsNode) selectChild() *mctsNode {
+	var best *mctsNode
+	bestValue := 0.0
+	for _, c := range n.children {
+		value := c.reward/float64(c.visits) +
+			mctsExploration*math.Sqrt(math.Log(float64(n.visits))/float64(c.visits))
+		if best == nil || value > bestValue {
+			best, bestValue = c, value
+		}
+	}
+	return best
+}
+
+// mctsStrategy is the expert bot: it chooses its attacks with Monte Carlo
+// tree search and decides everything else like the hard bot. Every iteration
+// guesses what the bot cannot see, the objectives and cards of the others and
+// the order of the deck, rolls fresh dice down the tree and plays the game on
+// for a few rounds with the simpler strategies.
+type mctsStrategy struct {
+	objectiveStrategy
+	budget MCTSBudget
+	rng    *rand.Rand
+}
+
+// NewMCTSStrategy returns an expert bot searching within budget
+func NewMCTSStrategy(budget MCTSBudget, rng *rand.Rand) BotStrategy {
+	return &mctsStrategy{budget: budget, rng: rng}
+}
+
+func (s *mctsStrategy) Attack(v *BotView) (BotAttack, bool) {
+	if len(v.AttackOptions()) == 0 {
+		return BotAttack{}, false
+	}
+
+	root := &mctsNode{}
+	deadline := time.Now().Add(s.budget.Time)
+	for i := 0; i < s.budget.Iterations && (s.budget.Time == 0 || time.Now().Before(deadline)); i++ {
+		s.iterate(root, s.sample(v), v.BotID)
+	}
+
+	var best *mctsNode
+	for _, c := range root.children {
+		if best == nil || c.visits > best.visits {
+			best = c
+		}
+	}
+	if best == nil || best.action.stop {
+		return BotAttack{}, false
+	}
+	return best.action.attack, true
+}
+
+// iterate walks down the tree playing its attacks on state, adds one node
+// and scores it with a playout
+func (s *mctsStrategy) iterate(root *mctsNode, state *GameState, botID string) {
+	path := []*mctsNode{root}
+	node := root
+	attacking := true
+
+	for attacking {
+		if !node.expanded {
+			node.expanded = true
+			node.untried = mctsActions(state.BotView(botID))
+		}
+
+		if n := len(node.untried); n > 0 {
+			child := &mctsNode{action: node.untried[n-1]}
+			node.untried = node.untried[:n-1]
+			node.children = append(node.children, child)
+			path = append(path, child)
+			attacking = s.apply(state, botID, child.action)
+			break
+		}
+
+		node = node.selectChild()
+		if node == nil {
+			break
+		}
+		path = append(path, node)
+		attacking = s.apply(state, botID, node.action)
+	}
+
+	reward := s.playout(state, botID, attacking)
+	for _, n := range path {
+		n.visits++
+		n.reward += reward
+	}
+}
+
+// mctsActions returns the best scored attacks of the view and stopping
+func mctsActions(v *BotView) []mctsAction {
+	options := v.AttackOptions()
+	score := func(a BotAttack) int { return attackScore(v, v.Territory(a.From), v.Territory(a.To)) }
+	slices.SortStableFunc(options, func(a, b BotAttack) int { return score(b) - score(a) })
+
+	actions := []mctsAction{{stop: true}}
+	for _, option := range options[:min(len(options), mctsAttacks)] {
+		actions = append(actions, mctsAction{attack: option})
+	}
+	return actions
+}
+
+// apply plays the action on state and reports whether the bot goes on
+// attacking. An attack this sample does not allow ends the attacks.
+func (s *mctsStrategy) apply(state *GameState, botID string, action mctsAction) bool {
+	if action.stop {
+		return false
+	}
+	if _, err := state.Attack(botID, action.attack.From, action.attack.To, action.attack.Armies); err != nil {
+		return false
+	}
+	if state.Winner() != "" {
+		return false
+	}
+	occupy(state, botID, &s.objectiveStrategy, action.attack)
+	return true
+}
+
+// playout ends the bot's turn, plays the configured rounds and scores the
+// result for the bot
+func (s *mctsStrategy) playout(state *GameState, botID string, attacking bool) float64 {
+	self := &objectiveStrategy{}
+	if attacking {
+		playAttacks(state, botID, self)
+	}
+	if state.Winner() == "" {
+		playFortify(state, botID, self)
+		state.NextTurn(botID)
+	}
+
+	for range s.budget.Rounds * len(state.TurnOrder) {
+		if state.Winner() != "" {
+			break
+		}
+		var strategy BotStrategy = &mediumStrategy{}
+		if state.CurrentTurn == botID {
+			strategy = &objectiveStrategy{}
+		}
+		if PlayBotTurn(state, strategy) != nil {
+			break
+		}
+	}
+	return mctsReward(state, botID)
+}
+
+// mctsReward is 1 for a win and 0 for a loss, otherwise the share of the
+// territories and armies the bot holds, kept below a win
+func mctsReward(state *GameState, botID string) float64 {
+	switch state.WinnerID {
+	case botID:
+		return 1
+	case "":
+	default:
+		return 0
+	}
+
+	territories, armies, totalArmies := 0, 0, 0
+	for _, t := range state.Territories {
+		totalArmies += t.Armies
+		if t.Owner == botID {
+			territories++
+			armies += t.Armies
+		}
+	}
+	if totalArmies == 0 {
+		return 0
+	}
+	share := float64(territories)/float64(len(state.Territories)) + float64(armies)/float64(totalArmies)
+	return 0.45 * share
+}
+
+// sample builds a game from the view, with the objectives and cards of the
+// other players and the order of the deck drawn at random
+func (s *mctsStrategy) sample(v *BotView) *GameState {
+	gs := &GameState{
+		Players:     make(map[string]*Player, len(v.TurnOrder)),
+		Territories: copyTerritories(v.Territories),
+		TurnOrder:   slices.Clone(v.TurnOrder),
+		CurrentTurn: v.BotID,
+		TurnNumber:  1,
+		TradesCount: v.TradesCount,
+		Settings:    room.DefaultSettings(),
+		Stats:       make(map[string]*PlayerStats),
+		rng:         rand.NewPCG(s.rng.Uint64(), s.rng.Uint64()),
+		simulation:  true,
+	}
+
+	ownObjective := objective.ObjectiveID(v.ObjectiveID)
+	if _, exists := objective.ObjectiveDetails[ownObjective]; !exists {
+		gs.Settings.RuleVariant = room.VariantWorldDomination
+	}
+	objectives := slices.Sorted(maps.Keys(objective.ObjectiveDetails))
+	objectives = slices.DeleteFunc(objectives, func(id objective.ObjectiveID) bool { return id == ownObjective })
+	s.rng.Shuffle(len(objectives), func(i, j int) { objectives[i], objectives[j] = objectives[j], objectives[i] })
+
+	held := make(map[string]bool, len(v.Cards))
+	for _, c := range v.Cards {
+		held[c.TerritoryName] = true
+	}
+	deck := slices.DeleteFunc(card.NewDeck().Cards, func(c card.Card) bool { return held[c.TerritoryName] })
+	s.rng.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+	gs.Deck = &card.Deck{Cards: deck}
+
+	for i, id := range v.TurnOrder {
+		p := &Player{ID: id, IsBot: true, ObjectiveID: int(objectives[i%len(objectives)])}
+		hand := v.Cards
+		if id == v.BotID {
+			p.Armies = v.Armies
+			p.ObjectiveID = v.ObjectiveID
+		} else {
+			hand = nil
+			for range v.HandSizes[id] {
+				if c := gs.Deck.Draw(); c != nil {
+					hand = append(hand, *c)
+				}
+			}
+		}
+		for _, c := range hand {
+			p.CardsInHand = append(p.CardsInHand, &c)
+		}
+		gs.Players[id] = p
+	}
+	return gs
+}
@@ -0,0 +1,59 @@
+package ws
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"es2.uff/war-server/internal/domain/room"
+)
+
+func newTestMCTS() *mctsStrategy {
+	budget := MCTSBudget{Iterations: 200, Time: time.Minute, Rounds: 1}
+	return NewMCTSStrategy(budget, rand.New(rand.NewPCG(1, 2))).(*mctsStrategy)
+}
+
+func TestMCTSStrategy_TakesTheWinningAttack(t *testing.T) {
+	v := newTestBotView()
+	v.TurnOrder = []string{"bot", "enemy"}
+	v.ObjectiveID = -1 // World domination
+	v.Territory("a3").Armies = 12
+	v.Territory("e1").Armies = 1
+
+	attack, ok := newTestMCTS().Attack(v)
+	if !ok || attack.From != "a3" || attack.To != "e1" {
+		t.Errorf("Attack() = %+v, %v, want a3 on e1 for the win", attack, ok)
+	}
+}
+
+func TestMCTSStrategy_AttacksLegally(t *testing.T) {
+	state := newBotGame(room.BotExpert, room.BotMedium, room.BotMedium)
+	botID := state.CurrentTurn
+	s := newTestMCTS()
+
+	for state.BotView(botID).Armies > 0 {
+		state.Deploy(botID, s.Deploy(state.BotView(botID)))
+	}
+
+	attack, ok := s.Attack(state.BotView(botID))
+	if !ok {
+		return
+	}
+	if _, err := state.Attack(botID, attack.From, attack.To, attack.Armies); err != nil {
+		t.Errorf("Attack() = %+v, which the game refused: %v", attack, err)
+	}
+}
+
+func TestMCTSStrategy_KeepsItsBudget(t *testing.T) {
+	state := newBotGame(room.BotExpert, room.BotMedium, room.BotMedium)
+	v := state.BotView(state.CurrentTurn)
+	v.Territories[0].Armies = 20
+	v.Territories[0].Owner = v.BotID
+
+	s := NewMCTSStrategy(MCTSBudget{Iterations: 1 << 30, Time: 50 * time.Millisecond, Rounds: 1}, rand.New(rand.NewPCG(1, 2)))
+	start := time.Now()
+	s.Attack(v)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Attack() took %v with a budget of 50ms", elapsed)
+	}
+}
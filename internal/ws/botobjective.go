@@ -0,0 +1,211 @@
+package ws
+
+import (
+	"maps"
+	"slices"
+
+	"es2.uff/war-server/internal/domain/objective"
+)
+
+// objectivePlan is what a bot works towards to complete its objective
+type objectivePlan struct {
+	regions   map[int]bool // Continents to conquer
+	spread    bool         // Hold as many territories as possible instead
+	minArmies int          // Armies to keep on each territory when spreading
+}
+
+// planObjective reads the bot's objective. The "and a third one" continent is
+// the one held by the fewest enemy armies, and world domination, which has
+// no objective card, plays like a territory count.
+func planObjective(v *BotView) objectivePlan {
+	details, exists := objective.ObjectiveDetails[objective.ObjectiveID(v.ObjectiveID)]
+	if !exists {
+		return objectivePlan{spread: true, minArmies: 1}
+	}
+	if details.Type == objective.TerritoryCount {
+		return objectivePlan{spread: true, minArmies: details.MinArmiesPerTerritory}
+	}
+
+	plan := objectivePlan{regions: make(map[int]bool)}
+	for _, r := range details.RequiredRegions {
+		plan.regions[int(r)] = true
+	}
+	if details.RequiresAdditionalRegion {
+		if extra, ok := cheapestRegion(v, plan.regions); ok {
+			plan.regions[extra] = true
+		}
+	}
+	return plan
+}
+
+// cheapestRegion returns the continent outside exclude with the fewest enemy
+// armies on it
+func cheapestRegion(v *BotView, exclude map[int]bool) (int, bool) {
+	enemyArmies := make(map[int]int)
+	for _, t := range v.Territories {
+		if exclude[t.Region] {
+			continue
+		}
+		if _, seen := enemyArmies[t.Region]; !seen {
+			enemyArmies[t.Region] = 0
+		}
+		if t.Owner != v.BotID {
+			enemyArmies[t.Region] += t.Armies
+		}
+	}
+
+	best, found := 0, false
+	for _, r := range slices.Sorted(maps.Keys(enemyArmies)) {
+		if !found || enemyArmies[r] < enemyArmies[best] {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// heldRegions returns the continents the bot holds entirely
+func heldRegions(v *BotView) map[int]bool {
+	held := make(map[int]bool)
+	for _, t := range v.Territories {
+		if _, seen := held[t.Region]; !seen {
+			held[t.Region] = true
+		}
+		if t.Owner != v.BotID {
+			held[t.Region] = false
+		}
+	}
+	maps.DeleteFunc(held, func(_ int, h bool) bool { return !h })
+	return held
+}
+
+// threatenedBorder returns the border of a held continent most outnumbered
+// by its enemy neighbors, nil when none is outnumbered
+func threatenedBorder(v *BotView) *Territory {
+	held := heldRegions(v)
+
+	var worst *Territory
+	worstDeficit := 0
+	for _, t := range v.Owned() {
+		if !held[t.Region] {
+			continue
+		}
+		deficit := -t.Armies
+		for _, enemy := range v.EnemyNeighbors(t) {
+			deficit += enemy.Armies
+		}
+		if deficit > worstDeficit {
+			worst, worstDeficit = t, deficit
+		}
+	}
+	return worst
+}
+
+// objectiveStrategy is the hard bot playing for its objective: it defends the
+// continents it holds, stacks armies next to the continents it needs and,
+// for territory counts, spreads out over the weakest territories
+type objectiveStrategy struct {
+	hardStrategy
+}
+
+// score rates an attack from from on to for the plan, higher is better
+func (s *objectiveStrategy) score(v *BotView, plan objectivePlan, from, to *Territory) int {
+	if plan.spread {
+		// Any territory counts, so the cheapest ones come first
+		return from.Armies - 2*to.Armies
+	}
+
+	score := attackScore(v, from, to)
+	if plan.regions[to.Region] {
+		score += 5
+	}
+	return score
+}
+
+func (s *objectiveStrategy) Deploy(v *BotView) string {
+	if t := threatenedBorder(v); t != nil {
+		return t.ID
+	}
+
+	plan := planObjective(v)
+	if plan.spread {
+		for _, t := range v.Owned() {
+			if t.Armies < plan.minArmies {
+				return t.ID
+			}
+		}
+	}
+
+	best, bestScore := "", 0
+	for _, from := range v.Owned() {
+		for _, to := range v.EnemyNeighbors(from) {
+			if score := s.score(v, plan, from, to); best == "" || score > bestScore {
+				best, bestScore = from.ID, score
+			}
+		}
+	}
+	if best == "" {
+		return s.hardStrategy.Deploy(v)
+	}
+	return best
+}
+
+func (s *objectiveStrategy) Attack(v *BotView) (BotAttack, bool) {
+	plan := planObjective(v)
+
+	var best BotAttack
+	bestScore := 0
+	found := false
+	for _, option := range v.AttackOptions() {
+		from, to := v.Territory(option.From), v.Territory(option.To)
+		if !goodOdds(from, to) {
+			continue
+		}
+		if score := s.score(v, plan, from, to); !found || score > bestScore {
+			best, bestScore, found = option, score, true
+		}
+	}
+	return best, found
+}
+
+// Occupy keeps the minimum of a territory count objective on both sides of a
+// conquest, and otherwise follows the hard bot
+func (s *objectiveStrategy) Occupy(v *BotView, attack BotAttack) int {
+	plan := planObjective(v)
+	if !plan.spread || plan.minArmies <= 1 {
+		return s.hardStrategy.Occupy(v, attack)
+	}
+
+	from, to := v.Territory(attack.From), v.Territory(attack.To)
+	missing := plan.minArmies - to.Armies
+	if missing <= 0 || from.Armies-missing < plan.minArmies {
+		return 0
+	}
+	return missing
+}
+
+// Fortify follows the hard bot, but for a territory count objective leaves
+// the minimum on the interior territories it moves armies from
+func (s *objectiveStrategy) Fortify(v *BotView) (BotMove, bool) {
+	plan := planObjective(v)
+	if !plan.spread || plan.minArmies <= 1 {
+		return s.hardStrategy.Fortify(v)
+	}
+
+	owned := v.Owned()
+	slices.SortStableFunc(owned, func(a, b *Territory) int { return b.Armies - a.Armies })
+
+	for _, from := range owned {
+		armies := from.Armies - plan.minArmies
+		if armies < 1 || len(v.EnemyNeighbors(from)) > 0 {
+			continue
+		}
+
+		for _, id := range from.Adjacent {
+			to := v.Territory(id)
+			if to != nil && to.Owner == v.BotID && len(v.EnemyNeighbors(to)) > 0 {
+				return BotMove{From: from.ID, To: to.ID, Armies: armies}, true
+			}
+		}
+	}
+	return BotMove{}, false
+}
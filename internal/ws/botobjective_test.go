@@ -0,0 +1,150 @@
+package ws
+
+import (
+	"testing"
+
+	"es2.uff/war-server/internal/domain/objective"
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/domain/territory"
+)
+
+// newObjectiveTestView builds a board where the bot's b1 can attack a weak
+// x0 in Europe or a stronger x1 in Asia, and holds all of Africa with c1
+func newObjectiveTestView(objectiveID objective.ObjectiveID) *BotView {
+	europe, asia, africa := int(territory.Europe), int(territory.Asia), int(territory.Africa)
+
+	gs := NewGameState("room-1")
+	gs.Players["bot"] = &Player{ID: "bot", IsBot: true, ObjectiveID: int(objectiveID)}
+	gs.Territories = []*Territory{
+		{ID: "b1", Owner: "bot", Armies: 6, Region: europe, Adjacent: []string{"x0", "x1"}},
+		{ID: "x0", Owner: "enemy", Armies: 1, Region: europe, Adjacent: []string{"b1"}},
+		{ID: "x1", Owner: "enemy", Armies: 2, Region: asia, Adjacent: []string{"b1", "y1"}},
+		{ID: "y1", Owner: "enemy", Armies: 1, Region: asia, Adjacent: []string{"x1"}},
+		{ID: "c1", Owner: "bot", Armies: 3, Region: africa, Adjacent: []string{"z1"}},
+		{ID: "z1", Owner: "enemy", Armies: 2, Region: int(territory.Oceania), Adjacent: []string{"c1"}},
+	}
+	return newBotView(gs, "bot")
+}
+
+func TestPlanObjective(t *testing.T) {
+	v := newObjectiveTestView(objective.ConquerEuropeOceaniaAndOne)
+	plan := planObjective(v)
+	// Africa has no enemy armies, so it is the cheapest third continent
+	if plan.spread || !plan.regions[int(territory.Europe)] || !plan.regions[int(territory.Oceania)] ||
+		!plan.regions[int(territory.Africa)] || len(plan.regions) != 3 {
+		t.Errorf("planObjective() = %+v, want Europe, Oceania and Africa", plan)
+	}
+
+	v = newObjectiveTestView(objective.Conquer18TerritoriesWith2Armies)
+	if plan := planObjective(v); !plan.spread || plan.minArmies != 2 {
+		t.Errorf("planObjective() = %+v, want to spread with 2 armies each", plan)
+	}
+
+	// World domination games give bots no objective
+	v = newObjectiveTestView(-1)
+	if plan := planObjective(v); !plan.spread || plan.minArmies != 1 {
+		t.Errorf("planObjective() without objective = %+v, want to spread", plan)
+	}
+}
+
+func TestObjectiveStrategy_AttacksTowardsObjective(t *testing.T) {
+	s := &objectiveStrategy{}
+
+	v := newObjectiveTestView(objective.ConquerAsiaSouthAmerica)
+	if attack, ok := s.Attack(v); !ok || attack.To != "x1" {
+		t.Errorf("Attack() = %+v, %v, want x1 in Asia", attack, ok)
+	}
+
+	v = newObjectiveTestView(objective.Conquer24Territories)
+	if attack, ok := s.Attack(v); !ok || attack.To != "x0" {
+		t.Errorf("Attack() = %+v, %v, want the weakest territory x0", attack, ok)
+	}
+
+	// Bad odds are never taken, whatever the objective
+	v = newObjectiveTestView(objective.ConquerAsiaSouthAmerica)
+	v.Territory("b1").Armies = 2
+	if attack, ok := s.Attack(v); ok {
+		t.Errorf("Attack() = %+v, want no attack with 2 armies", attack)
+	}
+}
+
+func TestObjectiveStrategy_DefendsHeldContinents(t *testing.T) {
+	s := &objectiveStrategy{}
+
+	v := newObjectiveTestView(objective.ConquerAsiaSouthAmerica)
+	if got := s.Deploy(v); got != "b1" {
+		t.Errorf("Deploy() = %s, want b1 facing Asia", got)
+	}
+
+	v.Territory("z1").Armies = 8
+	if got := s.Deploy(v); got != "c1" {
+		t.Errorf("Deploy() = %s, want c1 defending Africa", got)
+	}
+}
+
+func TestObjectiveStrategy_OccupiesWithMinimumArmies(t *testing.T) {
+	s := &objectiveStrategy{}
+	v := newObjectiveTestView(objective.Conquer18TerritoriesWith2Armies)
+	v.Territory("x0").Owner = "bot"
+
+	if got := s.Occupy(v, BotAttack{From: "b1", To: "x0", Armies: 3}); got != 1 {
+		t.Errorf("Occupy() = %d, want 1 more army to reach 2 on x0", got)
+	}
+}
+
+func TestObjectiveStrategy_TopsUpInteriorTerritories(t *testing.T) {
+	s := &objectiveStrategy{}
+	v := newObjectiveTestView(objective.Conquer18TerritoriesWith2Armies)
+	v.Territories = append(v.Territories, &Territory{ID: "i1", Owner: "bot", Armies: 1, Region: int(territory.Europe), Adjacent: []string{"b1"}})
+	v.Territory("b1").Adjacent = append(v.Territory("b1").Adjacent, "i1")
+	v.index()
+
+	if got := s.Deploy(v); got != "i1" {
+		t.Errorf("Deploy() = %s, want i1 below the minimum", got)
+	}
+}
+
+func TestObjectiveStrategy_KeepsMinimumThroughTurn(t *testing.T) {
+	state := newBotGame(room.BotHard, room.BotEasy)
+	botID := state.CurrentTurn
+	bot := state.Players[botID]
+	bot.ObjectiveID = int(objective.Conquer18TerritoriesWith2Armies)
+	bot.CardsInHand = nil
+	bot.Armies = 2
+
+	// The bot holds an interior hub and its neighbors, against enemies too
+	// strong to attack, so the turn is deploying and fortifying only
+	var hub *Territory
+	for _, tr := range state.Territories {
+		if hub == nil || len(tr.Adjacent) > len(hub.Adjacent) {
+			hub = tr
+		}
+	}
+	neighbors := map[string]bool{}
+	for _, id := range hub.Adjacent {
+		neighbors[id] = true
+	}
+	for _, tr := range state.Territories {
+		switch {
+		case tr == hub:
+			tr.Owner, tr.Armies = botID, 8
+		case neighbors[tr.ID]:
+			tr.Owner, tr.Armies = botID, 2
+		default:
+			tr.Owner, tr.Armies = "enemy", 99
+		}
+	}
+
+	if err := PlayBotTurn(state, &objectiveStrategy{}); err != nil {
+		t.Fatalf("PlayBotTurn() error = %v", err)
+	}
+
+	if hub.Armies != 2 {
+		t.Errorf("hub has %d armies, want 2 after fortifying the borders", hub.Armies)
+	}
+	for _, tr := range state.Territories {
+		if tr.Owner == botID && tr.Armies < 2 {
+			t.Errorf("%s has %d armies, want at least 2", tr.ID, tr.Armies)
+		}
+	}
+}
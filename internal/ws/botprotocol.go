@@ -0,0 +1,192 @@
+package ws
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"es2.uff/war-server/internal/domain/card"
+)
+
+// BotDecision names what an external bot is asked to decide, one for each
+// method of BotStrategy
+type BotDecision string
+
+const (
+	DecideTrade   BotDecision = "trade"
+	DecideDeploy  BotDecision = "deploy"
+	DecideAttack  BotDecision = "attack"
+	DecideOccupy  BotDecision = "occupy"
+	DecideFortify BotDecision = "fortify"
+)
+
+// BotRequest asks an external bot for one decision. Over stdin and stdout
+// each request and reply is a single line of JSON, over HTTP the request is
+// POSTed and the reply is the response body.
+type BotRequest struct {
+	Seq      int         `json:"seq"` // Echoed in the reply
+	Decision BotDecision `json:"decision"`
+	State    *BotView    `json:"state"`
+	Actions  []BotAction `json:"actions"`          // The legal choices
+	Attack   *BotAttack  `json:"attack,omitempty"` // The won attack to occupy after
+}
+
+// BotAction is a choice of an external bot. The actions of a request carry
+// the most armies allowed, the reply picks one of them with the armies to
+// use.
+type BotAction struct {
+	Pass        bool     `json:"pass,omitempty"` // Keep the cards, stop attacking or moving
+	Cards       []string `json:"cards,omitempty"`
+	TerritoryID string   `json:"territory_id,omitempty"`
+	From        string   `json:"from,omitempty"`
+	To          string   `json:"to,omitempty"`
+	Armies      int      `json:"armies,omitempty"`
+}
+
+type BotReply struct {
+	Seq int `json:"seq"`
+	BotAction
+}
+
+// LegalActions lists the choices of a decision, empty when there is nothing
+// to decide
+func LegalActions(decision BotDecision, v *BotView, attack *BotAttack) []BotAction {
+	actions := []BotAction{}
+	switch decision {
+	case DecideTrade:
+		for _, set := range card.Sets(v.Cards) {
+			actions = append(actions, BotAction{Cards: []string{set[0].TerritoryName, set[1].TerritoryName, set[2].TerritoryName}})
+		}
+		if len(actions) > 0 && !v.MustTrade() {
+			actions = append(actions, BotAction{Pass: true})
+		}
+
+	case DecideDeploy:
+		if v.Armies > 0 {
+			for _, t := range v.Owned() {
+				actions = append(actions, BotAction{TerritoryID: t.ID})
+			}
+		}
+
+	case DecideAttack:
+		for _, option := range v.AttackOptions() {
+			actions = append(actions, BotAction{From: option.From, To: option.To, Armies: option.Armies})
+		}
+		if len(actions) > 0 {
+			actions = append(actions, BotAction{Pass: true})
+		}
+
+	case DecideOccupy:
+		if attack == nil {
+			break
+		}
+		if from := v.Territory(attack.From); from != nil && from.Armies > 1 {
+			actions = append(actions, BotAction{From: attack.From, To: attack.To, Armies: from.Armies - 1}, BotAction{Pass: true})
+		}
+
+	case DecideFortify:
+		for _, from := range v.Owned() {
+			if from.Armies <= 1 {
+				continue
+			}
+			for _, id := range from.Adjacent {
+				if to := v.Territory(id); to != nil && to.Owner == v.BotID {
+					actions = append(actions, BotAction{From: from.ID, To: to.ID, Armies: from.Armies - 1})
+				}
+			}
+		}
+		if len(actions) > 0 {
+			actions = append(actions, BotAction{Pass: true})
+		}
+	}
+	return actions
+}
+
+// CheckAction returns an error unless choice is one of the legal actions
+// with armies it is allowed to use
+func CheckAction(decision BotDecision, actions []BotAction, choice BotAction) error {
+	for _, legal := range actions {
+		if legal.Pass != choice.Pass || legal.TerritoryID != choice.TerritoryID ||
+			legal.From != choice.From || legal.To != choice.To {
+			continue
+		}
+		if decision == DecideTrade && !legal.Pass && !sameCards(legal.Cards, choice.Cards) {
+			continue
+		}
+		if legal.Armies > 0 && (choice.Armies < 1 || choice.Armies > legal.Armies) {
+			return fmt.Errorf("%d armies is not between 1 and %d", choice.Armies, legal.Armies)
+		}
+		return nil
+	}
+	return fmt.Errorf("%+v is not a legal %s", choice, decision)
+}
+
+// sameCards reports whether a and b name the same cards in any order
+func sameCards(a, b []string) bool {
+	return len(a) == len(b) && !slices.ContainsFunc(a, func(name string) bool { return !slices.Contains(b, name) })
+}
+
+// decide answers a request with a strategy, the way an external bot would
+func decide(strategy BotStrategy, req BotRequest) BotAction {
+	v := req.State
+	switch req.Decision {
+	case DecideTrade:
+		if cards, ok := strategy.Trade(v); ok {
+			return BotAction{Cards: cards[:]}
+		}
+	case DecideDeploy:
+		return BotAction{TerritoryID: strategy.Deploy(v)}
+	case DecideAttack:
+		if attack, ok := strategy.Attack(v); ok {
+			return BotAction{From: attack.From, To: attack.To, Armies: attack.Armies}
+		}
+	case DecideOccupy:
+		if req.Attack != nil {
+			if armies := strategy.Occupy(v, *req.Attack); armies > 0 {
+				return BotAction{From: req.Attack.From, To: req.Attack.To, Armies: armies}
+			}
+		}
+	case DecideFortify:
+		if move, ok := strategy.Fortify(v); ok {
+			return BotAction{From: move.From, To: move.To, Armies: move.Armies}
+		}
+	}
+	return BotAction{Pass: true}
+}
+
+// ServeBot answers the requests read from r with a strategy, one line each
+// on w, until r ends. It is the reference for external bots: run from a
+// main on stdin and stdout it makes a subprocess bot.
+func ServeBot(r io.Reader, w io.Writer, strategy BotStrategy) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	encoder := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		var req BotRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+		if err := encoder.Encode(BotReply{Seq: req.Seq, BotAction: decide(strategy, req)}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// NewBotHandler returns the HTTP variant of ServeBot
+func NewBotHandler(strategy BotStrategy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req BotRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(BotReply{Seq: req.Seq, BotAction: decide(strategy, req)})
+	})
+}
@@ -0,0 +1,102 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"es2.uff/war-server/internal/domain/card"
+	"es2.uff/war-server/internal/domain/territory"
+)
+
+func TestLegalActions(t *testing.T) {
+	v := newTestBotView()
+	v.Territory("a3").Armies = 5
+
+	attacks := LegalActions(DecideAttack, v, nil)
+	want := []BotAction{{From: "a3", To: "e1", Armies: 3}, {Pass: true}}
+	if len(attacks) != len(want) || attacks[0].From != "a3" || attacks[0].Armies != 3 || !attacks[1].Pass {
+		t.Errorf("attack actions = %+v, want %+v", attacks, want)
+	}
+
+	if deploys := LegalActions(DecideDeploy, v, nil); len(deploys) != 3 {
+		t.Errorf("deploy actions = %+v, want one for each of the 3 territories", deploys)
+	}
+	v.Armies = 0
+	if deploys := LegalActions(DecideDeploy, v, nil); len(deploys) != 0 {
+		t.Errorf("deploy actions = %+v, want none without armies", deploys)
+	}
+
+	if trades := LegalActions(DecideTrade, v, nil); len(trades) != 0 {
+		t.Errorf("trade actions = %+v, want none without cards", trades)
+	}
+}
+
+func TestCheckAction(t *testing.T) {
+	v := newTestBotView()
+	v.Territory("a3").Armies = 5
+	v.Cards = []card.Card{
+		{TerritoryName: "Brasil", Shape: territory.Square},
+		{TerritoryName: "Chile", Shape: territory.Square},
+		{TerritoryName: "Egito", Shape: territory.Square},
+	}
+
+	tests := []struct {
+		name     string
+		decision BotDecision
+		choice   BotAction
+		wantErr  bool
+	}{
+		{"Attack", DecideAttack, BotAction{From: "a3", To: "e1", Armies: 2}, false},
+		{"Stop attacking", DecideAttack, BotAction{Pass: true}, false},
+		{"Too many armies", DecideAttack, BotAction{From: "a3", To: "e1", Armies: 4}, true},
+		{"No armies", DecideAttack, BotAction{From: "a3", To: "e1"}, true},
+		{"Not a neighbor", DecideAttack, BotAction{From: "a1", To: "e1", Armies: 1}, true},
+		{"Trade in any order", DecideTrade, BotAction{Cards: []string{"Egito", "Brasil", "Chile"}}, false},
+		{"Trade other cards", DecideTrade, BotAction{Cards: []string{"Egito", "Brasil", "Congo"}}, true},
+		{"Deploy", DecideDeploy, BotAction{TerritoryID: "a2"}, false},
+		{"Deploy on an enemy", DecideDeploy, BotAction{TerritoryID: "e1"}, true},
+		{"Move to an enemy", DecideFortify, BotAction{From: "a3", To: "e1", Armies: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAction(tt.decision, LegalActions(tt.decision, v, nil), tt.choice)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckAction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServeBot(t *testing.T) {
+	v := newTestBotView()
+	v.Territory("a3").Armies = 8
+
+	var in bytes.Buffer
+	for i, decision := range []BotDecision{DecideDeploy, DecideAttack} {
+		line, _ := json.Marshal(BotRequest{Seq: i + 1, Decision: decision, State: v, Actions: LegalActions(decision, v, nil)})
+		in.Write(append(line, '\n'))
+	}
+
+	var out bytes.Buffer
+	if err := ServeBot(&in, &out, &mediumStrategy{}); err != nil {
+		t.Fatalf("ServeBot() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d replies, want 2: %q", len(lines), out.String())
+	}
+
+	var deploy, attack BotReply
+	json.Unmarshal([]byte(lines[0]), &deploy)
+	json.Unmarshal([]byte(lines[1]), &attack)
+	if deploy.Seq != 1 || deploy.TerritoryID != "a2" {
+		t.Errorf("deploy reply = %+v, want seq 1 on a2", deploy)
+	}
+	if attack.Seq != 2 || attack.From != "a3" || attack.To != "e1" || attack.Armies != 3 {
+		t.Errorf("attack reply = %+v, want seq 2 from a3 on e1 with 3 armies", attack)
+	}
+}
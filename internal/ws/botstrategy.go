@@ -0,0 +1,415 @@
+package ws
+
+import (
+	"math/rand/v2"
+	"slices"
+
+	"es2.uff/war-server/internal/domain/card"
+	"es2.uff/war-server/internal/domain/room"
+)
+
+// BotView is a copy of the state a bot decides on, changing it has no
+// effect on the game
+type BotView struct {
+	BotID       string         `json:"bot_id"`
+	ObjectiveID int            `json:"objective_id"`
+	Armies      int            `json:"armies"` // Left to deploy
+	Cards       []card.Card    `json:"cards"`
+	TradesCount int            `json:"trades_count"`
+	Territories []*Territory   `json:"territories"`
+	TurnOrder   []string       `json:"turn_order"`
+	HandSizes   map[string]int `json:"hand_sizes"` // Cards held by each player
+	byID        map[string]*Territory
+}
+
+// newBotView copies the state seen by botID, the caller holds the read lock
+func newBotView(gs *GameState, botID string) *BotView {
+	v := &BotView{
+		BotID:       botID,
+		TradesCount: gs.TradesCount,
+		Territories: copyTerritories(gs.Territories),
+		TurnOrder:   slices.Clone(gs.turnOrderLocked()),
+		HandSizes:   make(map[string]int, len(gs.Players)),
+	}
+
+	for id, p := range gs.Players {
+		v.HandSizes[id] = len(p.CardsInHand)
+	}
+
+	if bot := gs.Players[botID]; bot != nil {
+		v.ObjectiveID = bot.ObjectiveID
+		v.Armies = bot.Armies
+		for _, c := range bot.CardsInHand {
+			v.Cards = append(v.Cards, *c)
+		}
+	}
+	v.index()
+
+	return v
+}
+
+func (v *BotView) Territory(id string) *Territory {
+	if v.byID == nil {
+		v.index()
+	}
+	return v.byID[id]
+}
+
+// index maps the territories by ID, views decoded from JSON start without
+func (v *BotView) index() {
+	v.byID = make(map[string]*Territory, len(v.Territories))
+	for _, t := range v.Territories {
+		v.byID[t.ID] = t
+	}
+}
+
+// Owned returns the bot's territories in board order
+func (v *BotView) Owned() []*Territory {
+	owned := []*Territory{}
+	for _, t := range v.Territories {
+		if t.Owner == v.BotID {
+			owned = append(owned, t)
+		}
+	}
+	return owned
+}
+
+// EnemyNeighbors returns the territories next to t held by someone else
+func (v *BotView) EnemyNeighbors(t *Territory) []*Territory {
+	enemies := []*Territory{}
+	for _, id := range t.Adjacent {
+		if adj := v.Territory(id); adj != nil && adj.Owner != t.Owner {
+			enemies = append(enemies, adj)
+		}
+	}
+	return enemies
+}
+
+// AttackOptions lists every attack the bot can make from its territories
+func (v *BotView) AttackOptions() []BotAttack {
+	options := []BotAttack{}
+	for _, from := range v.Owned() {
+		if from.Armies <= 1 {
+			continue
+		}
+		for _, to := range v.EnemyNeighbors(from) {
+			options = append(options, BotAttack{From: from.ID, To: to.ID, Armies: min(from.Armies-1, 3)})
+		}
+	}
+	return options
+}
+
+// MustTrade reports whether the bot's hand is full
+func (v *BotView) MustTrade() bool {
+	return len(v.Cards) >= card.MaxHand
+}
+
+// TradeBonus returns the armies the next trade gives
+func (v *BotView) TradeBonus() int {
+	return tradeBonus(v.TradesCount)
+}
+
+// findSet returns the names of the first set of cards the bot can trade
+func findSet(v *BotView) ([3]string, bool) {
+	set, ok := card.FindSet(v.Cards)
+	if !ok {
+		return [3]string{}, false
+	}
+	return [3]string{set[0].TerritoryName, set[1].TerritoryName, set[2].TerritoryName}, true
+}
+
+type BotAttack struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Armies int    `json:"armies"`
+}
+
+type BotMove struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Armies int    `json:"armies"`
+}
+
+// BotStrategy plays a bot's turn one decision at a time, each call gets a
+// fresh view of the game. A strategy is created for every turn, so it may
+// keep state between the calls of one turn.
+type BotStrategy interface {
+	// Trade returns the names of three cards to trade, or false to keep them
+	Trade(v *BotView) ([3]string, bool)
+	// Deploy returns the territory to place one army on
+	Deploy(v *BotView) string
+	// Attack returns the next attack, or false to end the attack phase
+	Attack(v *BotView) (BotAttack, bool)
+	// Occupy returns how many more armies follow a won attack into the
+	// conquered territory
+	Occupy(v *BotView, attack BotAttack) int
+	// Fortify returns the next move, or false to end the turn
+	Fortify(v *BotView) (BotMove, bool)
+}
+
+// NewBotStrategy returns the strategy of a difficulty, unknown ones play easy.
+// External bots the server does not know play hard.
+func NewBotStrategy(difficulty room.BotDifficulty, rng *rand.Rand) BotStrategy {
+	if name, ok := difficulty.External(); ok {
+		if transport := externalBot(name); transport != nil {
+			return &externalStrategy{transport: transport, fallback: &objectiveStrategy{}, timeout: ExternalBotTimeout}
+		}
+		return &objectiveStrategy{}
+	}
+
+	switch difficulty {
+	case room.BotMedium:
+		return &mediumStrategy{}
+	case room.BotHard:
+		return &objectiveStrategy{}
+	case room.BotExpert:
+		return NewMCTSStrategy(DefaultMCTSBudget, rng)
+	default:
+		return &easyStrategy{rng: rng}
+	}
+}
+
+// easyStrategy only trades when its hand is full, deploys at random, makes
+// one to three random attacks and a single move of half the armies of a
+// territory
+type easyStrategy struct {
+	rng         *rand.Rand
+	attacksLeft int
+	started     bool
+	moved       bool
+}
+
+func (s *easyStrategy) Trade(v *BotView) ([3]string, bool) {
+	if !v.MustTrade() {
+		return [3]string{}, false
+	}
+	return findSet(v)
+}
+
+func (s *easyStrategy) Deploy(v *BotView) string {
+	owned := v.Owned()
+	if len(owned) == 0 {
+		return ""
+	}
+	return owned[s.rng.IntN(len(owned))].ID
+}
+
+func (s *easyStrategy) Attack(v *BotView) (BotAttack, bool) {
+	if !s.started {
+		s.started = true
+		s.attacksLeft = s.rng.IntN(3) + 1
+	}
+
+	options := v.AttackOptions()
+	if s.attacksLeft == 0 || len(options) == 0 {
+		return BotAttack{}, false
+	}
+	s.attacksLeft--
+
+	attack := options[s.rng.IntN(len(options))]
+	attack.Armies = s.rng.IntN(attack.Armies) + 1
+	return attack, true
+}
+
+func (s *easyStrategy) Occupy(v *BotView, attack BotAttack) int {
+	return 0
+}
+
+func (s *easyStrategy) Fortify(v *BotView) (BotMove, bool) {
+	if s.moved {
+		return BotMove{}, false
+	}
+	s.moved = true
+
+	for _, from := range v.Owned() {
+		for _, id := range from.Adjacent {
+			to := v.Territory(id)
+			if to == nil || to.Owner != v.BotID {
+				continue
+			}
+			if armies := (from.Armies - 1) / 2; armies >= 1 {
+				return BotMove{From: from.ID, To: to.ID, Armies: armies}, true
+			}
+		}
+	}
+	return BotMove{}, false
+}
+
+// mediumStrategy trades as soon as it can, reinforces its most threatened
+// border, only attacks with more armies than the defender and pulls idle
+// armies to a border once
+type mediumStrategy struct {
+	moved bool
+}
+
+func (s *mediumStrategy) Trade(v *BotView) ([3]string, bool) {
+	return findSet(v)
+}
+
+// Deploy picks the border territory facing the most enemy armies compared
+// to its own
+func (s *mediumStrategy) Deploy(v *BotView) string {
+	best, bestThreat := "", 0
+	for _, t := range v.Owned() {
+		threat := 0
+		for _, enemy := range v.EnemyNeighbors(t) {
+			threat += enemy.Armies
+		}
+		threat -= t.Armies
+		if best == "" || threat > bestThreat {
+			best, bestThreat = t.ID, threat
+		}
+	}
+	return best
+}
+
+func (s *mediumStrategy) Attack(v *BotView) (BotAttack, bool) {
+	var best BotAttack
+	bestMargin := 0
+	for _, option := range v.AttackOptions() {
+		margin := v.Territory(option.From).Armies - v.Territory(option.To).Armies
+		if margin > 1 && margin > bestMargin {
+			best, bestMargin = option, margin
+		}
+	}
+	return best, bestMargin > 0
+}
+
+// Occupy moves half of what is left when the conquered territory still
+// faces enemies
+func (s *mediumStrategy) Occupy(v *BotView, attack BotAttack) int {
+	to := v.Territory(attack.To)
+	if len(v.EnemyNeighbors(to)) == 0 {
+		return 0
+	}
+	return (v.Territory(attack.From).Armies - 1) / 2
+}
+
+func (s *mediumStrategy) Fortify(v *BotView) (BotMove, bool) {
+	if s.moved {
+		return BotMove{}, false
+	}
+	s.moved = true
+	return fortifyBorders(v, false)
+}
+
+// hardStrategy stacks its armies on the single best staging territory,
+// presses every attack with good odds, favoring those that complete a
+// continent, and keeps its interior empty
+type hardStrategy struct{}
+
+// hardTradeBonus is the bonus from which hard bots stop holding on to sets
+const hardTradeBonus = 6
+
+// Trade holds sets while the bonus is low and nothing is at risk, as the
+// bonus grows with the trades of the other players too
+func (s *hardStrategy) Trade(v *BotView) ([3]string, bool) {
+	if !v.MustTrade() && v.TradeBonus() < hardTradeBonus && threatenedBorder(v) == nil {
+		return [3]string{}, false
+	}
+	return findSet(v)
+}
+
+// Deploy reinforces the territory with the best attack, or the most
+// threatened border when there is none
+func (s *hardStrategy) Deploy(v *BotView) string {
+	best, bestScore := "", 0
+	for _, t := range v.Owned() {
+		for _, enemy := range v.EnemyNeighbors(t) {
+			score := attackScore(v, t, enemy)
+			if best == "" || score > bestScore {
+				best, bestScore = t.ID, score
+			}
+		}
+	}
+	if best == "" {
+		return (&mediumStrategy{}).Deploy(v)
+	}
+	return best
+}
+
+func (s *hardStrategy) Attack(v *BotView) (BotAttack, bool) {
+	var best BotAttack
+	bestScore := 0
+	found := false
+	for _, option := range v.AttackOptions() {
+		from, to := v.Territory(option.From), v.Territory(option.To)
+		if !goodOdds(from, to) {
+			continue
+		}
+		if score := attackScore(v, from, to); !found || score > bestScore {
+			best, bestScore, found = option, score, true
+		}
+	}
+	return best, found
+}
+
+// Occupy moves everything but one army forward when the territory attacked
+// from no longer touches an enemy
+func (s *hardStrategy) Occupy(v *BotView, attack BotAttack) int {
+	from := v.Territory(attack.From)
+	if len(v.EnemyNeighbors(from)) == 0 {
+		return from.Armies - 1
+	}
+	if len(v.EnemyNeighbors(v.Territory(attack.To))) == 0 {
+		return 0
+	}
+	return (from.Armies - 1) / 2
+}
+
+func (s *hardStrategy) Fortify(v *BotView) (BotMove, bool) {
+	return fortifyBorders(v, true)
+}
+
+// goodOdds reports whether attacking from from on to is likely to pay off,
+// three dice win more often than not unless outnumbered
+func goodOdds(from, to *Territory) bool {
+	return from.Armies > to.Armies+1 || (from.Armies >= 4 && from.Armies > to.Armies)
+}
+
+// attackScore rates an attack from from on to, higher is better. Beyond the
+// army margin it rewards taking the last territories of a continent.
+func attackScore(v *BotView, from, to *Territory) int {
+	score := from.Armies - to.Armies
+
+	missing := 0
+	for _, t := range v.Territories {
+		if t.Region == to.Region && t.Owner != v.BotID {
+			missing++
+		}
+	}
+	if missing <= 2 {
+		score += 3 - missing
+	}
+	return score
+}
+
+// fortifyBorders moves the armies of the largest interior territory to an
+// adjacent border, all but one of them or half of them
+func fortifyBorders(v *BotView, all bool) (BotMove, bool) {
+	owned := v.Owned()
+	slices.SortStableFunc(owned, func(a, b *Territory) int { return b.Armies - a.Armies })
+
+	for _, from := range owned {
+		if from.Armies <= 1 || len(v.EnemyNeighbors(from)) > 0 {
+			continue
+		}
+
+		for _, id := range from.Adjacent {
+			to := v.Territory(id)
+			if to == nil || to.Owner != v.BotID || len(v.EnemyNeighbors(to)) == 0 {
+				continue
+			}
+
+			armies := (from.Armies - 1) / 2
+			if all {
+				armies = from.Armies - 1
+			}
+			if armies < 1 {
+				continue
+			}
+			return BotMove{From: from.ID, To: to.ID, Armies: armies}, true
+		}
+	}
+	return BotMove{}, false
+}
@@ -0,0 +1,155 @@
+package ws
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"es2.uff/war-server/internal/domain/card"
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/domain/territory"
+)
+
+// newTestBotView builds a view of a line of territories, a1-a2-a3 held by
+// the bot and e1 by an enemy next to a3
+func newTestBotView() *BotView {
+	gs := NewGameState("room-1")
+	gs.Players["bot"] = &Player{ID: "bot", IsBot: true, Armies: 3}
+	gs.Territories = []*Territory{
+		{ID: "a1", Owner: "bot", Armies: 6, Adjacent: []string{"a2"}},
+		{ID: "a2", Owner: "bot", Armies: 1, Adjacent: []string{"a1", "a3"}},
+		{ID: "a3", Owner: "bot", Armies: 2, Adjacent: []string{"a2", "e1"}, Region: 1},
+		{ID: "e1", Owner: "enemy", Armies: 5, Adjacent: []string{"a3"}, Region: 1},
+	}
+	return newBotView(gs, "bot")
+}
+
+func TestNewBotStrategy(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	if _, ok := NewBotStrategy(room.BotEasy, rng).(*easyStrategy); !ok {
+		t.Error("easy difficulty did not get the easy strategy")
+	}
+	if _, ok := NewBotStrategy(room.BotMedium, rng).(*mediumStrategy); !ok {
+		t.Error("medium difficulty did not get the medium strategy")
+	}
+	if _, ok := NewBotStrategy(room.BotHard, rng).(*objectiveStrategy); !ok {
+		t.Error("hard difficulty did not get the objective strategy")
+	}
+	if _, ok := NewBotStrategy(room.BotExpert, rng).(*mctsStrategy); !ok {
+		t.Error("expert difficulty did not get the search strategy")
+	}
+	if _, ok := NewBotStrategy("", rng).(*easyStrategy); !ok {
+		t.Error("unknown difficulty did not fall back to easy")
+	}
+}
+
+func TestBotView_IsACopy(t *testing.T) {
+	gs := NewGameState("room-1")
+	gs.Territories = []*Territory{{ID: "a1", Owner: "bot", Armies: 2, Adjacent: []string{"a2"}}}
+	v := newBotView(gs, "bot")
+
+	v.Territory("a1").Armies = 10
+	v.Territory("a1").Adjacent[0] = "x"
+	if gs.Territories[0].Armies != 2 || gs.Territories[0].Adjacent[0] != "a2" {
+		t.Errorf("changing the view changed the game: %+v", gs.Territories[0])
+	}
+}
+
+func TestMediumStrategy(t *testing.T) {
+	v := newTestBotView()
+	s := &mediumStrategy{}
+
+	if got := s.Deploy(v); got != "a3" {
+		t.Errorf("Deploy() = %s, want the threatened border a3", got)
+	}
+	if attack, ok := s.Attack(v); ok {
+		t.Errorf("Attack() = %+v, want no attack with 2 armies against 5", attack)
+	}
+
+	v.Territory("a3").Armies = 8
+	if attack, ok := s.Attack(v); !ok || attack.From != "a3" || attack.To != "e1" || attack.Armies != 3 {
+		t.Errorf("Attack() = %+v, %v, want a3 on e1 with 3 armies", attack, ok)
+	}
+}
+
+func TestHardStrategy_FortifiesBorders(t *testing.T) {
+	v := newTestBotView()
+	s := &hardStrategy{}
+
+	// a1 has nothing to defend, a2 is its only neighbor but not a border
+	if move, ok := s.Fortify(v); ok {
+		t.Errorf("Fortify() = %+v, want no move without an adjacent border", move)
+	}
+
+	v.Territory("a2").Armies = 4
+	if move, ok := s.Fortify(v); !ok || move.From != "a2" || move.To != "a3" || move.Armies != 3 {
+		t.Errorf("Fortify() = %+v, %v, want all but one army of a2 moved to a3", move, ok)
+	}
+}
+
+func TestEasyStrategy_AttacksAtMostThreeTimes(t *testing.T) {
+	v := newTestBotView()
+	v.Territory("a3").Armies = 20
+	s := NewBotStrategy(room.BotEasy, rand.New(rand.NewPCG(1, 2)))
+
+	attacks := 0
+	for range 10 {
+		attack, ok := s.Attack(v)
+		if !ok {
+			break
+		}
+		if attack.From != "a3" || attack.To != "e1" || attack.Armies < 1 || attack.Armies > 3 {
+			t.Fatalf("Attack() = %+v, want a3 on e1 with 1 to 3 armies", attack)
+		}
+		attacks++
+	}
+	if attacks < 1 || attacks > 3 {
+		t.Errorf("made %d attacks, want 1 to 3", attacks)
+	}
+}
+
+func TestBotStrategies_Trade(t *testing.T) {
+	set := []card.Card{
+		{TerritoryName: "Brasil", Shape: territory.Square},
+		{TerritoryName: "Chile", Shape: territory.Square},
+		{TerritoryName: "Egito", Shape: territory.Square},
+	}
+	fullHand := append(slices.Clone(set),
+		card.Card{TerritoryName: "Sudão", Shape: territory.Circle},
+		card.Card{TerritoryName: "Congo", Shape: territory.Triangle},
+	)
+	noSet := []card.Card{set[0], set[1], fullHand[3], fullHand[4]}
+
+	tests := []struct {
+		name        string
+		difficulty  room.BotDifficulty
+		cards       []card.Card
+		tradesCount int
+		want        bool
+	}{
+		{"Easy keeps a set", room.BotEasy, set, 2, false},
+		{"Easy trades a full hand", room.BotEasy, fullHand, 2, true},
+		{"Medium trades any set", room.BotMedium, set, 2, true},
+		{"Hard holds a small bonus", room.BotHard, set, 2, false},
+		{"Hard trades a large bonus", room.BotHard, set, 3, true},
+		{"Hard trades a full hand", room.BotHard, fullHand, 2, true},
+		{"No set to trade", room.BotMedium, noSet, 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestBotView()
+			v.Cards = tt.cards
+			v.TradesCount = tt.tradesCount
+
+			s := NewBotStrategy(tt.difficulty, rand.New(rand.NewPCG(1, 2)))
+			cards, ok := s.Trade(v)
+			if ok != tt.want {
+				t.Fatalf("Trade() = %v, %v, want trade %v", cards, ok, tt.want)
+			}
+			if ok && cards != [3]string{"Brasil", "Chile", "Egito"} {
+				t.Errorf("Trade() = %v, want the three squares", cards)
+			}
+		})
+	}
+}
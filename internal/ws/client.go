@@ -1,13 +1,13 @@
 package ws
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
 
 	"es2.uff/war-server/internal/domain/player"
-	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -15,6 +15,7 @@ type Client struct {
 	id       string
 	username string
 	ready    bool
+	filter   LobbyFilter // Only used by lobby clients
 	hub      HubInterface
 	conn     *websocket.Conn
 	send     chan []byte
@@ -35,40 +36,48 @@ const (
 	maxMessageSize = 512
 )
 
-func ServeWs(hub HubInterface, w http.ResponseWriter, r *http.Request, userId string) error {
-	conn, err := upgrader.Upgrade(w, r, nil)
-
+// ServeWs upgrades the request to a websocket connection of player p and
+// registers it with the hub
+func ServeWs(hub HubInterface, w http.ResponseWriter, r *http.Request, p *player.Player) error {
+	client, err := newClient(hub, w, r, p)
 	if err != nil {
-		log.Println(err)
 		return err
 	}
 
-	playerID, err := uuid.Parse(userId)
+	return client.start()
+}
+
+// newClient upgrades the connection and builds a client for the given player,
+// the client is not registered with its hub yet
+func newClient(hub HubInterface, w http.ResponseWriter, r *http.Request, p *player.Player) (*Client, error) {
+	conn, err := upgrader.Upgrade(w, r, nil)
 
 	if err != nil {
 		log.Println(err)
-		return err
-	}
-
-	p := player.GetPlayer(playerID)
-
-	if p == nil {
-		return fmt.Errorf("Player not found.")
+		return nil, err
 	}
 
-	client := &Client{
+	return &Client{
 		id:       p.ID.String(),
 		username: p.Name,
 		ready:    false,
 		hub:      hub,
 		conn:     conn,
 		send:     make(chan []byte, 256),
-	}
+	}, nil
+}
 
-	client.hub.GetRegisterChan() <- client
+// start registers the client with its hub and starts its pumps
+func (c *Client) start() error {
+	select {
+	case c.hub.GetRegisterChan() <- c:
+	case <-c.hub.Done():
+		_ = c.conn.Close()
+		return fmt.Errorf("hub is closed")
+	}
 
-	go client.writePump()
-	go client.readPump()
+	go c.writePump()
+	go c.readPump()
 	return nil
 }
 
@@ -133,9 +142,29 @@ func (c *Client) writePump() {
 	}
 }
 
+// attribute sets the message's player_id to the client's player, so nobody
+// can act for someone else, and drops bot_seq, which only bots may acknowledge
+func (c *Client) attribute(message []byte) []byte {
+	var msg map[string]any
+	if err := json.Unmarshal(message, &msg); err != nil || msg == nil {
+		return message
+	}
+
+	msg["player_id"] = c.id
+	delete(msg, "bot_seq")
+	attributed, err := json.Marshal(msg)
+	if err != nil {
+		return message
+	}
+	return attributed
+}
+
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.GetUnregisterChan() <- c
+		select {
+		case c.hub.GetUnregisterChan() <- c:
+		case <-c.hub.Done():
+		}
 		if err := c.conn.Close(); err != nil {
 			log.Printf("Error closing connection in readPump: %v", err)
 		}
@@ -166,6 +195,10 @@ func (c *Client) readPump() {
 
 		log.Printf("Received message from client %s: %s\n", c.id, string(message))
 
-		c.hub.GetBroadcastChan() <- message
+		select {
+		case c.hub.GetBroadcastChan() <- c.attribute(message):
+		case <-c.hub.Done():
+			return
+		}
 	}
 }
@@ -0,0 +1,44 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestClient_Attribute(t *testing.T) {
+	c := &Client{id: "alice"}
+
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"own id", `{"type":"finish_turn","player_id":"alice"}`, "alice"},
+		{"someone else", `{"type":"finish_turn","player_id":"bob"}`, "alice"},
+		{"no id", `{"type":"finish_turn"}`, "alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg map[string]any
+			if err := json.Unmarshal(c.attribute([]byte(tt.message)), &msg); err != nil {
+				t.Fatalf("attribute() returned invalid JSON: %v", err)
+			}
+			if msg["player_id"] != tt.want || msg["type"] != "finish_turn" {
+				t.Errorf("attribute() = %v, want the message from %s", msg, tt.want)
+			}
+		})
+	}
+
+	var msg map[string]any
+	if err := json.Unmarshal(c.attribute([]byte(`{"type":"finish_turn","bot_seq":3}`)), &msg); err != nil {
+		t.Fatalf("attribute() returned invalid JSON: %v", err)
+	}
+	if _, ok := msg["bot_seq"]; ok {
+		t.Errorf("attribute() = %v, want bot_seq dropped", msg)
+	}
+
+	if got := string(c.attribute([]byte("not json"))); got != "not json" {
+		t.Errorf("attribute() of invalid JSON = %q, want it unchanged", got)
+	}
+}
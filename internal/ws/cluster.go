@@ -0,0 +1,43 @@
+package ws
+
+import (
+	"es2.uff/war-server/internal/bus"
+	"github.com/google/uuid"
+)
+
+// Cluster links the hubs of this instance with the other replicas sharing
+// the same bus. Each instance needs its own Node.
+type Cluster struct {
+	Node   string
+	Bus    bus.Bus
+	Leases bus.Leases
+}
+
+// NewLocalCluster is used when a single instance serves every client
+func NewLocalCluster() Cluster {
+	return Cluster{
+		Node:   uuid.NewString(),
+		Bus:    bus.NewMemoryBus(),
+		Leases: bus.NewMemoryLeases(),
+	}
+}
+
+const lobbyTopic = "lobby"
+
+func roomTopic(roomID string) string {
+	return "room:" + roomID
+}
+
+// Actions sent by players of a game, applied by the instance owning it
+func gameInboxTopic(gameID string) string {
+	return "game:" + gameID + ":in"
+}
+
+// Updates of a game, delivered by every instance to its own clients
+func gameOutboxTopic(gameID string) string {
+	return "game:" + gameID + ":out"
+}
+
+func gameLeaseKey(gameID string) string {
+	return "game:" + gameID
+}
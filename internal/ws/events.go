@@ -0,0 +1,207 @@
+package ws
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"es2.uff/war-server/internal/domain/card"
+	"es2.uff/war-server/internal/domain/room"
+)
+
+type GameEventType string
+
+const (
+	EventGameStarted GameEventType = "game_started"
+	EventDeployed    GameEventType = "deployed"
+	EventAttacked    GameEventType = "attacked"
+	EventMoved       GameEventType = "moved"
+	EventTraded      GameEventType = "traded"
+	EventTurnEnded   GameEventType = "turn_ended"
+	EventPaused      GameEventType = "paused"
+	EventResumed     GameEventType = "resumed"
+)
+
+// GameEvent is an accepted action of a game. Random outcomes are recorded
+// with it, the setup of the board and deck in game_started and the dice in
+// attacked, so folding the events rebuilds the same state.
+type GameEvent struct {
+	Seq          int           `json:"seq"`
+	Type         GameEventType `json:"type"`
+	PlayerID     string        `json:"player_id,omitempty"`
+	Timestamp    time.Time     `json:"timestamp"`
+	Setup        *GameSetup    `json:"setup,omitempty"`
+	TerritoryID  string        `json:"territory_id,omitempty"`
+	From         string        `json:"from,omitempty"`
+	To           string        `json:"to,omitempty"`
+	Armies       int           `json:"armies,omitempty"`
+	AttackerDice []int         `json:"attacker_dice,omitempty"`
+	DefenderDice []int         `json:"defender_dice,omitempty"`
+	Cards        []string      `json:"cards,omitempty"`
+}
+
+// GameSetup is the state a game starts from, once seats, territories,
+// objectives and the deck order have been drawn
+type GameSetup struct {
+	Settings    room.RoomSettings  `json:"settings"`
+	Players     map[string]*Player `json:"players"`
+	TurnOrder   []string           `json:"turn_order"`
+	Territories []*Territory       `json:"territories"`
+	Deck        []card.Card        `json:"deck"`
+	TradesCount int                `json:"trades_count"`
+	CurrentTurn string             `json:"current_turn"`
+	TurnNumber  int                `json:"turn_number"`
+}
+
+func (gs *GameState) recordLocked(e GameEvent) {
+	if gs.simulation {
+		return
+	}
+	e.Seq = len(gs.Events) + 1
+	e.Timestamp = time.Now()
+	gs.appendLocked(e)
+}
+
+// appendLocked adds an applied event, dating the start and end of the game
+// by it. No action is accepted after a win, so only the winning event can
+// find a winner set.
+func (gs *GameState) appendLocked(e GameEvent) {
+	switch {
+	case e.Type == EventGameStarted:
+		gs.StartedAt = e.Timestamp
+	case gs.WinnerID != "":
+		finishedAt := e.Timestamp
+		gs.FinishedAt = &finishedAt
+	}
+	gs.Events = append(gs.Events, e)
+}
+
+func (gs *GameState) setupLocked() *GameSetup {
+	setup := &GameSetup{
+		Settings:    gs.Settings,
+		Players:     make(map[string]*Player, len(gs.Players)),
+		TurnOrder:   slices.Clone(gs.TurnOrder),
+		Territories: copyTerritories(gs.Territories),
+		TradesCount: gs.TradesCount,
+		CurrentTurn: gs.CurrentTurn,
+		TurnNumber:  gs.TurnNumber,
+	}
+
+	for id, p := range gs.Players {
+		setup.Players[id] = copyPlayer(p)
+	}
+	if gs.Deck != nil {
+		setup.Deck = slices.Clone(gs.Deck.Cards)
+	}
+
+	return setup
+}
+
+func copyPlayer(p *Player) *Player {
+	c := *p
+	c.CardsInHand = make([]*card.Card, 0, len(p.CardsInHand))
+	for _, held := range p.CardsInHand {
+		held := *held
+		c.CardsInHand = append(c.CardsInHand, &held)
+	}
+	return &c
+}
+
+func copyTerritories(territories []*Territory) []*Territory {
+	copies := make([]*Territory, 0, len(territories))
+	for _, t := range territories {
+		c := *t
+		c.Adjacent = slices.Clone(t.Adjacent)
+		copies = append(copies, &c)
+	}
+	return copies
+}
+
+// ApplyEvent folds one event into the state. Events must come in order and
+// start with game_started.
+func (gs *GameState) ApplyEvent(e GameEvent) error {
+	gs.Lock()
+	defer gs.Unlock()
+
+	if e.Seq != len(gs.Events)+1 {
+		return fmt.Errorf("event %d out of order, expected %d", e.Seq, len(gs.Events)+1)
+	}
+	if (e.Type == EventGameStarted) != (e.Seq == 1) {
+		return fmt.Errorf("game must start with its first event")
+	}
+
+	var err error
+	switch e.Type {
+	case EventGameStarted:
+		if e.Setup == nil {
+			return fmt.Errorf("game_started event has no setup")
+		}
+		gs.startFromLocked(e.Setup)
+	case EventDeployed:
+		if gs.WinnerID != "" || gs.Paused || !gs.deployLocked(e.PlayerID, e.TerritoryID) {
+			err = fmt.Errorf("deploy could not be applied")
+		}
+	case EventAttacked:
+		if len(e.AttackerDice) == 0 || len(e.DefenderDice) == 0 {
+			return fmt.Errorf("attacked event has no dice")
+		}
+		_, _, err = gs.attackLocked(e.PlayerID, e.From, e.To, e.Armies, e.AttackerDice, e.DefenderDice)
+	case EventMoved:
+		err = gs.moveLocked(e.PlayerID, e.From, e.To, e.Armies)
+	case EventTraded:
+		if len(e.Cards) != 3 {
+			return fmt.Errorf("traded event must have 3 cards")
+		}
+		_, err = gs.tradeLocked(e.PlayerID, e.Cards[0], e.Cards[1], e.Cards[2])
+	case EventTurnEnded:
+		_, err = gs.nextTurnLocked(e.PlayerID)
+	case EventPaused:
+		if gs.Paused {
+			err = fmt.Errorf("game is already paused")
+		}
+		gs.Paused = true
+	case EventResumed:
+		if !gs.Paused {
+			err = fmt.Errorf("game is not paused")
+		}
+		gs.Paused = false
+	default:
+		return fmt.Errorf("unknown event type %q", e.Type)
+	}
+
+	if err != nil {
+		return fmt.Errorf("event %d (%s): %w", e.Seq, e.Type, err)
+	}
+
+	gs.appendLocked(e)
+	return nil
+}
+
+func (gs *GameState) startFromLocked(setup *GameSetup) {
+	gs.Settings = setup.Settings
+	gs.Players = make(map[string]*Player, len(setup.Players))
+	for id, p := range setup.Players {
+		gs.Players[id] = copyPlayer(p)
+	}
+	gs.TurnOrder = slices.Clone(setup.TurnOrder)
+	gs.Territories = copyTerritories(setup.Territories)
+	gs.Deck = &card.Deck{Cards: slices.Clone(setup.Deck)}
+	gs.TradesCount = setup.TradesCount
+	gs.CurrentTurn = setup.CurrentTurn
+	gs.TurnNumber = setup.TurnNumber
+	gs.Stats = make(map[string]*PlayerStats)
+	gs.FinishedAt = nil
+	gs.WinnerID = ""
+	gs.TurnDeadline = nil
+}
+
+// ReplayEvents rebuilds the state of a game by folding its events
+func ReplayEvents(roomID string, events []GameEvent) (*GameState, error) {
+	gs := NewGameState(roomID)
+	for _, e := range events {
+		if err := gs.ApplyEvent(e); err != nil {
+			return nil, err
+		}
+	}
+	return gs, nil
+}
@@ -0,0 +1,156 @@
+package ws
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+
+	"es2.uff/war-server/internal/domain/room"
+	"github.com/google/uuid"
+)
+
+// playTestGame starts a game of three players and plays a few rounds of
+// deploys, attacks and moves on it
+func playTestGame(t *testing.T) *GameState {
+	t.Helper()
+
+	state := NewGameState("room-1")
+	state.Settings = room.DefaultSettings()
+	for range 3 {
+		id := uuid.NewString()
+		state.Players[id] = &Player{ID: id, Username: "Player"}
+	}
+	state.StartGame()
+
+	for range 9 {
+		playerID := state.CurrentTurn
+		if state.WinnerID != "" {
+			break
+		}
+
+		for _, t := range state.Territories {
+			if t.Owner == playerID {
+				for state.Players[playerID].Armies > 0 && state.WinnerID == "" {
+					state.Deploy(playerID, t.ID)
+				}
+				break
+			}
+		}
+
+		byID := make(map[string]*Territory)
+		for _, t := range state.Territories {
+			byID[t.ID] = t
+		}
+		for _, from := range state.Territories {
+			for _, adjacentID := range from.Adjacent {
+				to := byID[adjacentID]
+				if from.Owner != playerID || from.Armies < 2 {
+					break
+				}
+				if to.Owner == playerID {
+					state.Move(playerID, from.ID, to.ID, 1)
+				} else {
+					state.Attack(playerID, from.ID, to.ID, min(from.Armies-1, 3))
+				}
+			}
+		}
+
+		if state.WinnerID != "" {
+			break
+		}
+		if _, err := state.NextTurn(playerID); err != nil {
+			t.Fatalf("NextTurn() error = %v", err)
+		}
+	}
+
+	return state
+}
+
+func TestReplayEvents_RebuildsState(t *testing.T) {
+	state := playTestGame(t)
+
+	// Events are replayed from storage, so they go through JSON first
+	data, err := json.Marshal(state.Events)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var events []GameEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if !slices.ContainsFunc(events, func(e GameEvent) bool { return e.Type == EventAttacked }) {
+		t.Fatal("the test game has no attacks to replay")
+	}
+
+	got, err := ReplayEvents(state.RoomID, events)
+	if err != nil {
+		t.Fatalf("ReplayEvents() error = %v", err)
+	}
+
+	if got.CurrentTurn != state.CurrentTurn || got.TurnNumber != state.TurnNumber {
+		t.Errorf("replayed turn = %s/%d, want %s/%d", got.CurrentTurn, got.TurnNumber, state.CurrentTurn, state.TurnNumber)
+	}
+	if !got.StartedAt.Equal(state.StartedAt) {
+		t.Errorf("replayed StartedAt = %v, want %v", got.StartedAt, state.StartedAt)
+	}
+	for i, want := range state.Territories {
+		territory := got.Territories[i]
+		if territory.ID != want.ID || territory.Owner != want.Owner || territory.Armies != want.Armies {
+			t.Errorf("replayed territory %s = %+v, want %+v", want.Name, territory, want)
+		}
+	}
+	for id, want := range state.Players {
+		p := got.Players[id]
+		if p.Armies != want.Armies || p.ObjectiveID != want.ObjectiveID || len(p.CardsInHand) != len(want.CardsInHand) {
+			t.Errorf("replayed player %s = %+v, want %+v", id, p, want)
+		}
+	}
+	if !slices.Equal(got.Deck.Cards, state.Deck.Cards) || got.TradesCount != state.TradesCount {
+		t.Error("replayed deck differs from the played one")
+	}
+	if len(got.Events) != len(state.Events) {
+		t.Errorf("replayed %d events, want %d", len(got.Events), len(state.Events))
+	}
+	if !reflect.DeepEqual(got.Stats, state.Stats) {
+		t.Errorf("replayed stats = %+v, want %+v", got.Stats, state.Stats)
+	}
+}
+
+func TestGameState_ApplyEvent_RejectsInvalidEvents(t *testing.T) {
+	state := playTestGame(t)
+	events := state.Events
+
+	tests := []struct {
+		name   string
+		events []GameEvent
+	}{
+		{"not started", events[1:2]},
+		{"out of order", []GameEvent{events[0], events[2]}},
+		{"started twice", []GameEvent{events[0], {Seq: 2, Type: EventGameStarted, Setup: events[0].Setup}}},
+		{"unknown type", []GameEvent{events[0], {Seq: 2, Type: "surrendered"}}},
+		{"attack without dice", []GameEvent{events[0], {Seq: 2, Type: EventAttacked}}},
+		{"illegal action", []GameEvent{events[0], {Seq: 2, Type: EventTurnEnded, PlayerID: "someone"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReplayEvents(state.RoomID, tt.events); err == nil {
+				t.Error("ReplayEvents() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestGameState_Deploy_NoOpRecordsNoEvent(t *testing.T) {
+	state := playTestGame(t)
+	recorded := len(state.Events)
+
+	if err := state.Deploy(state.CurrentTurn, "missing"); err != nil {
+		t.Fatalf("Deploy() error = %v", err)
+	}
+	if len(state.Events) != recorded {
+		t.Errorf("a deploy that placed nothing recorded an event")
+	}
+}
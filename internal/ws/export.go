@@ -0,0 +1,213 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"es2.uff/war-server/internal/repository"
+	"github.com/google/uuid"
+)
+
+// gameExportVersion is bumped whenever the layout of GameExport changes, so
+// old documents are refused instead of loaded wrong
+const gameExportVersion = 1
+
+var ErrInvalidExport = errors.New("invalid game export")
+
+// ErrExportForbidden is returned when a running game would be exported by
+// someone other than its owner, or while others still play it: the export
+// reveals its dice, deck, hands and objectives
+var ErrExportForbidden = errors.New("a running game can only be exported by its owner, once paused or against bots alone")
+
+// GameExport is a saved position of a game. Loading it starts a new game
+// from that position, with the dice picking up where they were.
+type GameExport struct {
+	Version    int       `json:"version"`
+	GameID     string    `json:"game_id"`
+	ExportedAt time.Time `json:"exported_at"`
+	GameSetup
+	RNG []byte `json:"rng"`
+}
+
+// Export saves the current position of the game
+func (gs *GameState) Export() (*GameExport, error) {
+	gs.RLock()
+	defer gs.RUnlock()
+
+	rng, err := gs.rng.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &GameExport{
+		Version:    gameExportVersion,
+		GameID:     gs.RoomID,
+		ExportedAt: time.Now(),
+		GameSetup:  *gs.setupLocked(),
+		RNG:        rng,
+	}, nil
+}
+
+// ImportGameState builds the state of a new game from an export. The
+// imported position becomes the game_started event of the new game. Anyone
+// can write an export, so imported games stay out of the match history.
+func ImportGameState(gameID string, export *GameExport) (*GameState, error) {
+	if export.Version != gameExportVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidExport, export.Version)
+	}
+	if err := export.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+
+	gs := NewGameState(gameID)
+	if len(export.RNG) > 0 {
+		if err := gs.rng.UnmarshalBinary(export.RNG); err != nil {
+			return nil, fmt.Errorf("%w: rng: %v", ErrInvalidExport, err)
+		}
+	}
+
+	gs.Lock()
+	defer gs.Unlock()
+
+	gs.startFromLocked(&export.GameSetup)
+	gs.Imported = true
+	for _, playerID := range gs.TurnOrder {
+		gs.checkWinnerLocked(playerID)
+	}
+	if gs.WinnerID != "" {
+		return nil, fmt.Errorf("%w: the position is already won", ErrInvalidExport)
+	}
+
+	gs.recordLocked(GameEvent{Type: EventGameStarted, Setup: gs.setupLocked()})
+	return gs, nil
+}
+
+func (e *GameExport) validate() error {
+	if err := e.Settings.Validate(); err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	if len(e.Players) < 2 || len(e.Players) > e.Settings.MaxPlayers {
+		return fmt.Errorf("needs between 2 and %d players", e.Settings.MaxPlayers)
+	}
+	for id, p := range e.Players {
+		if p == nil || p.ID != id {
+			return fmt.Errorf("player %s does not match its key", id)
+		}
+		if p.Armies < 0 {
+			return fmt.Errorf("player %s has negative armies", id)
+		}
+		if slices.Contains(p.CardsInHand, nil) {
+			return fmt.Errorf("player %s holds an empty card", id)
+		}
+	}
+
+	order := slices.Clone(e.TurnOrder)
+	slices.Sort(order)
+	if len(order) != len(e.Players) || len(slices.Compact(order)) != len(e.Players) {
+		return fmt.Errorf("turn order must seat every player once")
+	}
+	for _, id := range order {
+		if e.Players[id] == nil {
+			return fmt.Errorf("turn order has unknown player %s", id)
+		}
+	}
+
+	if e.Players[e.CurrentTurn] == nil || e.TurnNumber < 1 {
+		return fmt.Errorf("invalid current turn")
+	}
+
+	if len(e.Territories) == 0 {
+		return fmt.Errorf("has no territories")
+	}
+	ids := make(map[string]bool, len(e.Territories))
+	for _, t := range e.Territories {
+		if t == nil || t.ID == "" || ids[t.ID] {
+			return fmt.Errorf("territory IDs must be unique")
+		}
+		ids[t.ID] = true
+	}
+	for _, t := range e.Territories {
+		if e.Players[t.Owner] == nil {
+			return fmt.Errorf("territory %s has unknown owner %s", t.ID, t.Owner)
+		}
+		if t.Armies < 1 {
+			return fmt.Errorf("territory %s has no armies", t.ID)
+		}
+		for _, adjacent := range t.Adjacent {
+			if !ids[adjacent] {
+				return fmt.Errorf("territory %s borders unknown territory %s", t.ID, adjacent)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ExportGame saves the position of a game for playerID, as last stored by
+// the instance running it. Before it ends only the owner may export it, and
+// only while it is paused or no other human plays it.
+func (gm *GameManager) ExportGame(id, playerID string) (*GameExport, error) {
+	if gm.archive == nil {
+		return nil, repository.ErrNotFound
+	}
+
+	snapshot, err := gm.loadSnapshot(id)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := snapshot.restore()
+	if err != nil {
+		return nil, err
+	}
+	if state.WinnerID == "" && !state.exportableBy(playerID) {
+		return nil, ErrExportForbidden
+	}
+	return state.Export()
+}
+
+// exportableBy reports whether playerID may export the running game
+func (gs *GameState) exportableBy(playerID string) bool {
+	if gs.OwnerID == "" || gs.OwnerID != playerID {
+		return false
+	}
+	if gs.Paused {
+		return true
+	}
+	for id, p := range gs.Players {
+		if id != playerID && !p.IsBot {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportGame starts a new game owned by ownerID from an export and returns
+// its ID. Players keep their IDs, so they join the new game as themselves.
+func (gm *GameManager) ImportGame(export *GameExport, ownerID string) (string, error) {
+	if gm.archive == nil {
+		return "", fmt.Errorf("games cannot be stored")
+	}
+
+	id := uuid.NewString()
+	state, err := ImportGameState(id, export)
+	if err != nil {
+		return "", err
+	}
+	state.OwnerID = ownerID
+
+	game := gm.newGame(id, state)
+	game.log = append(game.log, Gamelog{
+		Timestamp: time.Now(),
+		Message:   "Partida carregada de uma posição salva.",
+	})
+	game.persist()
+
+	// The stored snapshot is picked up by whichever instance takes the game
+	if _, err := gm.GetOrCreateGame(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
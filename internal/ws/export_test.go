@@ -0,0 +1,193 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"es2.uff/war-server/internal/repository"
+	"github.com/google/uuid"
+)
+
+func exportTestGame(t *testing.T) (*GameState, *GameExport) {
+	t.Helper()
+
+	state := playTestGame(t)
+	export, err := state.Export()
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	// Exports are handed around as files, so they go through JSON first
+	data, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	decoded := new(GameExport)
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	return state, decoded
+}
+
+func TestGameState_ExportImport(t *testing.T) {
+	state, export := exportTestGame(t)
+
+	got, err := ImportGameState("imported", export)
+	if err != nil {
+		t.Fatalf("ImportGameState() error = %v", err)
+	}
+
+	if got.RoomID != "imported" || got.CurrentTurn != state.CurrentTurn || got.TurnNumber != state.TurnNumber {
+		t.Errorf("imported game %s at %s/%d, want %s/%d",
+			got.RoomID, got.CurrentTurn, got.TurnNumber, state.CurrentTurn, state.TurnNumber)
+	}
+	for i, want := range state.Territories {
+		if territory := got.Territories[i]; territory.Owner != want.Owner || territory.Armies != want.Armies {
+			t.Errorf("imported territory %s = %+v, want %+v", want.Name, territory, want)
+		}
+	}
+	for id, want := range state.Players {
+		if p := got.Players[id]; p.Armies != want.Armies || len(p.CardsInHand) != len(want.CardsInHand) {
+			t.Errorf("imported player %s = %+v, want %+v", id, p, want)
+		}
+	}
+	if !slices.Equal(got.Deck.Cards, state.Deck.Cards) || got.TradesCount != state.TradesCount {
+		t.Error("imported deck differs from the exported one")
+	}
+	if len(got.Events) != 1 || got.Events[0].Type != EventGameStarted {
+		t.Errorf("imported game has events %+v, want it to start from the position", got.Events)
+	}
+
+	// Both games keep rolling the same dice
+	if rand.New(got.rng).Uint64() != rand.New(state.rng).Uint64() {
+		t.Error("imported dice differ from the exported game's")
+	}
+}
+
+func TestImportGameState_RejectsInvalidExports(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(e *GameExport)
+	}{
+		{"unknown version", func(e *GameExport) { e.Version = 99 }},
+		{"unknown owner", func(e *GameExport) { e.Territories[0].Owner = "someone" }},
+		{"empty territory", func(e *GameExport) { e.Territories[0].Armies = 0 }},
+		{"unknown border", func(e *GameExport) { e.Territories[0].Adjacent = []string{"nowhere"} }},
+		{"seat missing", func(e *GameExport) { e.TurnOrder = e.TurnOrder[1:] }},
+		{"seat taken twice", func(e *GameExport) { e.TurnOrder[1] = e.TurnOrder[0] }},
+		{"unknown turn", func(e *GameExport) { e.CurrentTurn = "someone" }},
+		{"broken dice", func(e *GameExport) { e.RNG = []byte("dice") }},
+		{"invalid settings", func(e *GameExport) { e.Settings.MaxPlayers = 0 }},
+		{"negative armies", func(e *GameExport) { e.Players[e.CurrentTurn].Armies = -5 }},
+		{"already won", func(e *GameExport) {
+			for _, territory := range e.Territories {
+				territory.Owner = e.CurrentTurn
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, export := exportTestGame(t)
+			tt.modify(export)
+
+			if _, err := ImportGameState("imported", export); !errors.Is(err, ErrInvalidExport) {
+				t.Errorf("ImportGameState() error = %v, want %v", err, ErrInvalidExport)
+			}
+		})
+	}
+}
+
+func TestGameManager_ImportGame(t *testing.T) {
+	pacing := DefaultBotPacing
+	DefaultBotPacing = BotPacing{Load: time.Hour}
+	t.Cleanup(func() { DefaultBotPacing = pacing })
+
+	manager := NewGameManager(repository.NewMemoryRoomRepository(), repository.NewMemoryGameRepository(), nil, NewLocalCluster())
+	state, export := exportTestGame(t)
+
+	// The owner plays against bots, which wait to keep the position
+	ownerID := export.TurnOrder[0]
+	for _, id := range export.TurnOrder[1:] {
+		export.Players[id].IsBot = true
+	}
+	id, err := manager.ImportGame(export, ownerID)
+	if err != nil {
+		t.Fatalf("ImportGame() error = %v", err)
+	}
+
+	game, err := manager.GetOrCreateGame(id)
+	if err != nil {
+		t.Fatalf("GetOrCreateGame() error = %v", err)
+	}
+	if !game.owner {
+		t.Error("imported game is not run by this instance")
+	}
+
+	exported, err := manager.ExportGame(id, ownerID)
+	if err != nil {
+		t.Fatalf("ExportGame() error = %v", err)
+	}
+	if exported.GameID != id || exported.CurrentTurn != state.CurrentTurn || exported.TurnNumber != state.TurnNumber {
+		t.Errorf("ExportGame() = %s at %s/%d, want %s at %s/%d",
+			exported.GameID, exported.CurrentTurn, exported.TurnNumber, id, state.CurrentTurn, state.TurnNumber)
+	}
+
+	// Anyone else would see the dice, deck, hands and objectives
+	if _, err := manager.ExportGame(id, state.TurnOrder[1]); !errors.Is(err, ErrExportForbidden) {
+		t.Errorf("ExportGame() by a player error = %v, want %v", err, ErrExportForbidden)
+	}
+	if _, err := manager.ExportGame(id, ""); !errors.Is(err, ErrExportForbidden) {
+		t.Errorf("ExportGame() without a player error = %v, want %v", err, ErrExportForbidden)
+	}
+
+	if _, err := manager.ExportGame("missing", ownerID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("ExportGame() of a missing game error = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func TestGameState_ExportableBy(t *testing.T) {
+	gs := NewGameState("room-1")
+	gs.OwnerID = "owner"
+	gs.Players["owner"] = &Player{ID: "owner"}
+	gs.Players["bot"] = &Player{ID: "bot", IsBot: true}
+	gs.Players["human"] = &Player{ID: "human"}
+
+	if gs.exportableBy("owner") {
+		t.Error("exportableBy() the owner against another human = true, want false")
+	}
+
+	gs.Paused = true
+	if !gs.exportableBy("owner") || gs.exportableBy("human") {
+		t.Error("exportableBy() of a paused game, want it for the owner alone")
+	}
+
+	gs.Paused = false
+	gs.Players["human"].IsBot = true
+	if !gs.exportableBy("owner") {
+		t.Error("exportableBy() the owner against bots alone = false, want true")
+	}
+}
+
+func TestGameManager_ImportedGamesStayOutOfHistory(t *testing.T) {
+	history := repository.NewMemoryHistoryRepository()
+	manager := NewGameManager(repository.NewMemoryRoomRepository(), repository.NewMemoryGameRepository(), history, NewLocalCluster())
+	_, export := exportTestGame(t)
+
+	state, err := ImportGameState(uuid.NewString(), export)
+	if err != nil {
+		t.Fatalf("ImportGameState() error = %v", err)
+	}
+	state.WinnerID = state.TurnOrder[0]
+
+	game := manager.newGame(state.RoomID, state)
+	game.checkGameOver()
+	if _, err := history.GetMatch(state.RoomID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetMatch() of an imported game error = %v, want %v", err, repository.ErrNotFound)
+	}
+}
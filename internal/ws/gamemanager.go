@@ -1,20 +1,31 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"es2.uff/war-server/internal/bus"
 	"es2.uff/war-server/internal/domain/bot"
-	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/repository"
 	"github.com/google/uuid"
 )
 
 type GameManager struct {
 	sync.RWMutex
-	games map[string]*Game
+	games   map[string]*Game
+	rooms   repository.RoomRepository
+	archive repository.GameRepository
+	history repository.HistoryRepository
+	cluster Cluster
 }
 
 type Gamelog struct {
@@ -23,91 +34,215 @@ type Gamelog struct {
 }
 
 type Game struct {
-	ID         string
-	GameState  *GameState
-	clients    map[*Client]bool
-	log        []Gamelog
-	broadcast  chan []byte
-	register   chan *Client
-	unregister chan *Client
+	ID          string
+	GameState   *GameState
+	clients     map[*Client]bool
+	log         []Gamelog
+	broadcast   chan []byte
+	register    chan *Client
+	unregister  chan *Client
+	done        chan struct{}
+	turnTimer   *time.Timer
+	resumeTimer *time.Timer
+	archive     repository.GameRepository
+	history     repository.HistoryRepository
+	finished    bool
+	manager     *GameManager
+	cluster     Cluster
+	owner       bool // Whether this instance holds the game's lease
+	ownership   chan bool
+	inbox       bus.Subscription
+	outbox      bus.Subscription
+	ctx         context.Context // Cancelled when the game ends, stopping its bots
+	cancel      context.CancelFunc
+	pacing      BotPacing
+	botTurn     *botTurn     // The bot turn being played
+	botSeq      atomic.Int64 // Numbers the actions of bots
+	savedEvents int          // Events of the state already in the stored event log
 }
 
-func NewGameManager() *GameManager {
+func NewGameManager(
+	rooms repository.RoomRepository,
+	archive repository.GameRepository,
+	history repository.HistoryRepository,
+	cluster Cluster,
+) *GameManager {
 	return &GameManager{
-		games: make(map[string]*Game),
+		games:   make(map[string]*Game),
+		rooms:   rooms,
+		archive: archive,
+		history: history,
+		cluster: cluster,
 	}
 }
 
-func (gm *GameManager) GetOrCreateGame(roomID string) *Game {
+func (gm *GameManager) newGame(id string, state *GameState) *Game {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Game{
+		ID:         id,
+		GameState:  state,
+		clients:    make(map[*Client]bool),
+		log:        []Gamelog{},
+		broadcast:  make(chan []byte),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		done:       make(chan struct{}),
+		archive:    gm.archive,
+		history:    gm.history,
+		manager:    gm,
+		cluster:    gm.cluster,
+		ownership:  make(chan bool),
+		ctx:        ctx,
+		cancel:     cancel,
+		pacing:     DefaultBotPacing,
+	}
+}
+
+// ErrGameOver is returned for games that already ended
+var ErrGameOver = errors.New("game is over")
+
+// GetOrCreateGame returns the game of a room on this instance. The instance
+// that takes the game's lease loads and runs it, the others only relay
+// actions and updates for their own clients. Only stored games and started
+// rooms have a game.
+func (gm *GameManager) GetOrCreateGame(roomID string) (*Game, error) {
 	gm.Lock()
 	defer gm.Unlock()
 
 	if game, exists := gm.games[roomID]; exists {
-		return game
+		return game, nil
 	}
 
-	game := &Game{
-		ID:         roomID,
-		GameState:  NewGameState(roomID),
-		clients:    make(map[*Client]bool),
-		log:        []Gamelog{},
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+	if err := gm.checkGameExists(roomID); err != nil {
+		return nil, err
 	}
 
-	roomUUID, err := uuid.Parse(roomID)
-	if err == nil {
-		r := room.GetRoom(roomUUID)
-		if r != nil && len(r.Players) > 0 {
-			colors := []string{"#FF0000", "#0066FF", "#00CC00", "#FFD700", "#9933FF", "#FF6600"}
-
-			playerCount := 0
-			for i, p := range r.Players {
-				game.GameState.Players[p.ID.String()] = &Player{
-					ID:       p.ID.String(),
-					Username: p.Name,
-					Armies:   0,
-					Color:    colors[i%len(colors)],
-					IsReady:  true,
-				}
-				playerCount++
-			}
+	game := gm.newGame(roomID, NewGameState(roomID))
+	if err := game.start(); err != nil {
+		game.cancel()
+		return nil, fmt.Errorf("starting game %s: %w", roomID, err)
+	}
 
-			if playerCount < 3 {
-				botsToAdd := 3 - playerCount
-
-				for i := range botsToAdd {
-					newBot := bot.NewBot(
-						fmt.Sprintf("Bot %d", i+1),
-						colors[(playerCount+i)%len(colors)],
-					)
-					game.GameState.Players[newBot.ID.String()] = &Player{
-						ID:       newBot.ID.String(),
-						Username: newBot.Name,
-						Armies:   0,
-						Color:    newBot.Color,
-						IsReady:  true,
-						IsBot:    true,
-					}
-				}
-			}
+	gm.games[roomID] = game
+	return game, nil
+}
 
-			botID := game.GameState.StartGame()
+// checkGameExists returns repository.ErrNotFound unless the game is stored
+// or its room has started, and ErrGameOver once it ended
+func (gm *GameManager) checkGameExists(id string) error {
+	if gm.archive != nil {
+		if _, err := gm.archive.GetGame(id); err == nil {
+			return nil
+		}
+	}
 
-			if botID != "" {
-				go func() {
-					time.Sleep(2 * time.Second) // Wait for clients to connect
-					game.executeBotTurn(botID)
-				}()
-			}
+	if gm.history != nil {
+		if _, err := gm.history.GetMatch(id); err == nil {
+			return ErrGameOver
 		}
 	}
 
-	gm.games[roomID] = game
-	go game.Run()
+	roomUUID, err := uuid.Parse(id)
+	if err != nil {
+		return repository.ErrNotFound
+	}
+	if r, err := gm.rooms.Get(roomUUID); err != nil || r == nil || !r.Started {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// forget drops a game that closed, the next request for it starts over
+func (gm *GameManager) forget(game *Game) {
+	gm.Lock()
+	defer gm.Unlock()
+
+	if gm.games[game.ID] == game {
+		delete(gm.games, game.ID)
+	}
+}
+
+// loadGame resumes the stored snapshot of a game, or starts it from its room
+// when there is none
+func (gm *GameManager) loadGame(game *Game) {
+	if !gm.restoreGame(game) {
+		gm.setupGame(game)
+	}
+
+	game.resetTurnTimer()
+	game.scheduleResume()
+
+	game.GameState.RLock()
+	current := game.GameState.Players[game.GameState.CurrentTurn]
+	paused := game.GameState.Paused
+	game.GameState.RUnlock()
+
+	if current != nil && current.IsBot && !paused {
+		game.startBotTurn(current.ID, game.pacing.Load)
+	}
+}
+
+// setupGame seats the room's players and bots and starts a new game
+func (gm *GameManager) setupGame(game *Game) {
+	roomUUID, err := uuid.Parse(game.ID)
+	if err != nil {
+		return
+	}
+
+	r, _ := gm.rooms.Get(roomUUID)
+	if r == nil || len(r.Players) == 0 {
+		return
+	}
+
+	game.GameState.Settings = r.Settings
+	game.GameState.OwnerID = r.OwnerID.String()
+
+	// Humans keep the colors picked in the lobby, the rest take the
+	// leftovers in palette order
+	available := r.AvailableColors()
+	nextColor := func() string {
+		if len(available) == 0 {
+			return player.Palette[0]
+		}
+		color := available[0]
+		available = available[1:]
+		return color
+	}
+
+	playerCount := 0
+	for _, p := range r.Players {
+		color, chosen := r.Colors[p.ID]
+		if !chosen {
+			color = nextColor()
+		}
+
+		game.GameState.Players[p.ID.String()] = &Player{
+			ID:       p.ID.String(),
+			Username: p.Name,
+			Armies:   0,
+			Color:    color,
+			IsReady:  true,
+		}
+		game.GameState.TurnOrder = append(game.GameState.TurnOrder, p.ID.String())
+		playerCount++
+	}
+
+	for i := range r.BotsToAdd(playerCount) {
+		newBot := bot.NewBot(fmt.Sprintf("Bot %d", i+1), nextColor())
+		game.GameState.Players[newBot.ID.String()] = &Player{
+			ID:            newBot.ID.String(),
+			Username:      newBot.Name,
+			Armies:        0,
+			Color:         newBot.Color,
+			IsReady:       true,
+			IsBot:         true,
+			BotDifficulty: string(r.Settings.DifficultyOf(i)),
+		}
+		game.GameState.TurnOrder = append(game.GameState.TurnOrder, newBot.ID.String())
+	}
 
-	return game
+	game.GameState.StartGame()
+	game.persist()
 }
 
 func (g *Game) Run() {
@@ -115,19 +250,41 @@ func (g *Game) Run() {
 		select {
 		case client := <-g.register:
 			g.clients[client] = true
-			g.broadcastGameState()
+			g.requestSync()
 
 		case client := <-g.unregister:
 			if _, ok := g.clients[client]; ok {
 				delete(g.clients, client)
 				close(client.send)
 
-				g.broadcastGameState()
+				g.requestSync()
 			}
 
 		case message := <-g.broadcast:
+			// Actions always go through the bus so only the owner applies them
+			g.publish(gameInboxTopic(g.ID), message)
+
+		case message := <-g.inbox.Messages():
+			if !g.owner {
+				continue
+			}
 			g.handleMessage(message)
+			g.syncBotTurn()
+			g.checkGameOver()
+			g.persist()
 			g.broadcastGameState()
+			if g.finished {
+				g.publishClosed()
+			}
+
+		case message := <-g.outbox.Messages():
+			if g.deliver(message) {
+				g.close()
+				return
+			}
+
+		case owner := <-g.ownership:
+			g.setOwner(owner)
 		}
 	}
 }
@@ -146,23 +303,29 @@ func (g *Game) handleMessage(message []byte) {
 
 	playerID, _ := msg["player_id"].(string)
 
+	if seq, ok := msg["bot_seq"].(float64); ok {
+		defer g.ackBotAction(int64(seq))
+	}
+
 	switch msgType {
 	case "finish_turn":
-		botID, err := g.GameState.NextTurn(playerID)
-		if err != nil {
-			log.Printf("Error processing next turn: %v", err)
-		} else {
-			playerName := g.GameState.Players[playerID].Username
-			g.log = append(g.log, Gamelog{
-				Timestamp: time.Now(),
-				Message:   fmt.Sprintf("%s finalizou o turno.", playerName),
-			})
+		g.finishTurn(playerID, "%s finalizou o turno.")
+	case "turn_timeout":
+		turnNumber, _ := msg["turn_number"].(float64)
 
-			// If next player is bot, schedule bot turn
-			if botID != "" {
-				go g.executeBotTurn(botID)
-			}
+		g.GameState.RLock()
+		stale := g.GameState.CurrentTurn != playerID || g.GameState.TurnNumber != int(turnNumber)
+		g.GameState.RUnlock()
+
+		if !stale {
+			g.finishTurn(playerID, "%s esgotou o tempo do turno.")
 		}
+	case "pause":
+		g.handlePause(playerID, msg)
+	case "resume":
+		g.handleResume(playerID)
+	case "scheduled_resume":
+		g.handleScheduledResume(msg)
 	case "attack":
 		from, _ := msg["from"].(string)
 		to, _ := msg["to"].(string)
@@ -235,34 +398,69 @@ func (g *Game) handleMessage(message []byte) {
 	}
 }
 
+// finishTurn passes the turn to the next player, logging logFormat with the
+// player's name and scheduling the next turn if it belongs to a bot
+func (g *Game) finishTurn(playerID string, logFormat string) {
+	botID, err := g.GameState.NextTurn(playerID)
+	if err != nil {
+		log.Printf("Error processing next turn: %v", err)
+		return
+	}
+
+	playerName := g.GameState.Players[playerID].Username
+	g.log = append(g.log, Gamelog{
+		Timestamp: time.Now(),
+		Message:   fmt.Sprintf(logFormat, playerName),
+	})
+
+	g.resetTurnTimer()
+
+	// If next player is bot, schedule bot turn
+	g.stopBotTurn()
+	if botID != "" {
+		g.startBotTurn(botID, 0)
+	}
+}
+
+// broadcastGameState publishes the state to the players of the game on
+// every instance, the current player's update also lists its legal actions
 func (g *Game) broadcastGameState() {
 	g.GameState.RLock()
-	defer g.GameState.RUnlock()
+	update := map[string]any{
+		"type":      "update",
+		"gameState": g.GameState,
+		"log":       g.log,
+	}
+	data, err := json.Marshal(update)
+	var hinted []byte
+	current := g.GameState.CurrentTurn
+	if hints := g.GameState.turnHintsLocked(); err == nil && hints != nil {
+		update["legalActions"] = hints
+		hinted, err = json.Marshal(update)
+	}
+	recipients := slices.Collect(maps.Keys(g.GameState.Players))
+	g.GameState.RUnlock()
 
-	for client := range g.clients {
-		player := g.GameState.Players[client.id]
-		if player == nil {
-			continue
-		}
+	if err != nil {
+		log.Printf("Error marshaling game state: %v", err)
+		return
+	}
 
-		message := map[string]any{
-			"type":      "update",
-			"gameState": g.GameState,
-			"log":       g.log,
+	updates := []gameUpdate{{To: recipients, Data: data}}
+	if hinted != nil {
+		updates = []gameUpdate{
+			{To: slices.DeleteFunc(recipients, func(id string) bool { return id == current }), Data: data},
+			{To: []string{current}, Data: hinted},
 		}
+	}
 
-		data, err := json.Marshal(message)
+	for _, update := range updates {
+		payload, err := json.Marshal(update)
 		if err != nil {
-			log.Printf("Error marshaling game state: %v", err)
-			continue
-		}
-
-		select {
-		case client.send <- data:
-		default:
-			close(client.send)
-			delete(g.clients, client)
+			log.Printf("Error marshaling game update: %v", err)
+			return
 		}
+		g.publish(gameOutboxTopic(g.ID), payload)
 	}
 }
 
@@ -278,6 +476,10 @@ func (g *Game) GetBroadcastChan() chan []byte {
 	return g.broadcast
 }
 
+func (g *Game) Done() <-chan struct{} {
+	return g.done
+}
+
 func (g *Game) getTerritoryNameByID(territoryID string) string {
 	g.GameState.RLock()
 	defer g.GameState.RUnlock()
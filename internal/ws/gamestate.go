@@ -1,15 +1,20 @@
 package ws
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"math/rand/v2"
 	"slices"
 	"sync"
+	"time"
 
 	"es2.uff/war-server/internal/domain/battle"
 	"es2.uff/war-server/internal/domain/card"
 	"es2.uff/war-server/internal/domain/game"
 	"es2.uff/war-server/internal/domain/objective"
 	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
 	"es2.uff/war-server/internal/domain/territory"
 	"github.com/google/uuid"
 )
@@ -22,6 +27,7 @@ type Player struct {
 	IsReady       bool         `json:"is_ready"`
 	IsOwner       bool         `json:"is_owner"`
 	IsBot         bool         `json:"is_bot"`
+	BotDifficulty string       `json:"bot_difficulty,omitempty"`
 	ObjectiveID   int          `json:"objective_id"`
 	ObjectiveDesc string       `json:"objective_desc"`
 	CardsInHand   []*card.Card `json:"cards_in_hand"`
@@ -33,19 +39,36 @@ type Territory struct {
 	Owner      string   `json:"owner"` // Player ID
 	OwnerColor string   `json:"owner_color"`
 	Armies     int      `json:"armies"`
+	Region     int      `json:"region"`
 	Adjacent   []string `json:"adjacent"` // Adjacent territory IDs
 }
 
 type GameState struct {
 	sync.RWMutex
-	RoomID                    string             `json:"room_id"`
-	Players                   map[string]*Player `json:"players"`
-	FinishedInitialDeployment []string           `json:"finished_initial_deployment"`
-	Territories               []*Territory       `json:"territories"`
-	CurrentTurn               string             `json:"current_turn"` // Player ID whose turn it is
-	OwnerID                   string             `json:"owner_id"`
-	Deck                      *card.Deck         `json:"-"`
-	TradesCount               int                `json:"trades_count"`
+	RoomID                    string                  `json:"room_id"`
+	Players                   map[string]*Player      `json:"players"`
+	FinishedInitialDeployment []string                `json:"finished_initial_deployment"`
+	Territories               []*Territory            `json:"territories"`
+	TurnOrder                 []string                `json:"turn_order"`   // Player IDs in seat order
+	CurrentTurn               string                  `json:"current_turn"` // Player ID whose turn it is
+	TurnNumber                int                     `json:"turn_number"`
+	TurnDeadline              *time.Time              `json:"turn_deadline,omitempty"`
+	OwnerID                   string                  `json:"owner_id"`
+	Deck                      *card.Deck              `json:"-"`
+	TradesCount               int                     `json:"trades_count"`
+	Settings                  room.RoomSettings       `json:"settings"`
+	StartedAt                 time.Time               `json:"started_at"`
+	FinishedAt                *time.Time              `json:"finished_at,omitempty"`
+	WinnerID                  string                  `json:"winner_id,omitempty"`
+	Paused                    bool                    `json:"paused"`
+	PauseVotes                []string                `json:"pause_votes,omitempty"` // Players asking to pause or resume
+	ResumeAt                  *time.Time              `json:"resume_at,omitempty"`
+	TurnTimeLeft              time.Duration           `json:"turn_time_left,omitempty"` // Left on the turn timer when paused
+	Stats                     map[string]*PlayerStats `json:"stats"`
+	Imported                  bool                    `json:"imported,omitempty"` // Loaded from an export, kept out of the match history
+	Events                    []GameEvent             `json:"-"`
+	rng                       *rand.PCG               // Source of the dice, exported with the game
+	simulation                bool                    // Set on clones, which record no events
 }
 
 func NewGameState(roomID string) *GameState {
@@ -55,17 +78,44 @@ func NewGameState(roomID string) *GameState {
 		Territories: nil,
 		CurrentTurn: "",
 		TradesCount: 2,
+		Stats:       make(map[string]*PlayerStats),
+		rng:         newRNG(),
 	}
 
 	return gs
 }
 
+// newRNG returns a dice source with a random seed
+func newRNG() *rand.PCG {
+	return rand.NewPCG(rand.Uint64(), rand.Uint64())
+}
+
+// Seed replaces the dice with a source seeded with seed. Seeded before the
+// game starts, the same players and decisions play the same game.
+func (gs *GameState) Seed(seed uint64) {
+	gs.Lock()
+	defer gs.Unlock()
+	gs.rng = rand.NewPCG(seed, seed)
+}
+
+// newTerritoryID draws a UUID from random, so seeded games name their
+// territories the same
+func newTerritoryID(random *rand.Rand) string {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], random.Uint64())
+	binary.LittleEndian.PutUint64(b[8:], random.Uint64())
+	id, _ := uuid.NewRandomFromReader(bytes.NewReader(b[:]))
+	return id.String()
+}
+
 func (gs *GameState) StartGame() string {
 	gs.Lock()
 	defer gs.Unlock()
 
+	gs.TurnOrder = gs.turnOrderLocked()
+
 	domainPlayers := make([]*player.Player, 0, len(gs.Players))
-	for playerID := range gs.Players {
+	for _, playerID := range gs.TurnOrder {
 		playerUUID, err := uuid.Parse(playerID)
 		if err != nil {
 			continue
@@ -78,13 +128,15 @@ func (gs *GameState) StartGame() string {
 		domainPlayers = append(domainPlayers, domainPlayer)
 	}
 
-	domainTerritories := game.InstantiateGameTerritories(domainPlayers)
+	// The setup draws from the dice, so a seeded game deals the same way
+	random := rand.New(gs.rng)
+	domainTerritories := game.InstantiateGameTerritories(domainPlayers, random)
 
 	territoryIDMap := make(map[int]string)
 	gs.Territories = make([]*Territory, 0, len(domainTerritories))
 
 	for _, dt := range domainTerritories {
-		wsID := uuid.NewString()
+		wsID := newTerritoryID(random)
 		territoryIDMap[dt.TerritoryID] = wsID
 
 		wsTerr := &Territory{
@@ -93,6 +145,7 @@ func (gs *GameState) StartGame() string {
 			Owner:      dt.OwnerID.String(),
 			OwnerColor: dt.OwnerColor,
 			Armies:     dt.ArmyQuantity,
+			Region:     dt.RegionID,
 			Adjacent:   []string{},
 		}
 
@@ -113,24 +166,35 @@ func (gs *GameState) StartGame() string {
 		gs.Territories[i].Adjacent = adjacentWSIDs
 	}
 
-	game.AssignObjectivesToPlayers(domainPlayers)
-
-	for _, domainPlayer := range domainPlayers {
-		wsPlayer := gs.Players[domainPlayer.ID.String()]
-		if wsPlayer != nil {
-			wsPlayer.ObjectiveID = int(domainPlayer.ObjectiveID)
-			if objDetails, exists := objective.ObjectiveDetails[domainPlayer.ObjectiveID]; exists {
-				wsPlayer.ObjectiveDesc = objDetails.Description
+	if gs.Settings.RuleVariant == room.VariantWorldDomination {
+		for _, wsPlayer := range gs.Players {
+			wsPlayer.ObjectiveID = -1
+			wsPlayer.ObjectiveDesc = "Conquistar todos os territórios."
+		}
+	} else {
+		game.AssignObjectivesToPlayers(domainPlayers, random)
+
+		for _, domainPlayer := range domainPlayers {
+			wsPlayer := gs.Players[domainPlayer.ID.String()]
+			if wsPlayer != nil {
+				wsPlayer.ObjectiveID = int(domainPlayer.ObjectiveID)
+				if objDetails, exists := objective.ObjectiveDetails[domainPlayer.ObjectiveID]; exists {
+					wsPlayer.ObjectiveDesc = objDetails.Description
+				}
 			}
 		}
 	}
 
 	gs.Deck = card.NewDeck()
-	gs.Deck.Shuffle()
+	gs.Deck.ShuffleWith(random)
 
 	firstPlayerID := domainPlayers[0].ID.String()
 	gs.getTurnAdditionalTroopsLocked(firstPlayerID)
 	gs.CurrentTurn = firstPlayerID
+	gs.TurnNumber = 1
+
+	gs.Events = nil
+	gs.recordLocked(GameEvent{Type: EventGameStarted, Setup: gs.setupLocked()})
 
 	// Return bot ID if first player is a bot
 	if gs.Players[firstPlayerID].IsBot {
@@ -143,6 +207,28 @@ func (gs *GameState) Move(playerID, fromTerritoryID, toTerritoryID string, movin
 	gs.Lock()
 	defer gs.Unlock()
 
+	if err := gs.moveLocked(playerID, fromTerritoryID, toTerritoryID, movingArmies); err != nil {
+		return err
+	}
+
+	gs.recordLocked(GameEvent{
+		Type:     EventMoved,
+		PlayerID: playerID,
+		From:     fromTerritoryID,
+		To:       toTerritoryID,
+		Armies:   movingArmies,
+	})
+	return nil
+}
+
+func (gs *GameState) moveLocked(playerID, fromTerritoryID, toTerritoryID string, movingArmies int) error {
+	if gs.WinnerID != "" {
+		return fmt.Errorf("game is over")
+	}
+	if gs.Paused {
+		return fmt.Errorf("game is paused")
+	}
+
 	var fromTerritory, toTerritory *Territory
 	for _, t := range gs.Territories {
 		if t.ID == fromTerritoryID {
@@ -179,6 +265,7 @@ func (gs *GameState) Move(playerID, fromTerritoryID, toTerritoryID string, movin
 
 	fromTerritory.Armies -= movingArmies
 	toTerritory.Armies += movingArmies
+	gs.checkWinnerLocked(playerID)
 
 	return nil
 }
@@ -187,6 +274,28 @@ func (gs *GameState) Trade(playerID, card1, card2, card3 string) (int, error) {
 	gs.Lock()
 	defer gs.Unlock()
 
+	received, err := gs.tradeLocked(playerID, card1, card2, card3)
+	if err != nil {
+		return 0, err
+	}
+
+	gs.recordLocked(GameEvent{
+		Type:     EventTraded,
+		PlayerID: playerID,
+		Cards:    []string{card1, card2, card3},
+		Armies:   received,
+	})
+	return received, nil
+}
+
+func (gs *GameState) tradeLocked(playerID, card1, card2, card3 string) (int, error) {
+	if gs.WinnerID != "" {
+		return 0, fmt.Errorf("game is over")
+	}
+	if gs.Paused {
+		return 0, fmt.Errorf("game is paused")
+	}
+
 	player := gs.Players[playerID]
 	if player == nil {
 		return 0, fmt.Errorf("player not found")
@@ -209,15 +318,12 @@ func (gs *GameState) Trade(playerID, card1, card2, card3 string) (int, error) {
 		}
 	}
 
-	if len(cardsToRemove) != 3 {
-		return 0, fmt.Errorf("must trade exactly 3 cards")
-	}
-
-	firstShape := cardsToRemove[0].Shape
+	set := make([]card.Card, 0, len(cardsToRemove))
 	for _, c := range cardsToRemove {
-		if c.Shape != firstShape {
-			return 0, fmt.Errorf("all cards must have the same shape")
-		}
+		set = append(set, *c)
+	}
+	if err := card.ValidateSet(set); err != nil {
+		return 0, err
 	}
 
 	newHand := make([]*card.Card, 0, len(player.CardsInHand)-3)
@@ -239,17 +345,54 @@ func (gs *GameState) Trade(playerID, card1, card2, card3 string) (int, error) {
 		gs.Deck.AddToBottom(*c)
 	}
 
-	troopsReceived := 2 * gs.TradesCount
+	troopsReceived := tradeBonus(gs.TradesCount)
 	player.Armies += troopsReceived
 	gs.TradesCount++
+	gs.statsLocked(playerID).Trades++
 
 	return troopsReceived, nil
 }
 
+// tradeBonus returns the armies given by a trade after tradesCount others
+func tradeBonus(tradesCount int) int {
+	return 2 * tradesCount
+}
+
 func (gs *GameState) Attack(playerID, fromTerritoryID, toTerritoryID string, attackingArmies int) (bool, error) {
 	gs.Lock()
 	defer gs.Unlock()
 
+	victory, event, err := gs.attackLocked(playerID, fromTerritoryID, toTerritoryID, attackingArmies, nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	gs.recordLocked(event)
+	return victory, nil
+}
+
+// attackLocked resolves an attack with the given dice, rolling them when they
+// are nil. The returned event records the dice used.
+func (gs *GameState) attackLocked(
+	playerID, fromTerritoryID, toTerritoryID string,
+	attackingArmies int,
+	attackerDice, defenderDice []int,
+) (bool, GameEvent, error) {
+	event := GameEvent{
+		Type:     EventAttacked,
+		PlayerID: playerID,
+		From:     fromTerritoryID,
+		To:       toTerritoryID,
+		Armies:   attackingArmies,
+	}
+
+	if gs.WinnerID != "" {
+		return false, event, fmt.Errorf("game is over")
+	}
+	if gs.Paused {
+		return false, event, fmt.Errorf("game is paused")
+	}
+
 	var fromTerritory, toTerritory *Territory
 	for _, t := range gs.Territories {
 		if t.ID == fromTerritoryID {
@@ -261,37 +404,43 @@ func (gs *GameState) Attack(playerID, fromTerritoryID, toTerritoryID string, att
 	}
 
 	if fromTerritory == nil || toTerritory == nil {
-		return false, fmt.Errorf("territory not found")
+		return false, event, fmt.Errorf("territory not found")
 	}
 
 	if fromTerritory.Owner != playerID {
-		return false, fmt.Errorf("not the owner of attacking territory")
+		return false, event, fmt.Errorf("not the owner of attacking territory")
 	}
 
 	if toTerritory.Owner == playerID {
-		return false, fmt.Errorf("cannot attack your own territory")
+		return false, event, fmt.Errorf("cannot attack your own territory")
 	}
 
 	if fromTerritory.Armies <= attackingArmies {
-		return false, fmt.Errorf("not enough armies (must leave 1 for occupation)")
+		return false, event, fmt.Errorf("not enough armies (must leave 1 for occupation)")
 	}
 
 	if attackingArmies > 3 || attackingArmies < 1 {
-		return false, fmt.Errorf("attacking armies must be between 1 and 3")
+		return false, event, fmt.Errorf("attacking armies must be between 1 and 3")
 	}
 
 	if !slices.Contains(fromTerritory.Adjacent, toTerritoryID) {
-		return false, fmt.Errorf("territories are not adjacent")
+		return false, event, fmt.Errorf("territories are not adjacent")
 	}
 
-	defendingArmies := min(toTerritory.Armies, 3)
-
-	attackerDice := battle.RollDice(attackingArmies)
-	defenderDice := battle.RollDice(defendingArmies)
+	if attackerDice == nil || defenderDice == nil {
+		dice := rand.New(gs.rng)
+		attackerDice = battle.RollDiceWith(dice, attackingArmies)
+		defenderDice = battle.RollDiceWith(dice, min(toTerritory.Armies, 3))
+	}
+	event.AttackerDice = attackerDice
+	event.DefenderDice = defenderDice
 
 	attackerLosses, defenderLosses := battle.CompareDice(attackerDice, defenderDice)
 	attackResult := attackerLosses < defenderLosses
 
+	defenderID := toTerritory.Owner
+	gs.countBattleLocked(playerID, defenderID, attackerLosses, defenderLosses)
+
 	fromTerritory.Armies -= attackerLosses
 	toTerritory.Armies -= defenderLosses
 
@@ -300,23 +449,45 @@ func (gs *GameState) Attack(playerID, fromTerritoryID, toTerritoryID string, att
 		toTerritory.OwnerColor = fromTerritory.OwnerColor
 		toTerritory.Armies = attackingArmies - attackerLosses
 		fromTerritory.Armies -= (attackingArmies - attackerLosses)
+		gs.countConquestLocked(playerID, defenderID, toTerritory)
 
 		drawnCard := gs.Deck.Draw()
 		if drawnCard != nil {
 			gs.Players[playerID].CardsInHand = append(gs.Players[playerID].CardsInHand, drawnCard)
 		}
+
+		gs.checkWinnerLocked(playerID)
 	}
 
-	return attackResult, nil
+	return attackResult, event, nil
 }
 
 func (gs *GameState) Deploy(playerID, territoryID string) error {
 	gs.Lock()
 	defer gs.Unlock()
 
+	if gs.WinnerID != "" {
+		return fmt.Errorf("game is over")
+	}
+	if gs.Paused {
+		return fmt.Errorf("game is paused")
+	}
+
+	if gs.deployLocked(playerID, territoryID) {
+		gs.recordLocked(GameEvent{
+			Type:        EventDeployed,
+			PlayerID:    playerID,
+			TerritoryID: territoryID,
+		})
+	}
+	return nil
+}
+
+// deployLocked places one army, reporting whether it could be placed
+func (gs *GameState) deployLocked(playerID, territoryID string) bool {
 	player := gs.Players[playerID]
 	if player == nil || player.Armies == 0 {
-		return nil
+		return false
 	}
 
 	var territory *Territory
@@ -327,22 +498,38 @@ func (gs *GameState) Deploy(playerID, territoryID string) error {
 		}
 	}
 
-	if territory == nil {
-		return nil
+	if territory == nil || territory.Owner != playerID {
+		return false
 	}
 
-	if territory.Owner == playerID {
-		territory.Armies += 1
-		player.Armies -= 1
-	}
+	territory.Armies += 1
+	player.Armies -= 1
+	gs.checkWinnerLocked(playerID)
 
-	return nil
+	return true
 }
 
 func (gs *GameState) NextTurn(senderID string) (string, error) {
 	gs.Lock()
 	defer gs.Unlock()
 
+	botID, err := gs.nextTurnLocked(senderID)
+	if err != nil {
+		return "", err
+	}
+
+	gs.recordLocked(GameEvent{Type: EventTurnEnded, PlayerID: senderID})
+	return botID, nil
+}
+
+func (gs *GameState) nextTurnLocked(senderID string) (string, error) {
+	if gs.WinnerID != "" {
+		return "", fmt.Errorf("game is over")
+	}
+	if gs.Paused {
+		return "", fmt.Errorf("game is paused")
+	}
+
 	if len(gs.Players) == 0 {
 		return "", nil
 	}
@@ -351,10 +538,7 @@ func (gs *GameState) NextTurn(senderID string) (string, error) {
 		return "", fmt.Errorf("Not the turn owner")
 	}
 
-	playerIDs := make([]string, 0, len(gs.Players))
-	for pid := range gs.Players {
-		playerIDs = append(playerIDs, pid)
-	}
+	playerIDs := gs.turnOrderLocked()
 
 	currentIndex := -1
 	for i, pid := range playerIDs {
@@ -364,10 +548,17 @@ func (gs *GameState) NextTurn(senderID string) (string, error) {
 		}
 	}
 
-	nextIndex := (currentIndex + 1) % len(playerIDs)
-	nextPlayerID := playerIDs[nextIndex]
+	// Players who lost every territory are out of the game
+	nextPlayerID := gs.CurrentTurn
+	for i := 1; i <= len(playerIDs); i++ {
+		nextPlayerID = playerIDs[(currentIndex+i)%len(playerIDs)]
+		if !gs.eliminatedLocked(nextPlayerID) {
+			break
+		}
+	}
 
 	gs.CurrentTurn = nextPlayerID
+	gs.TurnNumber++
 	gs.getTurnAdditionalTroopsLocked(nextPlayerID)
 
 	// Return bot ID if next player is a bot
@@ -377,6 +568,68 @@ func (gs *GameState) NextTurn(senderID string) (string, error) {
 	return "", nil
 }
 
+// eliminatedLocked reports whether the player holds no territory on a dealt
+// board
+func (gs *GameState) eliminatedLocked(playerID string) bool {
+	if len(gs.Territories) == 0 {
+		return false
+	}
+	for _, t := range gs.Territories {
+		if t.Owner == playerID {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWinnerLocked ends the game when the player has completed their
+// objective, or holds every territory in world domination
+func (gs *GameState) checkWinnerLocked(playerID string) {
+	p := gs.Players[playerID]
+	if p == nil || gs.WinnerID != "" {
+		return
+	}
+
+	holdings := make([]objective.Holding, 0, len(gs.Territories))
+	for _, t := range gs.Territories {
+		if t.Owner == playerID {
+			holdings = append(holdings, objective.Holding{
+				Region: territory.Region(t.Region),
+				Armies: t.Armies,
+			})
+		}
+	}
+
+	won := len(holdings) == len(gs.Territories)
+	if !won && gs.Settings.RuleVariant != room.VariantWorldDomination {
+		if details, exists := objective.ObjectiveDetails[objective.ObjectiveID(p.ObjectiveID)]; exists {
+			won = details.IsCompleted(holdings)
+		}
+	}
+
+	if won {
+		now := time.Now()
+		gs.WinnerID = playerID
+		gs.FinishedAt = &now
+		gs.TurnDeadline = nil
+	}
+}
+
+// turnOrderLocked returns the seat order set by the room, falling back to the
+// sorted player IDs when no complete order was given
+func (gs *GameState) turnOrderLocked() []string {
+	if len(gs.TurnOrder) == len(gs.Players) {
+		return gs.TurnOrder
+	}
+
+	order := make([]string, 0, len(gs.Players))
+	for pid := range gs.Players {
+		order = append(order, pid)
+	}
+	slices.Sort(order)
+	return order
+}
+
 func (gs *GameState) GetTurnAdditionalTroops(playerID string) {
 	gs.Lock()
 	defer gs.Unlock()
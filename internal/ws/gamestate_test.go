@@ -4,7 +4,9 @@ import (
 	"testing"
 
 	"es2.uff/war-server/internal/domain/card"
+	"es2.uff/war-server/internal/domain/room"
 	"es2.uff/war-server/internal/domain/territory"
+	"github.com/google/uuid"
 )
 
 func TestGameState_Deploy(t *testing.T) {
@@ -366,3 +368,113 @@ func TestGameState_NextTurn_NotCurrentPlayer(t *testing.T) {
 		t.Error("NextTurn() by non-current player should return error, got nil")
 	}
 }
+
+func TestGameState_StartGame_WorldDomination(t *testing.T) {
+	gs := NewGameState("test-room")
+	gs.Settings = room.DefaultSettings()
+	gs.Settings.RuleVariant = room.VariantWorldDomination
+
+	for range 3 {
+		id := uuid.NewString()
+		gs.Players[id] = &Player{ID: id, Username: "Player"}
+	}
+
+	gs.StartGame()
+
+	for _, p := range gs.Players {
+		if p.ObjectiveID != -1 {
+			t.Errorf("Player %s ObjectiveID = %d, want -1 in world domination", p.ID, p.ObjectiveID)
+		}
+	}
+
+	if gs.TurnNumber != 1 {
+		t.Errorf("TurnNumber = %d, want 1", gs.TurnNumber)
+	}
+}
+
+func TestGameState_NextTurn_FollowsTurnOrder(t *testing.T) {
+	gs := NewGameState("test-room")
+	for _, id := range []string{"a", "b", "c"} {
+		gs.Players[id] = &Player{ID: id, Username: id}
+	}
+	gs.TurnOrder = []string{"c", "a", "b"}
+	gs.CurrentTurn = "c"
+
+	for _, want := range []string{"a", "b", "c"} {
+		if _, err := gs.NextTurn(gs.CurrentTurn); err != nil {
+			t.Fatalf("NextTurn() error = %v, want nil", err)
+		}
+		if gs.CurrentTurn != want {
+			t.Errorf("CurrentTurn = %s, want %s", gs.CurrentTurn, want)
+		}
+	}
+}
+
+func TestGameState_NextTurn_SkipsEliminatedPlayers(t *testing.T) {
+	gs := NewGameState("test-room")
+	for _, id := range []string{"a", "b", "c"} {
+		gs.Players[id] = &Player{ID: id, Username: id}
+	}
+	gs.TurnOrder = []string{"a", "b", "c"}
+	gs.CurrentTurn = "a"
+	gs.Territories = []*Territory{
+		{ID: "t1", Owner: "a", Armies: 1},
+		{ID: "t2", Owner: "c", Armies: 1},
+	}
+
+	if _, err := gs.NextTurn("a"); err != nil {
+		t.Fatalf("NextTurn() error = %v, want nil", err)
+	}
+	if gs.CurrentTurn != "c" {
+		t.Errorf("CurrentTurn = %s, want c past the eliminated b", gs.CurrentTurn)
+	}
+	if gs.Players["b"].Armies != 0 {
+		t.Errorf("eliminated b got %d armies, want 0", gs.Players["b"].Armies)
+	}
+}
+
+func TestGameState_Deploy_WorldDominationWin(t *testing.T) {
+	gs := NewGameState("test-room")
+	gs.Settings = room.DefaultSettings()
+	gs.Settings.RuleVariant = room.VariantWorldDomination
+
+	gs.Players["player1"] = &Player{ID: "player1", Armies: 2}
+	gs.Players["player2"] = &Player{ID: "player2"}
+	gs.Territories = []*Territory{
+		{ID: "t1", Owner: "player1", Armies: 1},
+		{ID: "t2", Owner: "player2", Armies: 1},
+	}
+
+	if err := gs.Deploy("player1", "t1"); err != nil || gs.WinnerID != "" {
+		t.Fatalf("Deploy() = %v with winner %q, want no winner yet", err, gs.WinnerID)
+	}
+
+	gs.Territories[1].Owner = "player1"
+	if err := gs.Deploy("player1", "t1"); err != nil {
+		t.Fatalf("Deploy() error = %v", err)
+	}
+
+	if gs.WinnerID != "player1" || gs.FinishedAt == nil {
+		t.Errorf("WinnerID = %q, want player1 after holding every territory", gs.WinnerID)
+	}
+
+	if _, err := gs.NextTurn(gs.CurrentTurn); err == nil {
+		t.Error("NextTurn() after the game ended error = nil, want an error")
+	}
+}
+
+func TestGameState_Trade_SameCardTwice(t *testing.T) {
+	gs := NewGameState("test-room")
+	gs.Deck = card.NewDeck()
+	gs.Players["player1"] = &Player{
+		ID: "player1",
+		CardsInHand: []*card.Card{
+			{TerritoryName: "Brasil", Shape: territory.Square},
+			{TerritoryName: "Argentina", Shape: territory.Square},
+		},
+	}
+
+	if _, err := gs.Trade("player1", "Brasil", "Brasil", "Argentina"); err == nil {
+		t.Error("Trade() of the same card twice should return error, got nil")
+	}
+}
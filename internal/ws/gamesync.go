@@ -0,0 +1,200 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	gameLeaseTTL     = 15 * time.Second
+	gameLeaseRenewal = 5 * time.Second
+)
+
+// gameUpdate is a message for the clients of the listed players, on whichever
+// instance they are connected
+type gameUpdate struct {
+	To   []string        `json:"to"`
+	Data json.RawMessage `json:"data"`
+	// Closed tells every instance the game ended, after its last update
+	Closed bool `json:"closed,omitempty"`
+}
+
+// start subscribes the game to its topics, loads it if this instance gets the
+// lease and starts its loops
+func (g *Game) start() error {
+	inbox, err := g.cluster.Bus.Subscribe(gameInboxTopic(g.ID))
+	if err != nil {
+		return err
+	}
+
+	outbox, err := g.cluster.Bus.Subscribe(gameOutboxTopic(g.ID))
+	if err != nil {
+		inbox.Close()
+		return err
+	}
+
+	g.inbox = inbox
+	g.outbox = outbox
+
+	owner, err := g.cluster.Leases.Acquire(gameLeaseKey(g.ID), g.cluster.Node, gameLeaseTTL)
+	if err != nil {
+		log.Printf("Error acquiring lease of game %s: %v", g.ID, err)
+	}
+
+	if owner {
+		g.owner = true
+		g.manager.loadGame(g)
+	}
+
+	go g.Run()
+	go g.holdLease(owner)
+
+	return nil
+}
+
+// holdLease renews the lease while this instance owns the game and keeps
+// trying to take it over otherwise, so another instance picks the game up if
+// its owner goes away
+func (g *Game) holdLease(owner bool) {
+	ticker := time.NewTicker(gameLeaseRenewal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.done:
+			if owner {
+				if err := g.cluster.Leases.Release(gameLeaseKey(g.ID), g.cluster.Node); err != nil {
+					log.Printf("Error releasing lease of game %s: %v", g.ID, err)
+				}
+			}
+			return
+
+		case <-ticker.C:
+			acquired, err := g.cluster.Leases.Acquire(gameLeaseKey(g.ID), g.cluster.Node, gameLeaseTTL)
+			if err != nil {
+				log.Printf("Error renewing lease of game %s: %v", g.ID, err)
+				continue
+			}
+
+			if acquired == owner {
+				continue
+			}
+
+			owner = acquired
+			select {
+			case g.ownership <- owner:
+			case <-g.done:
+				return
+			}
+		}
+	}
+}
+
+// setOwner runs on the game's loop when this instance gains or loses the lease
+func (g *Game) setOwner(owner bool) {
+	if g.owner == owner {
+		return
+	}
+	g.owner = owner
+
+	if owner {
+		log.Printf("Taking over game %s", g.ID)
+		g.manager.loadGame(g)
+		g.broadcastGameState()
+		return
+	}
+
+	log.Printf("Lost the lease of game %s", g.ID)
+	g.stopBotTurn()
+	if g.turnTimer != nil {
+		g.turnTimer.Stop()
+		g.turnTimer = nil
+	}
+	if g.resumeTimer != nil {
+		g.resumeTimer.Stop()
+		g.resumeTimer = nil
+	}
+}
+
+// requestSync asks the owner to publish the current state, used when a client
+// connects to an instance that does not run the game
+func (g *Game) requestSync() {
+	data, err := json.Marshal(map[string]any{"type": "sync"})
+	if err != nil {
+		return
+	}
+	g.publish(gameInboxTopic(g.ID), data)
+}
+
+func (g *Game) publish(topic string, payload []byte) {
+	if err := g.cluster.Bus.Publish(topic, payload); err != nil {
+		log.Printf("Error publishing to %s: %v", topic, err)
+	}
+}
+
+// deliver sends an update to the local clients it is addressed to and
+// reports whether the game closed
+func (g *Game) deliver(payload []byte) bool {
+	var update gameUpdate
+	if err := json.Unmarshal(payload, &update); err != nil {
+		log.Printf("Error unmarshaling game update: %v", err)
+		return false
+	}
+	if update.Closed {
+		return true
+	}
+
+	for client := range g.clients {
+		if !slices.Contains(update.To, client.id) {
+			continue
+		}
+
+		select {
+		case client.send <- update.Data:
+		default:
+			close(client.send)
+			delete(g.clients, client)
+		}
+	}
+	return false
+}
+
+// publishClosed tells every instance to close the game, once the owner sent
+// its last update
+func (g *Game) publishClosed() {
+	payload, err := json.Marshal(gameUpdate{Closed: true})
+	if err != nil {
+		return
+	}
+	g.publish(gameOutboxTopic(g.ID), payload)
+}
+
+// close stops the game on this instance, on its loop: the lease is released,
+// the loops and bots end, the clients are disconnected and the manager
+// forgets it
+func (g *Game) close() {
+	g.cancel()
+	close(g.done)
+
+	if g.turnTimer != nil {
+		g.turnTimer.Stop()
+		g.turnTimer = nil
+	}
+	if g.resumeTimer != nil {
+		g.resumeTimer.Stop()
+		g.resumeTimer = nil
+	}
+
+	g.inbox.Close()
+	g.outbox.Close()
+
+	for client := range g.clients {
+		close(client.send)
+		delete(g.clients, client)
+	}
+
+	g.manager.forget(g)
+	log.Printf("Closed game %s", g.ID)
+}
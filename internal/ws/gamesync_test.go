@@ -0,0 +1,194 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"es2.uff/war-server/internal/bus"
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/repository"
+	"github.com/google/uuid"
+)
+
+// newTestReplicas builds two game managers sharing storage and a bus, as two
+// instances behind a load balancer, and a started room of two players
+func newTestReplicas(t *testing.T) (owner, follower *GameManager, roomID string, players []string) {
+	t.Helper()
+
+	rooms := repository.NewMemoryRoomRepository()
+	archive := repository.NewMemoryGameRepository()
+	history := repository.NewMemoryHistoryRepository()
+	b, leases := bus.NewMemoryBus(), bus.NewMemoryLeases()
+
+	settings := room.DefaultSettings()
+	settings.BotCount = 0
+
+	r, err := room.NewRoom("Replicated", uuid.New(), "Owner", settings)
+	if err != nil {
+		t.Fatalf("NewRoom() error = %v", err)
+	}
+	for _, name := range []string{"Alice", "Bob"} {
+		p, _ := player.NewPlayer(name)
+		r.AddPlayer(p)
+		players = append(players, p.ID.String())
+	}
+	r.Started = true
+	if err := rooms.Create(r); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	owner = NewGameManager(rooms, archive, history, Cluster{Node: "a", Bus: b, Leases: leases})
+	follower = NewGameManager(rooms, archive, history, Cluster{Node: "b", Bus: b, Leases: leases})
+
+	return owner, follower, r.RoomID.String(), players
+}
+
+func receiveUpdate(t *testing.T, c *Client) *GameState {
+	t.Helper()
+
+	select {
+	case data := <-c.send:
+		var msg struct {
+			Type      string     `json:"type"`
+			GameState *GameState `json:"gameState"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "update" {
+			t.Fatalf("unexpected message %s: %v", data, err)
+		}
+		return msg.GameState
+	case <-time.After(2 * time.Second):
+		t.Fatal("no update received")
+		return nil
+	}
+}
+
+func TestGame_FollowerRelaysToOwner(t *testing.T) {
+	a, b, roomID, players := newTestReplicas(t)
+
+	owned, _ := a.GetOrCreateGame(roomID)
+	relayed, _ := b.GetOrCreateGame(roomID)
+
+	if !owned.owner || relayed.owner {
+		t.Fatalf("owner flags = %v and %v, want only the first instance owning the game", owned.owner, relayed.owner)
+	}
+
+	client := &Client{id: players[1], send: make(chan []byte, 16)}
+	relayed.register <- client
+
+	// The follower has no state of its own, the update comes from the owner
+	state := receiveUpdate(t, client)
+	if state.TurnNumber != 1 || len(state.Territories) == 0 {
+		t.Fatalf("first update = turn %d with %d territories, want the started game", state.TurnNumber, len(state.Territories))
+	}
+
+	finish, _ := json.Marshal(map[string]any{"type": "finish_turn", "player_id": state.CurrentTurn})
+	relayed.broadcast <- finish
+
+	if next := receiveUpdate(t, client); next.TurnNumber != 2 {
+		t.Errorf("TurnNumber after finish_turn on the follower = %d, want 2", next.TurnNumber)
+	}
+}
+
+func TestGame_FollowerTakesOver(t *testing.T) {
+	a, b, roomID, players := newTestReplicas(t)
+
+	owned, _ := a.GetOrCreateGame(roomID)
+	relayed, _ := b.GetOrCreateGame(roomID)
+
+	owned.GameState.RLock()
+	want := owned.GameState.CurrentTurn
+	owned.GameState.RUnlock()
+
+	client := &Client{id: players[0], send: make(chan []byte, 16)}
+	relayed.register <- client
+	receiveUpdate(t, client)
+
+	// The lease moved to the follower, which resumes from the stored snapshot
+	relayed.ownership <- true
+
+	state := receiveUpdate(t, client)
+	if state.CurrentTurn != want || state.TurnNumber != 1 {
+		t.Errorf("state after takeover = turn of %s #%d, want %s #1", state.CurrentTurn, state.TurnNumber, want)
+	}
+}
+
+func TestGame_ClosesWhenOver(t *testing.T) {
+	a, b, roomID, players := newTestReplicas(t)
+
+	owned, _ := a.GetOrCreateGame(roomID)
+	relayed, _ := b.GetOrCreateGame(roomID)
+
+	client := &Client{id: players[1], send: make(chan []byte, 16)}
+	relayed.register <- client
+	receiveUpdate(t, client)
+
+	owned.GameState.Lock()
+	owned.GameState.WinnerID = players[0]
+	owned.GameState.Unlock()
+
+	// Any action makes the owner notice the winner
+	sync, _ := json.Marshal(map[string]any{"type": "sync"})
+	relayed.broadcast <- sync
+	if state := receiveUpdate(t, client); state.WinnerID != players[0] {
+		t.Fatalf("last update has winner %q, want %s", state.WinnerID, players[0])
+	}
+
+	for _, game := range []*Game{owned, relayed} {
+		select {
+		case <-game.Done():
+		case <-time.After(2 * time.Second):
+			t.Fatal("game still running after it ended")
+		}
+	}
+	select {
+	case _, open := <-client.send:
+		if open {
+			t.Error("client got an update after the last one")
+		}
+	case <-time.After(time.Second):
+		t.Error("client still connected after the game closed")
+	}
+
+	for _, manager := range []*GameManager{a, b} {
+		manager.RLock()
+		_, kept := manager.games[roomID]
+		manager.RUnlock()
+		if kept {
+			t.Errorf("instance %s kept the closed game", manager.cluster.Node)
+		}
+		if _, err := manager.GetOrCreateGame(roomID); !errors.Is(err, ErrGameOver) {
+			t.Errorf("GetOrCreateGame() of the ended game error = %v, want %v", err, ErrGameOver)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		released, _ := a.cluster.Leases.Acquire(gameLeaseKey(roomID), "c", gameLeaseTTL)
+		if released {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("lease of the ended game was not released")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestGameManager_GetOrCreateGameRejectsUnknownGames(t *testing.T) {
+	a, _, _, _ := newTestReplicas(t)
+
+	waiting, _ := room.NewRoom("Waiting", uuid.New(), "Owner", room.DefaultSettings())
+	a.rooms.Create(waiting)
+
+	for _, id := range []string{uuid.NewString(), "not-a-room", waiting.RoomID.String()} {
+		if _, err := a.GetOrCreateGame(id); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("GetOrCreateGame(%q) error = %v, want %v", id, err, repository.ErrNotFound)
+		}
+	}
+	if len(a.games) != 0 {
+		t.Errorf("%d games kept for unknown rooms", len(a.games))
+	}
+}
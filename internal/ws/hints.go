@@ -0,0 +1,62 @@
+package ws
+
+// The phases of a turn as hinted to clients, the server does not enforce them
+const (
+	PhaseDeploy = "deploy" // Trading cards and placing armies
+	PhaseAttack = "attack" // Attacking and then moving armies
+)
+
+// TurnHints lists what the current player can do, so clients highlight it
+// instead of checking the rules themselves. A turn is in the deploy phase
+// while the player has armies to place, then in the attack phase, and only
+// the actions of the current phase are listed.
+type TurnHints struct {
+	Phase     string      `json:"phase"`
+	Deploy    []string    `json:"deploy"`  // Territories to place armies on
+	Attacks   []BotAttack `json:"attacks"` // Armies is the most dice
+	Moves     []BotMove   `json:"moves"`   // Armies is the most that can move
+	Trades    [][]string  `json:"trades"`  // Card names of each set that can be traded
+	MustTrade bool        `json:"must_trade"`
+}
+
+// turnHintsLocked returns the hints of the current player, nil when no one
+// can act. The caller holds the read lock.
+func (gs *GameState) turnHintsLocked() *TurnHints {
+	if gs.WinnerID != "" || gs.Paused || gs.Players[gs.CurrentTurn] == nil {
+		return nil
+	}
+
+	v := newBotView(gs, gs.CurrentTurn)
+	hints := &TurnHints{
+		Deploy:    []string{},
+		Attacks:   []BotAttack{},
+		Moves:     []BotMove{},
+		Trades:    [][]string{},
+		MustTrade: v.MustTrade(),
+	}
+	if v.Armies > 0 {
+		hints.Phase = PhaseDeploy
+		for _, a := range LegalActions(DecideDeploy, v, nil) {
+			hints.Deploy = append(hints.Deploy, a.TerritoryID)
+		}
+		for _, a := range LegalActions(DecideTrade, v, nil) {
+			if !a.Pass {
+				hints.Trades = append(hints.Trades, a.Cards)
+			}
+		}
+		return hints
+	}
+
+	hints.Phase = PhaseAttack
+	for _, a := range LegalActions(DecideAttack, v, nil) {
+		if !a.Pass {
+			hints.Attacks = append(hints.Attacks, BotAttack{From: a.From, To: a.To, Armies: a.Armies})
+		}
+	}
+	for _, a := range LegalActions(DecideFortify, v, nil) {
+		if !a.Pass {
+			hints.Moves = append(hints.Moves, BotMove{From: a.From, To: a.To, Armies: a.Armies})
+		}
+	}
+	return hints
+}
@@ -0,0 +1,91 @@
+package ws
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/repository"
+)
+
+func TestGameState_TurnHints(t *testing.T) {
+	state := NewSeededGame(1, room.DefaultSettings(), []room.BotDifficulty{"", room.BotEasy})
+	playerID := state.CurrentTurn
+	v := state.BotView(playerID)
+	v.Territory(v.Owned()[0].ID).Armies = 5
+	state.Territories = v.Territories
+
+	state.RLock()
+	hints := state.turnHintsLocked()
+	state.RUnlock()
+
+	// Armies are placed first, attacks and moves wait for the attack phase
+	if hints.Phase != PhaseDeploy || len(hints.Deploy) != len(v.Owned()) {
+		t.Errorf("%s phase with %d deploy targets, want the deploy phase and the %d owned territories",
+			hints.Phase, len(hints.Deploy), len(v.Owned()))
+	}
+	if len(hints.Attacks) != 0 || len(hints.Moves) != 0 || len(hints.Trades) != 0 || hints.MustTrade {
+		t.Errorf("deploy hints = %+v, want no attacks, moves or trades", hints)
+	}
+
+	state.Players[playerID].Armies = 0
+	hints = state.turnHintsLocked()
+	if hints.Phase != PhaseAttack || len(hints.Deploy) != 0 || len(hints.Attacks) == 0 {
+		t.Errorf("hints = %+v, want the attack phase with attacks and nothing to deploy", hints)
+	}
+	for _, a := range hints.Attacks {
+		from, to := v.Territory(a.From), v.Territory(a.To)
+		if from.Owner != playerID || to.Owner == playerID || !slices.Contains(from.Adjacent, to.ID) || a.Armies != min(from.Armies-1, 3) {
+			t.Errorf("attack %+v is not legal", a)
+		}
+	}
+	for _, m := range hints.Moves {
+		if from := v.Territory(m.From); v.Territory(m.To).Owner != playerID || m.Armies != from.Armies-1 {
+			t.Errorf("move %+v is not legal", m)
+		}
+	}
+
+	state.WinnerID = playerID
+	if hints := state.turnHintsLocked(); hints != nil {
+		t.Errorf("hints after the game ended = %+v, want nil", hints)
+	}
+}
+
+func TestGame_BroadcastGameStateHintsTheCurrentPlayer(t *testing.T) {
+	state := NewSeededGame(1, room.DefaultSettings(), []room.BotDifficulty{"", ""})
+	manager := NewGameManager(repository.NewMemoryRoomRepository(), repository.NewMemoryGameRepository(), nil, NewLocalCluster())
+	game := manager.newGame(state.RoomID, state)
+
+	sub, err := game.cluster.Bus.Subscribe(gameOutboxTopic(game.ID))
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	t.Cleanup(func() { sub.Close() })
+
+	game.broadcastGameState()
+
+	hinted := map[string]bool{}
+	for range 2 {
+		select {
+		case payload := <-sub.Messages():
+			var update gameUpdate
+			json.Unmarshal(payload, &update)
+			var msg struct {
+				LegalActions *TurnHints `json:"legalActions"`
+			}
+			json.Unmarshal(update.Data, &msg)
+			for _, id := range update.To {
+				hinted[id] = msg.LegalActions != nil
+			}
+		case <-time.After(time.Second):
+			t.Fatal("missing game update")
+		}
+	}
+
+	current, other := state.TurnOrder[0], state.TurnOrder[1]
+	if !hinted[current] || hinted[other] {
+		t.Errorf("hinted players = %v, want only the current player %s", hinted, current)
+	}
+}
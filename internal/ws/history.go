@@ -0,0 +1,145 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"time"
+
+	"es2.uff/war-server/internal/repository"
+)
+
+// ErrGameInProgress is returned when asking for the events of a game that
+// has not finished yet
+var ErrGameInProgress = errors.New("game is still in progress")
+
+// checkGameOver wraps up a game the first time a winner is found: the result
+// is logged, the match is added to the history and its snapshot is dropped
+func (g *Game) checkGameOver() {
+	if g.finished {
+		return
+	}
+
+	g.GameState.RLock()
+	winner := g.GameState.Players[g.GameState.WinnerID]
+	imported := g.GameState.Imported
+	g.GameState.RUnlock()
+
+	if winner == nil {
+		return
+	}
+
+	g.finished = true
+	g.cancel()
+	if g.turnTimer != nil {
+		g.turnTimer.Stop()
+		g.turnTimer = nil
+	}
+
+	g.log = append(g.log, Gamelog{
+		Timestamp: time.Now(),
+		Message:   fmt.Sprintf("%s venceu a partida!", winner.Username),
+	})
+
+	// Imported positions may seat anyone, so they count for no one's record
+	if g.history != nil && !imported {
+		if err := g.history.RecordMatch(g.matchRecord()); err != nil {
+			log.Printf("Error recording match of game %s: %v", g.ID, err)
+		}
+	}
+
+	if g.archive != nil {
+		if err := g.archive.DeleteGame(g.ID); err != nil {
+			log.Printf("Error deleting snapshot of game %s: %v", g.ID, err)
+		}
+	}
+}
+
+func (g *Game) matchRecord() *repository.MatchRecord {
+	g.GameState.RLock()
+	defer g.GameState.RUnlock()
+
+	// The match keeps the game's ID so its events can be looked up by it
+	m := &repository.MatchRecord{
+		ID:        g.ID,
+		RoomID:    g.GameState.RoomID,
+		WinnerID:  g.GameState.WinnerID,
+		StartedAt: g.GameState.StartedAt,
+	}
+
+	if g.GameState.FinishedAt != nil {
+		m.FinishedAt = *g.GameState.FinishedAt
+	}
+
+	for _, playerID := range g.GameState.turnOrderLocked() {
+		p := g.GameState.Players[playerID]
+		participant := repository.MatchParticipant{
+			PlayerID:    p.ID,
+			Name:        p.Username,
+			Color:       p.Color,
+			IsBot:       p.IsBot,
+			ObjectiveID: p.ObjectiveID,
+			Objective:   p.ObjectiveDesc,
+			Won:         p.ID == g.GameState.WinnerID,
+		}
+
+		if stats := g.GameState.Stats[playerID]; stats != nil {
+			participant.MatchStats = repository.MatchStats{
+				ArmiesDestroyed: stats.ArmiesDestroyed,
+				ArmiesLost:      stats.ArmiesLost,
+				Conquests:       stats.Conquests,
+				Eliminations:    stats.Eliminations,
+				Trades:          stats.Trades,
+			}
+			participant.RegionConquests = maps.Clone(stats.RegionConquests)
+		}
+
+		m.Participants = append(m.Participants, participant)
+	}
+
+	events, err := json.Marshal(g.GameState.Events)
+	if err != nil {
+		log.Printf("Error marshaling events of game %s: %v", g.ID, err)
+	}
+	m.Events = events
+
+	for _, entry := range g.log {
+		m.Log = append(m.Log, repository.MatchLogEntry{
+			Message:   entry.Message,
+			Timestamp: entry.Timestamp,
+		})
+	}
+
+	return m
+}
+
+// GameEvents returns the events of a finished game. Those of a running game
+// are withheld since they reveal the deck order and every objective.
+func (gm *GameManager) GameEvents(id string) ([]GameEvent, error) {
+	if gm.history != nil {
+		m, err := gm.history.GetMatch(id)
+		if err == nil {
+			events := []GameEvent{}
+			if len(m.Events) == 0 {
+				return events, nil
+			}
+			if err := json.Unmarshal(m.Events, &events); err != nil {
+				return nil, fmt.Errorf("decoding events of game %s: %w", id, err)
+			}
+			return events, nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	if gm.archive != nil {
+		if _, err := gm.archive.GetGame(id); err == nil {
+			return nil, ErrGameInProgress
+		}
+	}
+
+	return nil, repository.ErrNotFound
+}
@@ -0,0 +1,57 @@
+package ws
+
+import (
+	"errors"
+	"testing"
+
+	"es2.uff/war-server/internal/repository"
+)
+
+func TestGame_CheckGameOver_RecordsMatch(t *testing.T) {
+	archive := repository.NewMemoryGameRepository()
+	history := repository.NewMemoryHistoryRepository()
+	manager := NewGameManager(repository.NewMemoryRoomRepository(), archive, history, NewLocalCluster())
+
+	state := NewGameState("room-1")
+	state.Players["human"] = &Player{ID: "human", Username: "Alice"}
+	state.Players["bot"] = &Player{ID: "bot", Username: "Bot 1", IsBot: true}
+	state.TurnOrder = []string{"human", "bot"}
+	state.CurrentTurn = "human"
+
+	game := manager.newGame("room-1", state)
+	game.persist()
+
+	if _, err := manager.GameEvents("room-1"); !errors.Is(err, ErrGameInProgress) {
+		t.Errorf("GameEvents() of a running game error = %v, want %v", err, ErrGameInProgress)
+	}
+
+	state.Territories = []*Territory{{ID: "t1", Owner: "human", Armies: 1}}
+	state.Players["human"].Armies = 1
+	if err := state.Deploy("human", "t1"); err != nil {
+		t.Fatalf("Deploy() error = %v", err)
+	}
+
+	game.checkGameOver()
+	game.checkGameOver()
+
+	matches, _ := history.ListPlayerMatches("human", 0)
+	if len(matches) != 1 {
+		t.Fatalf("recorded %d matches, want 1", len(matches))
+	}
+	if matches[0].WinnerID != "human" || len(matches[0].Participants) != 2 {
+		t.Errorf("recorded match = %+v, want human winning against the bot", matches[0])
+	}
+
+	if profile, err := history.GetProfile("human"); err != nil || profile.Wins != 1 {
+		t.Errorf("GetProfile() = %+v, %v, want one win", profile, err)
+	}
+
+	if ids, _ := archive.ListGames(); len(ids) != 0 {
+		t.Errorf("ListGames() = %v, want the finished game's snapshot removed", ids)
+	}
+
+	events, err := manager.GameEvents("room-1")
+	if err != nil || len(events) != 1 || events[0].Type != EventDeployed {
+		t.Errorf("GameEvents() = %+v, %v, want the winning deploy", events, err)
+	}
+}
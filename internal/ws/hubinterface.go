@@ -5,4 +5,6 @@ type HubInterface interface {
 	GetRegisterChan() chan *Client
 	GetUnregisterChan() chan *Client
 	GetBroadcastChan() chan []byte
+	// Done is closed when the hub stops running
+	Done() <-chan struct{}
 }
@@ -0,0 +1,282 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"es2.uff/war-server/internal/bus"
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/repository"
+)
+
+const (
+	LobbyRoomCreated = "room_created"
+	LobbyRoomUpdated = "room_updated"
+	LobbyRoomStarted = "room_started"
+	LobbyRoomDeleted = "room_deleted"
+
+	// Sent instead of an update when a room stops matching a client's filter
+	LobbyRoomRemoved = "room_removed"
+)
+
+type RoomSummary struct {
+	RoomID      string            `json:"room_id"`
+	RoomName    string            `json:"room_name"`
+	OwnerID     string            `json:"owner_id"`
+	OwnerName   string            `json:"owner_name"`
+	PlayerCount int               `json:"player_count"`
+	MaxPlayers  int               `json:"max_players"`
+	Started     bool              `json:"started"`
+	HasPassword bool              `json:"has_password"`
+	Settings    room.RoomSettings `json:"settings"`
+}
+
+func NewRoomSummary(r *room.Room) RoomSummary {
+	return RoomSummary{
+		RoomID:      r.RoomID.String(),
+		RoomName:    r.Name,
+		OwnerID:     r.OwnerID.String(),
+		OwnerName:   r.OwnerName,
+		PlayerCount: r.PlayerCount,
+		MaxPlayers:  r.MaxPlayers,
+		Started:     r.Started,
+		HasPassword: r.HasPassword(),
+		Settings:    r.Settings,
+	}
+}
+
+// LobbyFilter selects which rooms a lobby client is told about
+type LobbyFilter struct {
+	FreeSeat   bool   `json:"free_seat"`
+	NotStarted bool   `json:"not_started"`
+	Query      string `json:"query"`
+}
+
+// NewLobbyFilter reads a filter from the query parameters of a lobby connection
+func NewLobbyFilter(r *http.Request) LobbyFilter {
+	q := r.URL.Query()
+	return LobbyFilter{
+		FreeSeat:   q.Get("free_seat") == "true",
+		NotStarted: q.Get("not_started") == "true",
+		Query:      q.Get("q"),
+	}
+}
+
+func (f LobbyFilter) Matches(summary RoomSummary) bool {
+	if f.FreeSeat && summary.PlayerCount >= summary.MaxPlayers {
+		return false
+	}
+
+	if f.NotStarted && summary.Started {
+		return false
+	}
+
+	query := strings.TrimSpace(f.Query)
+	if query != "" && !strings.Contains(strings.ToLower(summary.RoomName), strings.ToLower(query)) {
+		return false
+	}
+
+	return true
+}
+
+type LobbyEvent struct {
+	Type string      `json:"type"`
+	Room RoomSummary `json:"room"`
+}
+
+// LobbyHub pushes changes of public rooms to every client browsing the lobby,
+// events travel over the bus so clients of every instance receive them
+type LobbyHub struct {
+	rooms      repository.RoomRepository
+	clients    map[*Client]bool
+	bus        bus.Bus
+	events     bus.Subscription
+	broadcast  chan []byte
+	register   chan *Client
+	unregister chan *Client
+	done       chan struct{}
+}
+
+func NewLobbyHub(rooms repository.RoomRepository, b bus.Bus) *LobbyHub {
+	return &LobbyHub{
+		rooms:      rooms,
+		clients:    make(map[*Client]bool),
+		bus:        b,
+		broadcast:  make(chan []byte),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		done:       make(chan struct{}),
+	}
+}
+
+func (l *LobbyHub) subscribe() error {
+	events, err := l.bus.Subscribe(lobbyTopic)
+	if err != nil {
+		return err
+	}
+
+	l.events = events
+	return nil
+}
+
+// Run starts the lobby hub's main loop
+func (l *LobbyHub) Run() {
+	for {
+		select {
+		case client := <-l.register:
+			l.clients[client] = true
+			l.sendSnapshot(client)
+
+		case client := <-l.unregister:
+			if _, ok := l.clients[client]; ok {
+				delete(l.clients, client)
+				close(client.send)
+			}
+
+		case message := <-l.broadcast:
+			l.handleMessage(message)
+
+		case message := <-l.events.Messages():
+			var event LobbyEvent
+			if err := json.Unmarshal(message, &event); err != nil {
+				log.Printf("Error unmarshaling lobby event: %v", err)
+				continue
+			}
+			l.dispatch(event)
+		}
+	}
+}
+
+// Publish queues a room event for the lobby, private rooms are never published
+func (l *LobbyHub) Publish(eventType string, r *room.Room) {
+	if r == nil || r.Private {
+		return
+	}
+
+	data, err := json.Marshal(LobbyEvent{Type: eventType, Room: NewRoomSummary(r)})
+	if err != nil {
+		log.Printf("Error marshaling lobby event: %v", err)
+		return
+	}
+
+	if err := l.bus.Publish(lobbyTopic, data); err != nil {
+		log.Printf("Error publishing lobby event: %v", err)
+	}
+}
+
+// handleMessage lets lobby clients change their filter with a set_filter message
+func (l *LobbyHub) handleMessage(message []byte) {
+	var msg struct {
+		Type     string      `json:"type"`
+		PlayerID string      `json:"player_id"`
+		Filter   LobbyFilter `json:"filter"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		log.Printf("Error unmarshaling lobby message: %v", err)
+		return
+	}
+
+	if msg.Type != "set_filter" {
+		return
+	}
+
+	for client := range l.clients {
+		if client.id == msg.PlayerID {
+			client.filter = msg.Filter
+			l.sendSnapshot(client)
+		}
+	}
+}
+
+func (l *LobbyHub) dispatch(event LobbyEvent) {
+	for client := range l.clients {
+		var data []byte
+		var err error
+
+		switch {
+		case event.Type == LobbyRoomDeleted || client.filter.Matches(event.Room):
+			data, err = json.Marshal(event)
+		case event.Type == LobbyRoomCreated:
+			continue
+		default:
+			data, err = json.Marshal(map[string]any{
+				"type":    LobbyRoomRemoved,
+				"room_id": event.Room.RoomID,
+			})
+		}
+
+		if err != nil {
+			log.Printf("Error marshaling lobby event: %v", err)
+			continue
+		}
+
+		l.send(client, data)
+	}
+}
+
+// sendSnapshot sends every public room matching the client's filter
+func (l *LobbyHub) sendSnapshot(client *Client) {
+	rooms, err := repository.ListPublicRooms(l.rooms)
+	if err != nil {
+		log.Printf("Error listing rooms for lobby: %v", err)
+		return
+	}
+
+	summaries := make([]RoomSummary, 0, len(rooms))
+	for _, r := range rooms {
+		summary := NewRoomSummary(r)
+		if client.filter.Matches(summary) {
+			summaries = append(summaries, summary)
+		}
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"type":  "lobby_snapshot",
+		"rooms": summaries,
+	})
+	if err != nil {
+		log.Printf("Error marshaling lobby snapshot: %v", err)
+		return
+	}
+
+	l.send(client, data)
+}
+
+func (l *LobbyHub) send(client *Client, data []byte) {
+	select {
+	case client.send <- data:
+	default:
+		close(client.send)
+		delete(l.clients, client)
+	}
+}
+
+// ServeLobbyWs connects a player to the lobby feed with an initial filter
+func ServeLobbyWs(lobby *LobbyHub, w http.ResponseWriter, r *http.Request, p *player.Player, filter LobbyFilter) error {
+	client, err := newClient(lobby, w, r, p)
+	if err != nil {
+		return err
+	}
+
+	client.filter = filter
+	return client.start()
+}
+
+func (l *LobbyHub) GetRegisterChan() chan *Client {
+	return l.register
+}
+
+func (l *LobbyHub) GetUnregisterChan() chan *Client {
+	return l.unregister
+}
+
+func (l *LobbyHub) GetBroadcastChan() chan []byte {
+	return l.broadcast
+}
+
+func (l *LobbyHub) Done() <-chan struct{} {
+	return l.done
+}
@@ -0,0 +1,77 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"es2.uff/war-server/internal/bus"
+	"es2.uff/war-server/internal/repository"
+)
+
+func TestLobbyFilter_Matches(t *testing.T) {
+	summary := RoomSummary{
+		RoomName:    "Partida dos Amigos",
+		PlayerCount: 3,
+		MaxPlayers:  4,
+	}
+
+	tests := []struct {
+		name   string
+		filter LobbyFilter
+		modify func(s *RoomSummary)
+		want   bool
+	}{
+		{"Empty filter", LobbyFilter{}, nil, true},
+		{"Free seat", LobbyFilter{FreeSeat: true}, nil, true},
+		{"Full room", LobbyFilter{FreeSeat: true}, func(s *RoomSummary) { s.PlayerCount = 4 }, false},
+		{"Not started", LobbyFilter{NotStarted: true}, nil, true},
+		{"Started room", LobbyFilter{NotStarted: true}, func(s *RoomSummary) { s.Started = true }, false},
+		{"Name search ignores case", LobbyFilter{Query: "amigos"}, nil, true},
+		{"Name search miss", LobbyFilter{Query: "torneio"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := summary
+			if tt.modify != nil {
+				tt.modify(&s)
+			}
+
+			if got := tt.filter.Matches(s); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLobbyHub_Dispatch(t *testing.T) {
+	lobby := NewLobbyHub(repository.NewMemoryRoomRepository(), bus.NewMemoryBus())
+
+	everything := &Client{id: "a", send: make(chan []byte, 10)}
+	freeSeat := &Client{id: "b", send: make(chan []byte, 10), filter: LobbyFilter{FreeSeat: true}}
+	lobby.clients[everything] = true
+	lobby.clients[freeSeat] = true
+
+	fullRoom := RoomSummary{RoomID: "room-1", PlayerCount: 2, MaxPlayers: 2}
+
+	lobby.dispatch(LobbyEvent{Type: LobbyRoomCreated, Room: fullRoom})
+	if len(everything.send) != 1 {
+		t.Errorf("client without filter received %d messages, want 1", len(everything.send))
+	}
+	if len(freeSeat.send) != 0 {
+		t.Errorf("client filtering free seats received %d messages for a full room, want 0", len(freeSeat.send))
+	}
+
+	lobby.dispatch(LobbyEvent{Type: LobbyRoomUpdated, Room: fullRoom})
+	if len(freeSeat.send) != 1 {
+		t.Fatalf("client filtering free seats received %d messages, want 1", len(freeSeat.send))
+	}
+
+	var msg map[string]any
+	if err := json.Unmarshal(<-freeSeat.send, &msg); err != nil {
+		t.Fatalf("invalid lobby message: %v", err)
+	}
+	if msg["type"] != LobbyRoomRemoved || msg["room_id"] != "room-1" {
+		t.Errorf("update not matching the filter sent %v, want %s for room-1", msg, LobbyRoomRemoved)
+	}
+}
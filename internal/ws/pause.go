@@ -0,0 +1,235 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// Pause stops the game when its owner asks, or once most human players have
+// asked. Until then the request counts as a vote. It reports whether the
+// game was paused.
+func (gs *GameState) Pause(playerID string, resumeAt *time.Time) (bool, error) {
+	gs.Lock()
+	defer gs.Unlock()
+
+	if gs.WinnerID != "" {
+		return false, fmt.Errorf("game is over")
+	}
+	if gs.Paused {
+		return false, fmt.Errorf("game is already paused")
+	}
+	if decided, err := gs.voteLocked(playerID); !decided {
+		return false, err
+	}
+
+	gs.Paused = true
+	gs.ResumeAt = resumeAt
+	if gs.TurnDeadline != nil {
+		gs.TurnTimeLeft = max(time.Until(*gs.TurnDeadline), 0)
+		gs.TurnDeadline = nil
+	}
+
+	gs.recordLocked(GameEvent{Type: EventPaused, PlayerID: playerID})
+	return true, nil
+}
+
+// Resume continues a paused game, asked by its owner or by most human
+// players like Pause. It reports whether the game was resumed.
+func (gs *GameState) Resume(playerID string) (bool, error) {
+	gs.Lock()
+	defer gs.Unlock()
+
+	if !gs.Paused {
+		return false, fmt.Errorf("game is not paused")
+	}
+	if decided, err := gs.voteLocked(playerID); !decided {
+		return false, err
+	}
+
+	gs.resumeLocked(playerID)
+	return true, nil
+}
+
+// ResumeScheduled continues a game paused until at, unless it was resumed
+// or paused again with another date since
+func (gs *GameState) ResumeScheduled(at time.Time) bool {
+	gs.Lock()
+	defer gs.Unlock()
+
+	if !gs.Paused || gs.ResumeAt == nil || !gs.ResumeAt.Equal(at) {
+		return false
+	}
+
+	gs.resumeLocked("")
+	return true
+}
+
+func (gs *GameState) resumeLocked(playerID string) {
+	gs.Paused = false
+	gs.PauseVotes = nil
+	gs.ResumeAt = nil
+	gs.recordLocked(GameEvent{Type: EventResumed, PlayerID: playerID})
+}
+
+// voteLocked counts the player's request to pause or resume, reporting
+// whether it is enough to go ahead. The owner decides alone, otherwise a
+// majority of the human players is needed.
+func (gs *GameState) voteLocked(playerID string) (bool, error) {
+	p := gs.Players[playerID]
+	if p == nil || p.IsBot {
+		return false, fmt.Errorf("only players can pause or resume the game")
+	}
+
+	if playerID == gs.OwnerID {
+		gs.PauseVotes = nil
+		return true, nil
+	}
+
+	if !slices.Contains(gs.PauseVotes, playerID) {
+		gs.PauseVotes = append(gs.PauseVotes, playerID)
+	}
+
+	humans := 0
+	for _, p := range gs.Players {
+		if !p.IsBot {
+			humans++
+		}
+	}
+
+	if len(gs.PauseVotes)*2 <= humans {
+		return false, nil
+	}
+
+	gs.PauseVotes = nil
+	return true, nil
+}
+
+// handlePause runs a pause request, resume_at optionally schedules when the
+// game continues on its own
+func (g *Game) handlePause(playerID string, msg map[string]any) {
+	var resumeAt *time.Time
+	if raw, ok := msg["resume_at"].(string); ok && raw != "" {
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil || !at.After(time.Now()) {
+			log.Printf("Invalid resume time %q for game %s", raw, g.ID)
+			return
+		}
+		resumeAt = &at
+	}
+
+	paused, err := g.GameState.Pause(playerID, resumeAt)
+	if err != nil {
+		log.Printf("Error processing pause: %v", err)
+		return
+	}
+
+	playerName := g.GameState.Players[playerID].Username
+	if !paused {
+		g.appendLog(fmt.Sprintf("%s pediu para pausar a partida.", playerName))
+		return
+	}
+
+	if g.turnTimer != nil {
+		g.turnTimer.Stop()
+		g.turnTimer = nil
+	}
+	g.stopBotTurn()
+	g.scheduleResume()
+
+	if resumeAt != nil {
+		g.appendLog(fmt.Sprintf("A partida foi pausada até %s.", resumeAt.Format("02/01/2006 15:04")))
+	} else {
+		g.appendLog("A partida foi pausada.")
+	}
+}
+
+func (g *Game) handleResume(playerID string) {
+	resumed, err := g.GameState.Resume(playerID)
+	if err != nil {
+		log.Printf("Error processing resume: %v", err)
+		return
+	}
+
+	playerName := g.GameState.Players[playerID].Username
+	if !resumed {
+		g.appendLog(fmt.Sprintf("%s pediu para retomar a partida.", playerName))
+		return
+	}
+
+	g.appendLog("A partida foi retomada.")
+	g.continueGame()
+}
+
+func (g *Game) handleScheduledResume(msg map[string]any) {
+	raw, _ := msg["resume_at"].(string)
+	at, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil || time.Now().Before(at) || !g.GameState.ResumeScheduled(at) {
+		return
+	}
+
+	g.appendLog("A partida foi retomada no horário agendado.")
+	g.continueGame()
+}
+
+// continueGame restarts the turn timer where it stopped and lets a bot play
+// the turn it was interrupted in
+func (g *Game) continueGame() {
+	if g.resumeTimer != nil {
+		g.resumeTimer.Stop()
+		g.resumeTimer = nil
+	}
+
+	g.resetTurnTimer()
+
+	g.GameState.RLock()
+	current := g.GameState.Players[g.GameState.CurrentTurn]
+	g.GameState.RUnlock()
+
+	if current != nil && current.IsBot {
+		g.startBotTurn(current.ID, 0)
+	}
+}
+
+// scheduleResume sets a timer for a paused game that has a resume date. The
+// resume is queued on the game's loop like any other action.
+func (g *Game) scheduleResume() {
+	if g.resumeTimer != nil {
+		g.resumeTimer.Stop()
+		g.resumeTimer = nil
+	}
+
+	g.GameState.RLock()
+	paused, resumeAt := g.GameState.Paused, g.GameState.ResumeAt
+	g.GameState.RUnlock()
+
+	if !paused || resumeAt == nil {
+		return
+	}
+
+	at := *resumeAt
+	g.resumeTimer = time.AfterFunc(time.Until(at), func() {
+		msg, err := json.Marshal(map[string]any{
+			"type":      "scheduled_resume",
+			"resume_at": at.Format(time.RFC3339Nano),
+		})
+		if err != nil {
+			log.Printf("Error marshaling scheduled resume: %v", err)
+			return
+		}
+
+		select {
+		case g.broadcast <- msg:
+		case <-g.done:
+		}
+	})
+}
+
+func (g *Game) appendLog(message string) {
+	g.log = append(g.log, Gamelog{
+		Timestamp: time.Now(),
+		Message:   message,
+	})
+}
@@ -0,0 +1,138 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/repository"
+)
+
+func newPausableState() *GameState {
+	state := NewGameState("room-1")
+	state.Settings = room.DefaultSettings()
+	state.Settings.TurnTimer = 60
+	state.OwnerID = "owner"
+	for _, id := range []string{"owner", "alice", "bob"} {
+		state.Players[id] = &Player{ID: id, Username: id}
+	}
+	state.Players["bot"] = &Player{ID: "bot", Username: "Bot 1", IsBot: true}
+	state.TurnOrder = []string{"owner", "alice", "bob", "bot"}
+	state.CurrentTurn = "alice"
+	state.TurnNumber = 1
+	return state
+}
+
+func TestGameState_Pause(t *testing.T) {
+	state := newPausableState()
+
+	if _, err := state.Pause("bot", nil); err == nil {
+		t.Error("Pause() by a bot error = nil, want an error")
+	}
+
+	if paused, err := state.Pause("owner", nil); err != nil || !paused {
+		t.Fatalf("Pause() by the owner = %v, %v, want the game paused", paused, err)
+	}
+	if _, err := state.NextTurn("alice"); err == nil {
+		t.Error("NextTurn() while paused error = nil, want an error")
+	}
+
+	// Two of the three humans make a majority
+	if resumed, err := state.Resume("alice"); err != nil || resumed {
+		t.Fatalf("Resume() by one vote = %v, %v, want the vote counted only", resumed, err)
+	}
+	if resumed, _ := state.Resume("alice"); resumed {
+		t.Fatal("Resume() counted the same vote twice")
+	}
+	if resumed, err := state.Resume("bob"); err != nil || !resumed {
+		t.Fatalf("Resume() by a majority = %v, %v, want the game resumed", resumed, err)
+	}
+
+	if _, err := state.NextTurn("alice"); err != nil {
+		t.Errorf("NextTurn() after resuming error = %v", err)
+	}
+
+	types := make([]GameEventType, 0, len(state.Events))
+	for _, e := range state.Events {
+		types = append(types, e.Type)
+	}
+	if len(types) != 3 || types[0] != EventPaused || types[1] != EventResumed {
+		t.Errorf("recorded events %v, want paused, resumed and turn_ended", types)
+	}
+}
+
+func TestGame_PauseFreezesTurnTimer(t *testing.T) {
+	manager := NewGameManager(repository.NewMemoryRoomRepository(), repository.NewMemoryGameRepository(), nil, NewLocalCluster())
+	game := manager.newGame("room-1", newPausableState())
+	game.resetTurnTimer()
+
+	game.handleMessage([]byte(`{"type":"pause","player_id":"owner"}`))
+
+	if game.turnTimer != nil || game.GameState.TurnDeadline != nil {
+		t.Error("turn timer still running while paused")
+	}
+	if left := game.GameState.TurnTimeLeft; left < 59*time.Second || left > 60*time.Second {
+		t.Errorf("TurnTimeLeft = %v, want about a minute", left)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	game.handleMessage([]byte(`{"type":"resume","player_id":"owner"}`))
+
+	if game.turnTimer == nil || game.GameState.TurnDeadline == nil {
+		t.Fatal("turn timer not restarted after resuming")
+	}
+	if left := time.Until(*game.GameState.TurnDeadline); left < 59*time.Second || left > 60*time.Second {
+		t.Errorf("turn deadline in %v, want the minute that was left", left)
+	}
+	game.turnTimer.Stop()
+}
+
+// A paused game stays paused across a restart and still resumes on schedule
+func TestGameManager_RestoresPausedGame(t *testing.T) {
+	archive := repository.NewMemoryGameRepository()
+
+	state := newPausableState()
+	resumeAt := time.Now().Add(time.Second)
+	if _, err := state.Pause("owner", &resumeAt); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+
+	manager := NewGameManager(repository.NewMemoryRoomRepository(), archive, nil, NewLocalCluster())
+	manager.newGame("room-1", state).persist()
+
+	restored := NewGameManager(repository.NewMemoryRoomRepository(), archive, nil, NewLocalCluster())
+	if err := restored.RestoreGames(); err != nil {
+		t.Fatalf("RestoreGames() error = %v", err)
+	}
+	game, err := restored.GetOrCreateGame("room-1")
+	if err != nil {
+		t.Fatalf("GetOrCreateGame() error = %v", err)
+	}
+
+	client := &Client{id: "alice", send: make(chan []byte, 16)}
+	game.register <- client
+
+	got := receiveUpdate(t, client)
+	if !got.Paused || got.TurnDeadline != nil {
+		t.Fatalf("restored game paused = %v with deadline %v, want it paused without a deadline", got.Paused, got.TurnDeadline)
+	}
+
+	deadline := time.After(3 * time.Second)
+	for {
+		select {
+		case data := <-client.send:
+			var msg struct {
+				GameState *GameState `json:"gameState"`
+			}
+			if err := json.Unmarshal(data, &msg); err == nil && msg.GameState != nil && !msg.GameState.Paused {
+				if msg.GameState.TurnDeadline == nil {
+					t.Error("resumed game has no turn deadline")
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("game was not resumed at the scheduled time")
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"es2.uff/war-server/internal/domain/player"
+)
+
+const (
+	// The wait between two events is kept within these bounds: long thinking
+	// pauses are not worth sitting through again, and bursts of bot moves
+	// would otherwise arrive all at once
+	minReplayDelay = 100 * time.Millisecond
+	maxReplayDelay = 5 * time.Second
+	minReplaySpeed = 0.25
+	maxReplaySpeed = 16
+)
+
+// ReplayHub streams the events of a finished game to one spectator, keeping
+// the pacing of the original game scaled by the chosen speed. Spectators can
+// send set_speed, pause and resume messages.
+type ReplayHub struct {
+	gameID     string
+	events     []GameEvent
+	state      *GameState
+	next       int
+	speed      float64
+	paused     bool
+	client     *Client
+	timer      *time.Timer
+	broadcast  chan []byte
+	register   chan *Client
+	unregister chan *Client
+	done       chan struct{}
+}
+
+func NewReplayHub(gameID string, events []GameEvent, speed float64) *ReplayHub {
+	return &ReplayHub{
+		gameID:     gameID,
+		events:     events,
+		state:      NewGameState(gameID),
+		speed:      clampReplaySpeed(speed),
+		broadcast:  make(chan []byte),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		done:       make(chan struct{}),
+	}
+}
+
+// ValidReplaySpeed reports whether speed is a finite, positive replay speed
+func ValidReplaySpeed(speed float64) bool {
+	return speed > 0 && !math.IsInf(speed, 1)
+}
+
+// clampReplaySpeed keeps a valid speed within bounds, invalid speeds replay
+// the game at its original pace
+func clampReplaySpeed(speed float64) float64 {
+	if !ValidReplaySpeed(speed) {
+		return 1
+	}
+	return min(max(speed, minReplaySpeed), maxReplaySpeed)
+}
+
+// ServeReplayWs upgrades the request of a spectator and starts streaming the
+// replay to it
+func ServeReplayWs(hub *ReplayHub, w http.ResponseWriter, r *http.Request, p *player.Player) error {
+	client, err := newClient(hub, w, r, p)
+	if err != nil {
+		return err
+	}
+
+	go hub.Run()
+	return client.start()
+}
+
+// Run streams the replay until its spectator leaves
+func (h *ReplayHub) Run() {
+	defer close(h.done)
+
+	for {
+		var tick <-chan time.Time
+		if h.timer != nil {
+			tick = h.timer.C
+		}
+
+		select {
+		case client := <-h.register:
+			h.client = client
+			if len(h.events) == 0 {
+				h.send(map[string]any{"type": "replay_finished"})
+			}
+			h.schedule(0)
+
+		case client := <-h.unregister:
+			if client == h.client {
+				h.stopTimer()
+				close(client.send)
+				return
+			}
+
+		case message := <-h.broadcast:
+			h.handleMessage(message)
+
+		case <-tick:
+			h.timer = nil
+			h.step()
+		}
+	}
+}
+
+func (h *ReplayHub) handleMessage(message []byte) {
+	var msg struct {
+		Type  string  `json:"type"`
+		Speed float64 `json:"speed"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		log.Printf("Error unmarshaling replay message: %v", err)
+		return
+	}
+
+	switch msg.Type {
+	case "set_speed":
+		if !ValidReplaySpeed(msg.Speed) {
+			log.Printf("Invalid replay speed %v for game %s", msg.Speed, h.gameID)
+			return
+		}
+		h.speed = clampReplaySpeed(msg.Speed)
+		if h.timer != nil {
+			h.stopTimer()
+			h.schedule(h.delay())
+		}
+	case "pause":
+		h.paused = true
+		h.stopTimer()
+	case "resume":
+		if h.paused {
+			h.paused = false
+			h.schedule(0)
+		}
+	}
+}
+
+// step applies the next event and sends it with the resulting state
+func (h *ReplayHub) step() {
+	if h.next >= len(h.events) {
+		return
+	}
+
+	event := h.events[h.next]
+	if err := h.state.ApplyEvent(event); err != nil {
+		log.Printf("Error replaying game %s: %v", h.gameID, err)
+		h.next = len(h.events)
+		h.send(map[string]any{"type": "replay_finished", "error": err.Error()})
+		return
+	}
+	h.next++
+
+	h.state.RLock()
+	h.send(map[string]any{
+		"type":      "replay_event",
+		"event":     event,
+		"gameState": h.state,
+	})
+	h.state.RUnlock()
+
+	if h.next == len(h.events) {
+		h.send(map[string]any{"type": "replay_finished"})
+		return
+	}
+
+	h.schedule(h.delay())
+}
+
+// delay is the wait before the next event, taken from the original game
+func (h *ReplayHub) delay() time.Duration {
+	if h.next == 0 || h.next >= len(h.events) {
+		return 0
+	}
+
+	gap := h.events[h.next].Timestamp.Sub(h.events[h.next-1].Timestamp)
+	gap = min(max(gap, minReplayDelay), maxReplayDelay)
+	return time.Duration(float64(gap) / h.speed)
+}
+
+func (h *ReplayHub) schedule(delay time.Duration) {
+	if h.client == nil || h.paused || h.next >= len(h.events) {
+		return
+	}
+	h.timer = time.NewTimer(delay)
+}
+
+func (h *ReplayHub) stopTimer() {
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+}
+
+func (h *ReplayHub) send(msg map[string]any) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error marshaling replay message: %v", err)
+		return
+	}
+
+	select {
+	case h.client.send <- data:
+	default:
+		log.Printf("Replay spectator %s is not keeping up, dropping message", h.client.id)
+	}
+}
+
+func (h *ReplayHub) GetRegisterChan() chan *Client {
+	return h.register
+}
+
+func (h *ReplayHub) GetUnregisterChan() chan *Client {
+	return h.unregister
+}
+
+func (h *ReplayHub) GetBroadcastChan() chan []byte {
+	return h.broadcast
+}
+
+func (h *ReplayHub) Done() <-chan struct{} {
+	return h.done
+}
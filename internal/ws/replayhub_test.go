@@ -0,0 +1,97 @@
+package ws
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func receiveReplay(t *testing.T, c *Client, within time.Duration) (string, *GameEvent) {
+	t.Helper()
+
+	select {
+	case data := <-c.send:
+		var msg struct {
+			Type  string     `json:"type"`
+			Event *GameEvent `json:"event"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("unexpected message %s: %v", data, err)
+		}
+		return msg.Type, msg.Event
+	case <-time.After(within):
+		t.Fatal("no replay message received")
+		return "", nil
+	}
+}
+
+func TestReplayHub_StreamsEventsAtSpeed(t *testing.T) {
+	events := playTestGame(t).Events[:4]
+
+	// The original game waited a second between each event
+	start := events[0].Timestamp
+	for i := range events {
+		events[i].Timestamp = start.Add(time.Duration(i) * time.Second)
+	}
+
+	hub := NewReplayHub("room-1", events, 10)
+	go hub.Run()
+
+	client := &Client{id: "spectator", send: make(chan []byte, 16)}
+	hub.register <- client
+
+	if kind, event := receiveReplay(t, client, time.Second); kind != "replay_event" || event.Seq != 1 {
+		t.Fatalf("first message = %s, want the first event", kind)
+	}
+
+	began := time.Now()
+	receiveReplay(t, client, time.Second)
+	if elapsed := time.Since(began); elapsed < 50*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Errorf("second event came after %v, want about 100ms at 10x speed", elapsed)
+	}
+
+	hub.broadcast <- []byte(`{"type":"pause"}`)
+	select {
+	case data := <-client.send:
+		t.Fatalf("received %s while paused", data)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	hub.broadcast <- []byte(`{"type":"resume"}`)
+	if _, event := receiveReplay(t, client, time.Second); event.Seq != 3 {
+		t.Errorf("resumed at event %d, want 3", event.Seq)
+	}
+	receiveReplay(t, client, time.Second)
+
+	if kind, _ := receiveReplay(t, client, time.Second); kind != "replay_finished" {
+		t.Errorf("last message = %s, want replay_finished", kind)
+	}
+
+	hub.unregister <- client
+	select {
+	case <-hub.Done():
+	case <-time.After(time.Second):
+		t.Error("hub kept running after its spectator left")
+	}
+}
+
+func TestClampReplaySpeed(t *testing.T) {
+	tests := []struct {
+		speed, want float64
+	}{
+		{0, 1},
+		{2, 2},
+		{0.01, minReplaySpeed},
+		{-3, 1},
+		{math.NaN(), 1},
+		{math.Inf(1), 1},
+		{1000, maxReplaySpeed},
+	}
+
+	for _, tt := range tests {
+		if got := clampReplaySpeed(tt.speed); got != tt.want {
+			t.Errorf("clampReplaySpeed(%v) = %v, want %v", tt.speed, got, tt.want)
+		}
+	}
+}
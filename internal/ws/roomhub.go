@@ -2,33 +2,82 @@ package ws
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"time"
+
+	"es2.uff/war-server/internal/bus"
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/repository"
+	"github.com/google/uuid"
 )
 
+// RoomAbandonGrace is how long a room whose game has not started waits for
+// its players to reconnect once nobody is connected, before it is deleted
+var RoomAbandonGrace = 2 * time.Minute
+
 type RoomHub struct {
-	ID         string
-	clients    map[*Client]bool
-	broadcast  chan []byte
-	register   chan *Client
-	unregister chan *Client
+	ID          string
+	clients     map[*Client]bool
+	broadcast   chan []byte
+	register    chan *Client
+	unregister  chan *Client
+	done        chan struct{}
+	rooms       repository.RoomRepository
+	players     repository.PlayerRepository
+	lobby       *LobbyHub
+	onRoomEmpty func(string)
+	cluster     Cluster
+	events      bus.Subscription
+	remote      map[string][]presence // Clients connected to other instances, by node
+	abandoned   *time.Timer           // Set while nobody is connected to the room
 }
 
-func NewRoomHub(roomID string, onOwnerLeft func(string)) *RoomHub {
+func NewRoomHub(
+	roomID string,
+	rooms repository.RoomRepository,
+	players repository.PlayerRepository,
+	lobby *LobbyHub,
+	cluster Cluster,
+	onRoomEmpty func(string),
+) *RoomHub {
 	return &RoomHub{
-		ID:         roomID,
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+		ID:          roomID,
+		clients:     make(map[*Client]bool),
+		broadcast:   make(chan []byte),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		done:        make(chan struct{}),
+		rooms:       rooms,
+		players:     players,
+		lobby:       lobby,
+		onRoomEmpty: onRoomEmpty,
+		cluster:     cluster,
+		remote:      make(map[string][]presence),
 	}
 }
 
-// Run starts the room hub's main loop
+// Run starts the room hub's main loop, it returns once the room is empty
 func (h *RoomHub) Run() {
+	defer h.events.Close()
+
+	defer h.stopAbandonTimer()
+
 	for {
+		var abandoned <-chan time.Time
+		if h.abandoned != nil {
+			abandoned = h.abandoned.C
+		}
+
 		select {
+		case <-h.done:
+			return
+
 		case client := <-h.register:
 			h.clients[client] = true
+			h.stopAbandonTimer()
 			h.broadcastRoomState()
 
 		case client := <-h.unregister:
@@ -36,17 +85,89 @@ func (h *RoomHub) Run() {
 				delete(h.clients, client)
 				close(client.send)
 				h.broadcastRoomState()
+				h.checkAbandoned()
+			}
+
+		case <-abandoned:
+			h.abandoned = nil
+			if h.isAbandoned() {
+				log.Printf("Nobody came back to room %s, closing it", h.ID)
+				h.close()
+				return
 			}
 
 		case message := <-h.broadcast:
 			shouldBroadcastState := h.handleMessage(message)
+			if h.isClosed() {
+				return
+			}
 			if shouldBroadcastState {
 				h.broadcastRoomState()
 			}
+
+		case message := <-h.events.Messages():
+			h.handleEvent(message)
+			if h.isClosed() {
+				return
+			}
 		}
 	}
 }
 
+// isAbandoned reports whether nobody is connected to the room on any instance
+// while its game has not started yet
+func (h *RoomHub) isAbandoned() bool {
+	if len(h.connected()) > 0 {
+		return false
+	}
+	r := h.getRoom()
+	return r != nil && !r.Started
+}
+
+// checkAbandoned gives the players of an abandoned room RoomAbandonGrace to
+// reconnect before the room is closed
+func (h *RoomHub) checkAbandoned() {
+	if !h.isAbandoned() {
+		h.stopAbandonTimer()
+		return
+	}
+	if h.abandoned == nil {
+		h.abandoned = time.NewTimer(RoomAbandonGrace)
+	}
+}
+
+func (h *RoomHub) stopAbandonTimer() {
+	if h.abandoned != nil {
+		h.abandoned.Stop()
+		h.abandoned = nil
+	}
+}
+
+func (h *RoomHub) isClosed() bool {
+	select {
+	case <-h.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops the hub and lets the room server delete the empty room, the
+// hubs of the room on other instances are told to stop as well
+func (h *RoomHub) close() {
+	h.publishEvent(roomEvent{Kind: roomEventClosed})
+	h.stop()
+
+	if h.onRoomEmpty != nil {
+		h.onRoomEmpty(h.ID)
+	}
+}
+
+func (h *RoomHub) stop() {
+	close(h.done)
+	log.Printf("Room hub %s closed", h.ID)
+}
+
 // handleMessage processes incoming messages for room operations
 // Returns true if room state should be broadcasted after handling
 func (h *RoomHub) handleMessage(message []byte) bool {
@@ -76,29 +197,370 @@ func (h *RoomHub) handleMessage(message []byte) bool {
 		}
 		return true
 
+	case "update_settings":
+		playerID, _ := msg["player_id"].(string)
+		if err := h.updateSettings(playerID, msg["settings"]); err != nil {
+			log.Printf("Error updating settings in room %s: %v", h.ID, err)
+			h.sendError(playerID, err.Error())
+			return false
+		}
+		return true
+
+	case "choose_color":
+		playerID, _ := msg["player_id"].(string)
+		color, _ := msg["color"].(string)
+		if err := h.chooseColor(playerID, color); err != nil {
+			log.Printf("Error choosing color in room %s: %v", h.ID, err)
+			h.sendError(playerID, err.Error())
+			return false
+		}
+		return true
+
+	case "reorder_seats":
+		playerID, _ := msg["player_id"].(string)
+		seats, _ := msg["seats"].([]any)
+		if err := h.reorderSeats(playerID, seats); err != nil {
+			log.Printf("Error reordering seats in room %s: %v", h.ID, err)
+			h.sendError(playerID, err.Error())
+			return false
+		}
+		return true
+
+	case "regenerate_code", "revoke_code":
+		playerID, _ := msg["player_id"].(string)
+		if err := h.updateInviteCode(playerID, msgType == "revoke_code"); err != nil {
+			log.Printf("Error updating invite code in room %s: %v", h.ID, err)
+			h.sendError(playerID, err.Error())
+			return false
+		}
+		return true
+
+	case "leave_room":
+		playerID, _ := msg["player_id"].(string)
+		if err := h.removePlayer(playerID, "left_room", false); err != nil {
+			log.Printf("Error leaving room %s: %v", h.ID, err)
+			h.sendError(playerID, err.Error())
+			return false
+		}
+		return true
+
+	case "kick_player":
+		playerID, _ := msg["player_id"].(string)
+		targetID, _ := msg["target_id"].(string)
+		if err := h.kickPlayer(playerID, targetID); err != nil {
+			log.Printf("Error kicking player from room %s: %v", h.ID, err)
+			h.sendError(playerID, err.Error())
+			return false
+		}
+		return true
+
 	case "start_game":
-		log.Printf("Start game requested in room %s", h.ID)
-		h.broadcastGameStart()
+		playerID, _ := msg["player_id"].(string)
+		if err := h.startGame(playerID); err != nil {
+			log.Printf("Error starting game in room %s: %v", h.ID, err)
+			h.sendError(playerID, err.Error())
+		}
 		return false
 	}
 
 	return false
 }
 
-func (h *RoomHub) broadcastRoomState() {
-	playerList := make([]map[string]any, 0)
+// startGame starts the room's game for its owner and sends everyone to it
+func (h *RoomHub) startGame(playerID string) error {
+	err := h.updateOwnedRoom(playerID, "start the game", func(r *room.Room) error {
+		return r.Start()
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Game started in room %s", h.ID)
+	h.publish(LobbyRoomStarted, h.getRoom())
+	h.broadcastGameStart()
+	h.publishEvent(roomEvent{Kind: roomEventGameStarted})
+	return nil
+}
+
+// updateSettings applies the settings sent by the room owner, fields missing
+// from the payload keep their current values
+func (h *RoomHub) updateSettings(playerID string, payload any) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("invalid settings")
+	}
+
+	var settings room.RoomSettings
+	err = h.updateOwnedRoom(playerID, "change settings", func(r *room.Room) error {
+		settings = r.Settings
+		if err := json.Unmarshal(raw, &settings); err != nil {
+			return fmt.Errorf("invalid settings")
+		}
+		return r.UpdateSettings(settings)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Settings updated in room %s: %+v", h.ID, settings)
+	return nil
+}
+
+func (h *RoomHub) chooseColor(playerID string, color string) error {
+	playerUUID, err := uuid.Parse(playerID)
+	if err != nil {
+		return fmt.Errorf("invalid player id")
+	}
+
+	err = h.updateRoom(func(r *room.Room) error {
+		return r.ChooseColor(playerUUID, color)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Remember the choice as the player's preference for future rooms
+	err = h.players.Update(playerUUID, func(p *player.Player) error {
+		p.Color = color
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error saving color preference of player %s: %v", playerID, err)
+	}
+
+	log.Printf("Player %s chose color %s in room %s", playerID, color, h.ID)
+	return nil
+}
+
+func (h *RoomHub) reorderSeats(playerID string, seats []any) error {
+	order := make([]uuid.UUID, 0, len(seats))
+	for _, seat := range seats {
+		id, _ := seat.(string)
+		seatUUID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("invalid player id in seat order")
+		}
+		order = append(order, seatUUID)
+	}
+
+	err := h.updateOwnedRoom(playerID, "reorder seats", func(r *room.Room) error {
+		return r.ReorderSeats(order)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Seats reordered in room %s", h.ID)
+	return nil
+}
+
+func (h *RoomHub) updateInviteCode(playerID string, revoke bool) error {
+	err := h.updateOwnedRoom(playerID, "manage the invite code", func(r *room.Room) error {
+		if revoke {
+			r.RevokeInviteCode()
+		} else {
+			r.RegenerateInviteCode()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if revoke {
+		log.Printf("Invite code revoked in room %s", h.ID)
+	} else {
+		log.Printf("Invite code regenerated in room %s", h.ID)
+	}
+	return nil
+}
+
+func (h *RoomHub) kickPlayer(playerID string, targetID string) error {
+	if targetID == playerID {
+		return fmt.Errorf("the owner cannot kick themselves")
+	}
+
+	r := h.getRoom()
+	if r == nil {
+		return fmt.Errorf("room not found")
+	}
+
+	if r.OwnerID.String() != playerID {
+		return fmt.Errorf("only the room owner can kick players")
+	}
+
+	return h.removePlayer(targetID, "kicked", true)
+}
+
+// removePlayer takes a player out of the room, notifies and disconnects their
+// clients with a message of the given type and closes the hub if the room is
+// left empty
+func (h *RoomHub) removePlayer(playerID string, notification string, kicked bool) error {
+	playerUUID, err := uuid.Parse(playerID)
+	if err != nil {
+		return fmt.Errorf("player is not in the room")
+	}
+
+	var empty, ownerChanged bool
+	var newOwner string
+
+	err = h.updateRoom(func(r *room.Room) error {
+		if !r.HasPlayer(playerUUID) {
+			return fmt.Errorf("player is not in the room")
+		}
+
+		previousOwner := r.OwnerID
+		if kicked {
+			empty = r.Kick(playerUUID)
+		} else {
+			empty = r.RemovePlayer(playerUUID)
+		}
+
+		ownerChanged = !empty && r.OwnerID != previousOwner
+		newOwner = r.OwnerName
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Player %s removed from room %s (%s)", playerID, h.ID, notification)
+
+	if ownerChanged {
+		log.Printf("Ownership of room %s transferred to %s", h.ID, newOwner)
+	}
+
+	h.disconnectPlayer(playerID, notification)
+	h.publishEvent(roomEvent{
+		Kind:         roomEventDisconnect,
+		PlayerID:     playerID,
+		Notification: notification,
+	})
+
+	if empty {
+		h.close()
+	}
+
+	return nil
+}
+
+// disconnectPlayer sends a final message to the player's clients and closes them
+func (h *RoomHub) disconnectPlayer(playerID string, notification string) {
+	data, err := json.Marshal(map[string]any{
+		"type":    notification,
+		"room_id": h.ID,
+	})
+	if err != nil {
+		log.Printf("Error marshaling %s message: %v", notification, err)
+		return
+	}
+
 	for client := range h.clients {
-		playerList = append(playerList, map[string]any{
-			"id":    client.id,
-			"name":  client.username,
-			"ready": client.ready,
-		})
+		if client.id != playerID {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+		}
+		close(client.send)
+		delete(h.clients, client)
+	}
+}
+
+func (h *RoomHub) publish(eventType string, r *room.Room) {
+	if h.lobby != nil {
+		h.lobby.Publish(eventType, r)
+	}
+}
+
+// getRoom returns a snapshot of the hub's room, nil if it no longer exists
+func (h *RoomHub) getRoom() *room.Room {
+	roomUUID, err := uuid.Parse(h.ID)
+	if err != nil {
+		return nil
+	}
+
+	r, err := h.rooms.Get(roomUUID)
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+func (h *RoomHub) updateRoom(fn func(r *room.Room) error) error {
+	roomUUID, err := uuid.Parse(h.ID)
+	if err != nil {
+		return fmt.Errorf("room not found")
+	}
+
+	err = h.rooms.Update(roomUUID, fn)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("room not found")
+	}
+	return err
+}
+
+// updateOwnedRoom runs fn only when playerID owns the room, action describes
+// the operation in the error sent to other players
+func (h *RoomHub) updateOwnedRoom(playerID string, action string, fn func(r *room.Room) error) error {
+	return h.updateRoom(func(r *room.Room) error {
+		if r.OwnerID.String() != playerID {
+			return fmt.Errorf("only the room owner can %s", action)
+		}
+		return fn(r)
+	})
+}
+
+// sendError delivers an error message only to the clients of the given player
+func (h *RoomHub) sendError(playerID string, message string) {
+	data, err := json.Marshal(map[string]any{
+		"type":    "error",
+		"room_id": h.ID,
+		"message": message,
+	})
+	if err != nil {
+		log.Printf("Error marshaling error message: %v", err)
+		return
 	}
 
+	for client := range h.clients {
+		if client.id != playerID {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+			close(client.send)
+			delete(h.clients, client)
+		}
+	}
+}
+
+// broadcastRoomState sends the room state to the clients of every instance
+// after a change handled by this hub
+func (h *RoomHub) broadcastRoomState() {
+	h.publish(LobbyRoomUpdated, h.getRoom())
+	h.publishEvent(roomEvent{Kind: roomEventState, Presence: h.localPresence()})
+	h.sendRoomState()
+}
+
+// sendRoomState sends the room state to the clients of this instance
+func (h *RoomHub) sendRoomState() {
 	message := map[string]any{
 		"type":    "room_update",
 		"room_id": h.ID,
-		"players": playerList,
+		"players": h.seatList(),
+	}
+
+	if r := h.getRoom(); r != nil {
+		message["owner_id"] = r.OwnerID.String()
+		message["max_players"] = r.MaxPlayers
+		message["settings"] = r.Settings
+		message["available_colors"] = r.AvailableColors()
+		message["private"] = r.Private
+		message["has_password"] = r.HasPassword()
+		message["invite_code"] = r.InviteCode
 	}
 
 	data, err := json.Marshal(message)
@@ -117,6 +579,59 @@ func (h *RoomHub) broadcastRoomState() {
 	}
 }
 
+// seatList lists the room players in seat order, followed by any connected
+// client that is not seated
+func (h *RoomHub) seatList() []map[string]any {
+	playerList := make([]map[string]any, 0)
+	listed := make(map[string]bool)
+
+	connected := make(map[string]presence)
+	for _, c := range h.connected() {
+		connected[c.ID] = c
+	}
+
+	if r := h.getRoom(); r != nil {
+		for i, p := range r.Players {
+			id := p.ID.String()
+			if listed[id] {
+				continue
+			}
+			listed[id] = true
+
+			entry := map[string]any{
+				"id":        id,
+				"name":      p.Name,
+				"ready":     false,
+				"connected": false,
+				"seat":      i,
+				"color":     r.Colors[p.ID],
+			}
+			if c, ok := connected[id]; ok {
+				entry["ready"] = c.Ready
+				entry["connected"] = true
+			}
+			playerList = append(playerList, entry)
+		}
+	}
+
+	for _, c := range h.connected() {
+		if listed[c.ID] {
+			continue
+		}
+		listed[c.ID] = true
+
+		playerList = append(playerList, map[string]any{
+			"id":        c.ID,
+			"name":      c.Name,
+			"ready":     c.Ready,
+			"connected": true,
+		})
+	}
+
+	return playerList
+}
+
+// broadcastGameStart tells the clients of this instance the game started
 func (h *RoomHub) broadcastGameStart() {
 	message := map[string]any{
 		"type":    "game_started",
@@ -152,3 +667,7 @@ func (h *RoomHub) GetUnregisterChan() chan *Client {
 func (h *RoomHub) GetBroadcastChan() chan []byte {
 	return h.broadcast
 }
+
+func (h *RoomHub) Done() <-chan struct{} {
+	return h.done
+}
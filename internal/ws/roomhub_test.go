@@ -0,0 +1,136 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/repository"
+)
+
+// newTestRoomHub returns the hub of a room with an owner and a guest, whose
+// clients are connected without running the hub's loop
+func newTestRoomHub(t *testing.T) (hub *RoomHub, owner, guest *Client) {
+	t.Helper()
+
+	rooms := repository.NewMemoryRoomRepository()
+	alice, _ := player.NewPlayer("Alice")
+	bob, _ := player.NewPlayer("Bob")
+	r, _ := room.NewRoom("Test", alice.ID, alice.Name, room.DefaultSettings())
+	r.AddPlayer(alice)
+	r.AddPlayer(bob)
+	if err := rooms.Create(r); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	hub = NewRoomHub(r.RoomID.String(), rooms, repository.NewMemoryPlayerRepository(), nil, NewLocalCluster(), nil)
+	owner = &Client{id: alice.ID.String(), send: make(chan []byte, 16)}
+	guest = &Client{id: bob.ID.String(), send: make(chan []byte, 16)}
+	hub.clients[owner] = true
+	hub.clients[guest] = true
+	return hub, owner, guest
+}
+
+// lastMessageType drains the client's messages and returns the type of the
+// last one
+func lastMessageType(c *Client) string {
+	var last string
+	for {
+		select {
+		case data := <-c.send:
+			var msg struct {
+				Type string `json:"type"`
+			}
+			json.Unmarshal(data, &msg)
+			last = msg.Type
+		default:
+			return last
+		}
+	}
+}
+
+func TestRoomHub_StartGame(t *testing.T) {
+	hub, owner, guest := newTestRoomHub(t)
+	start := func(c *Client) {
+		msg, _ := json.Marshal(map[string]any{"type": "start_game", "player_id": c.id})
+		hub.handleMessage(msg)
+	}
+
+	start(guest)
+	if got := lastMessageType(guest); got != "error" || hub.getRoom().Started {
+		t.Errorf("start by a guest sent %q, want an error and the room not started", got)
+	}
+
+	start(owner)
+	if got := lastMessageType(owner); got != "game_started" || !hub.getRoom().Started {
+		t.Errorf("start by the owner sent %q, want game_started", got)
+	}
+
+	start(owner)
+	if got := lastMessageType(owner); got != "error" {
+		t.Errorf("second start sent %q, want an error", got)
+	}
+}
+
+func TestRoomServer_AbandonedRooms(t *testing.T) {
+	grace := RoomAbandonGrace
+	RoomAbandonGrace = 50 * time.Millisecond
+	t.Cleanup(func() { RoomAbandonGrace = grace })
+
+	tests := []struct {
+		name      string
+		started   bool
+		reconnect bool
+		deleted   bool
+	}{
+		{"Abandoned", false, false, true},
+		{"Player reconnects", false, true, false},
+		{"Game started", true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rooms := repository.NewMemoryRoomRepository()
+			players := repository.NewMemoryPlayerRepository()
+			alice, _ := player.NewPlayer("Alice")
+			players.Create(alice)
+			r, _ := room.NewRoom("Test", alice.ID, alice.Name, room.DefaultSettings())
+			r.AddPlayer(alice)
+			r.Started = tt.started
+			rooms.Create(r)
+
+			server, err := NewRoomServer(rooms, players, NewLocalCluster())
+			if err != nil {
+				t.Fatalf("NewRoomServer() error = %v", err)
+			}
+			hub, err := server.GetOrCreateHub(r.RoomID.String(), alice.ID.String(), "", "")
+			if err != nil {
+				t.Fatalf("GetOrCreateHub() error = %v", err)
+			}
+
+			client := &Client{id: alice.ID.String(), send: make(chan []byte, 16)}
+			hub.register <- client
+			hub.unregister <- client
+			if tt.reconnect {
+				hub.register <- &Client{id: alice.ID.String(), send: make(chan []byte, 16)}
+			}
+
+			// The room is deleted after its hub stops, so the deletion is polled
+			deleted := false
+			for deadline := time.Now().Add(4 * RoomAbandonGrace); !deleted && time.Now().Before(deadline); {
+				time.Sleep(5 * time.Millisecond)
+				_, err = rooms.Get(r.RoomID)
+				deleted = errors.Is(err, repository.ErrNotFound)
+			}
+			if deleted != tt.deleted {
+				t.Errorf("room deleted = %v, want %v", deleted, tt.deleted)
+			}
+			if closed := server.GetHub(r.RoomID.String()) == nil; closed != tt.deleted {
+				t.Errorf("hub removed = %v, want %v", closed, tt.deleted)
+			}
+		})
+	}
+}
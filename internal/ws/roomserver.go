@@ -1,46 +1,132 @@
 package ws
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
 
-	"es2.uff/war-server/internal/domain/player"
 	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/repository"
 	"github.com/google/uuid"
 )
 
 type RoomServer struct {
 	sync.RWMutex
-	rooms map[string]*RoomHub
+	rooms   map[string]*RoomHub
+	roomDB  repository.RoomRepository
+	players repository.PlayerRepository
+	lobby   *LobbyHub
+	cluster Cluster
 }
 
-func NewRoomServer() *RoomServer {
-	return &RoomServer{
-		rooms: make(map[string]*RoomHub),
+func NewRoomServer(
+	rooms repository.RoomRepository,
+	players repository.PlayerRepository,
+	cluster Cluster,
+) (*RoomServer, error) {
+	lobby := NewLobbyHub(rooms, cluster.Bus)
+	if err := lobby.subscribe(); err != nil {
+		return nil, err
 	}
+	go lobby.Run()
+
+	return &RoomServer{
+		rooms:   make(map[string]*RoomHub),
+		roomDB:  rooms,
+		players: players,
+		lobby:   lobby,
+		cluster: cluster,
+	}, nil
+}
+
+func (rs *RoomServer) Lobby() *LobbyHub {
+	return rs.lobby
+}
+
+// NotifyRoomCreated announces a new room to the lobby feed
+func (rs *RoomServer) NotifyRoomCreated(r *room.Room) {
+	rs.lobby.Publish(LobbyRoomCreated, r)
 }
 
-func (rs *RoomServer) GetOrCreateHub(roomID string, userID string) *RoomHub {
+// GetOrCreateHub returns the hub of a room, adding the user to the room's
+// players first. Users not yet in the room must pass the room's access
+// checks (free seat, invite code for private rooms and password).
+func (rs *RoomServer) GetOrCreateHub(roomID string, userID string, inviteCode string, password string) (*RoomHub, error) {
+	roomUUID, err := uuid.Parse(roomID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid room id")
+	}
+
+	playerUUID, _ := uuid.Parse(userID)
+	joiningPlayer, err := rs.players.Get(playerUUID)
+	if err != nil {
+		return nil, fmt.Errorf("player not found")
+	}
+
+	// The password is checked before taking the locks, as bcrypt is slow
+	r, err := rs.roomDB.Get(roomUUID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("room not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkedHash, passwordChecked := r.PasswordHash, false
+	if !r.HasPlayer(playerUUID) {
+		if err := r.CheckPassword(password); err != nil {
+			return nil, err
+		}
+		passwordChecked = true
+	}
+
 	rs.Lock()
 	defer rs.Unlock()
 
-	if hub, exists := rs.rooms[roomID]; exists {
-		playerUUID, _ := uuid.Parse(userID)
-		roomUUID, _ := uuid.Parse(roomID)
-		joiningPlayer := player.GetPlayer(playerUUID)
-
-		room.AddPlayerToRoom(roomUUID, joiningPlayer)
+	joined := false
+	err = rs.roomDB.Update(roomUUID, func(r *room.Room) error {
+		if r.HasPlayer(playerUUID) {
+			return nil
+		}
+
+		// The password may have been set or changed since it was checked
+		if (!passwordChecked && r.HasPassword()) || !bytes.Equal(r.PasswordHash, checkedHash) {
+			return fmt.Errorf("wrong room password")
+		}
+		if err := r.CheckAccess(playerUUID, inviteCode); err != nil {
+			return err
+		}
+
+		r.AddPlayer(joiningPlayer)
+		joined = true
+		return nil
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("room not found")
+	}
+	if err != nil {
+		return nil, err
+	}
 
+	if joined {
 		log.Printf("Player %s joined room %s", joiningPlayer.Name, roomID)
-		return hub
 	}
 
-	hub := NewRoomHub(roomID, rs.handleOwnerLeft)
+	// Hubs stopped by another instance closing the room are replaced
+	if hub, exists := rs.rooms[roomID]; exists && !hub.isClosed() {
+		return hub, nil
+	}
+
+	hub := NewRoomHub(roomID, rs.roomDB, rs.players, rs.lobby, rs.cluster, rs.handleRoomEmpty)
+	if err := hub.subscribe(); err != nil {
+		return nil, err
+	}
 	rs.rooms[roomID] = hub
 
 	go hub.Run()
 
-	return hub
+	return hub, nil
 }
 
 func (rs *RoomServer) GetHub(roomID string) *RoomHub {
@@ -57,12 +143,21 @@ func (rs *RoomServer) RemoveHub(roomID string) {
 	delete(rs.rooms, roomID)
 }
 
-func (rs *RoomServer) handleOwnerLeft(roomID string) {
-	log.Printf("Handling owner left for room %s", roomID)
+// handleRoomEmpty deletes a room once its last player has left
+func (rs *RoomServer) handleRoomEmpty(roomID string) {
+	log.Printf("Room %s is empty, removing it", roomID)
 	rs.RemoveHub(roomID)
 
 	roomUUID, err := uuid.Parse(roomID)
-	if err == nil {
-		room.DeleteRoom(roomUUID)
+	if err != nil {
+		return
+	}
+
+	if r, err := rs.roomDB.Get(roomUUID); err == nil {
+		rs.lobby.Publish(LobbyRoomDeleted, r)
+	}
+
+	if err := rs.roomDB.Delete(roomUUID); err != nil {
+		log.Printf("Error deleting room %s: %v", roomID, err)
 	}
 }
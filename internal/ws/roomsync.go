@@ -0,0 +1,113 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+)
+
+const (
+	roomEventState       = "state"
+	roomEventDisconnect  = "disconnect"
+	roomEventGameStarted = "game_started"
+	roomEventClosed      = "closed"
+)
+
+// presence is a client connected to a room hub
+type presence struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+}
+
+// roomEvent lets the hubs of the same room on different instances follow each
+// other. Room data lives in the repository, events only carry what each hub
+// keeps in memory.
+type roomEvent struct {
+	Kind         string     `json:"kind"`
+	Node         string     `json:"node"`
+	Presence     []presence `json:"presence,omitempty"`
+	PlayerID     string     `json:"player_id,omitempty"`
+	Notification string     `json:"notification,omitempty"`
+}
+
+// subscribe starts receiving the room's events from other instances
+func (h *RoomHub) subscribe() error {
+	events, err := h.cluster.Bus.Subscribe(roomTopic(h.ID))
+	if err != nil {
+		return err
+	}
+
+	h.events = events
+	return nil
+}
+
+func (h *RoomHub) publishEvent(event roomEvent) {
+	event.Node = h.cluster.Node
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error marshaling room event: %v", err)
+		return
+	}
+
+	if err := h.cluster.Bus.Publish(roomTopic(h.ID), data); err != nil {
+		log.Printf("Error publishing event of room %s: %v", h.ID, err)
+	}
+}
+
+// handleEvent applies an event published by the hub of another instance,
+// events of this instance were already applied when they were published
+func (h *RoomHub) handleEvent(message []byte) {
+	var event roomEvent
+	if err := json.Unmarshal(message, &event); err != nil {
+		log.Printf("Error unmarshaling room event: %v", err)
+		return
+	}
+
+	if event.Node == h.cluster.Node {
+		return
+	}
+
+	switch event.Kind {
+	case roomEventState:
+		// Presence of an instance that crashed stays until the room closes
+		if len(event.Presence) == 0 {
+			delete(h.remote, event.Node)
+		} else {
+			h.remote[event.Node] = event.Presence
+		}
+		h.sendRoomState()
+		h.checkAbandoned()
+
+	case roomEventDisconnect:
+		h.disconnectPlayer(event.PlayerID, event.Notification)
+		h.sendRoomState()
+
+	case roomEventGameStarted:
+		h.broadcastGameStart()
+
+	case roomEventClosed:
+		h.stop()
+	}
+}
+
+func (h *RoomHub) localPresence() []presence {
+	local := make([]presence, 0, len(h.clients))
+	for client := range h.clients {
+		local = append(local, presence{
+			ID:    client.id,
+			Name:  client.username,
+			Ready: client.ready,
+		})
+	}
+	return local
+}
+
+// connected lists the clients of the room on every instance
+func (h *RoomHub) connected() []presence {
+	all := h.localPresence()
+	for _, remote := range h.remote {
+		all = append(all, remote...)
+	}
+	return all
+}
@@ -0,0 +1,69 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"es2.uff/war-server/internal/bus"
+	"es2.uff/war-server/internal/domain/player"
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/repository"
+	"github.com/google/uuid"
+)
+
+func TestRoomHub_PresenceAcrossInstances(t *testing.T) {
+	rooms := repository.NewMemoryRoomRepository()
+	players := repository.NewMemoryPlayerRepository()
+	b, leases := bus.NewMemoryBus(), bus.NewMemoryLeases()
+
+	r, _ := room.NewRoom("Replicated", uuid.New(), "Owner", room.DefaultSettings())
+	alice, _ := player.NewPlayer("Alice")
+	bob, _ := player.NewPlayer("Bob")
+	r.AddPlayer(alice)
+	r.AddPlayer(bob)
+	if err := rooms.Create(r); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	newHub := func(node string) *RoomHub {
+		hub := NewRoomHub(r.RoomID.String(), rooms, players, nil, Cluster{Node: node, Bus: b, Leases: leases}, nil)
+		if err := hub.subscribe(); err != nil {
+			t.Fatalf("subscribe() error = %v", err)
+		}
+		go hub.Run()
+		return hub
+	}
+	first, second := newHub("a"), newHub("b")
+
+	bobClient := &Client{id: bob.ID.String(), username: bob.Name, send: make(chan []byte, 16)}
+	second.register <- bobClient
+
+	aliceClient := &Client{id: alice.ID.String(), username: alice.Name, ready: true, send: make(chan []byte, 16)}
+	first.register <- aliceClient
+
+	// Bob, on the other instance, sees Alice connected and ready
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case data := <-bobClient.send:
+			var msg struct {
+				Players []struct {
+					ID        string `json:"id"`
+					Connected bool   `json:"connected"`
+					Ready     bool   `json:"ready"`
+				} `json:"players"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatalf("invalid room update: %v", err)
+			}
+			for _, p := range msg.Players {
+				if p.ID == alice.ID.String() && p.Connected && p.Ready {
+					return
+				}
+			}
+		case <-deadline:
+			t.Fatal("Alice never showed up as connected on the other instance")
+		}
+	}
+}
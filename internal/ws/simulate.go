@@ -0,0 +1,164 @@
+package ws
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"es2.uff/war-server/internal/domain/card"
+	"es2.uff/war-server/internal/domain/room"
+	"github.com/google/uuid"
+)
+
+// Clone returns a copy of the state for simulations. The copy shares nothing
+// with the original, keeps its dice where they were and records no events.
+func (gs *GameState) Clone() *GameState {
+	gs.RLock()
+	defer gs.RUnlock()
+
+	c := &GameState{
+		RoomID:                    gs.RoomID,
+		Players:                   make(map[string]*Player, len(gs.Players)),
+		FinishedInitialDeployment: slices.Clone(gs.FinishedInitialDeployment),
+		Territories:               copyTerritories(gs.Territories),
+		TurnOrder:                 slices.Clone(gs.TurnOrder),
+		CurrentTurn:               gs.CurrentTurn,
+		TurnNumber:                gs.TurnNumber,
+		OwnerID:                   gs.OwnerID,
+		TradesCount:               gs.TradesCount,
+		Settings:                  gs.Settings,
+		StartedAt:                 gs.StartedAt,
+		WinnerID:                  gs.WinnerID,
+		Paused:                    gs.Paused,
+		Stats:                     make(map[string]*PlayerStats, len(gs.Stats)),
+		simulation:                true,
+	}
+
+	for id, p := range gs.Players {
+		c.Players[id] = copyPlayer(p)
+	}
+	for id, s := range gs.Stats {
+		stats := *s
+		stats.RegionConquests = maps.Clone(s.RegionConquests)
+		c.Stats[id] = &stats
+	}
+	if gs.Deck != nil {
+		c.Deck = &card.Deck{Cards: slices.Clone(gs.Deck.Cards)}
+	}
+	if gs.rng != nil {
+		rng := *gs.rng
+		c.rng = &rng
+	}
+
+	return c
+}
+
+// NewSeededGame starts a game seeded with seed, with a seat for each
+// difficulty in turn order. An empty difficulty seats a player who is not a
+// bot. The same seed and seats deal the same game with the same IDs.
+func NewSeededGame(seed uint64, settings room.RoomSettings, seats []room.BotDifficulty) *GameState {
+	gs := NewGameState(fmt.Sprintf("simulation-%d", seed))
+	gs.Settings = settings
+	gs.Seed(seed)
+
+	for i, d := range seats {
+		id := uuid.NewSHA1(uuid.NameSpaceOID, fmt.Appendf(nil, "simulation/%d/%d", seed, i)).String()
+		gs.Players[id] = &Player{ID: id, Username: fmt.Sprintf("Bot %d", i+1), IsBot: d != "", BotDifficulty: string(d)}
+		gs.TurnOrder = append(gs.TurnOrder, id)
+	}
+	gs.StartGame()
+	return gs
+}
+
+// BotView returns a copy of what the player sees of the game
+func (gs *GameState) BotView(playerID string) *BotView {
+	gs.RLock()
+	defer gs.RUnlock()
+	return newBotView(gs, playerID)
+}
+
+// PlayBotTurn plays the current player's whole turn with a strategy and
+// passes the turn, applying each decision to gs right away. It needs no
+// Game, so simulations and tools can run bots on their own.
+func PlayBotTurn(gs *GameState, strategy BotStrategy) error {
+	gs.RLock()
+	playerID := gs.CurrentTurn
+	gs.RUnlock()
+
+	for range MaxBotDecisions {
+		cards, ok := strategy.Trade(gs.BotView(playerID))
+		if !ok {
+			break
+		}
+		if _, err := gs.Trade(playerID, cards[0], cards[1], cards[2]); err != nil {
+			break
+		}
+	}
+
+	// A deploy that places nothing leaves the armies as they were
+	for left := -1; ; {
+		view := gs.BotView(playerID)
+		if view.Armies <= 0 || view.Armies == left {
+			break
+		}
+		left = view.Armies
+
+		territoryID := strategy.Deploy(view)
+		if territoryID == "" || gs.Deploy(playerID, territoryID) != nil {
+			break
+		}
+	}
+
+	playAttacks(gs, playerID, strategy)
+	playFortify(gs, playerID, strategy)
+
+	if gs.Winner() != "" {
+		return nil
+	}
+	_, err := gs.NextTurn(playerID)
+	return err
+}
+
+// playAttacks attacks until the strategy stops or the game is won
+func playAttacks(gs *GameState, playerID string, strategy BotStrategy) {
+	for range MaxBotDecisions {
+		attack, ok := strategy.Attack(gs.BotView(playerID))
+		if !ok {
+			return
+		}
+		if _, err := gs.Attack(playerID, attack.From, attack.To, attack.Armies); err != nil {
+			return
+		}
+		if gs.Winner() != "" {
+			return
+		}
+		occupy(gs, playerID, strategy, attack)
+	}
+}
+
+// occupy moves armies into the territory of a won attack
+func occupy(gs *GameState, playerID string, strategy BotStrategy, attack BotAttack) {
+	view := gs.BotView(playerID)
+	if view.Territory(attack.To).Owner != playerID {
+		return
+	}
+	if armies := strategy.Occupy(view, attack); armies > 0 {
+		gs.Move(playerID, attack.From, attack.To, armies)
+	}
+}
+
+func playFortify(gs *GameState, playerID string, strategy BotStrategy) {
+	for range MaxBotDecisions {
+		move, ok := strategy.Fortify(gs.BotView(playerID))
+		if !ok || gs.Move(playerID, move.From, move.To, move.Armies) != nil {
+			return
+		}
+	}
+}
+
+// Winner returns the ID of the player who won, empty while the game goes on
+func (gs *GameState) Winner() string {
+	gs.RLock()
+	defer gs.RUnlock()
+	return gs.WinnerID
+}
@@ -0,0 +1,69 @@
+package ws
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"es2.uff/war-server/internal/domain/room"
+	"github.com/google/uuid"
+)
+
+// newBotGame starts a game between bots of the given difficulties
+func newBotGame(difficulties ...room.BotDifficulty) *GameState {
+	state := NewGameState("room-1")
+	state.Settings = room.DefaultSettings()
+	for _, d := range difficulties {
+		id := uuid.NewString()
+		state.Players[id] = &Player{ID: id, Username: "Bot", IsBot: true, BotDifficulty: string(d)}
+	}
+	state.StartGame()
+	return state
+}
+
+func TestGameState_Clone(t *testing.T) {
+	state := newBotGame(room.BotEasy, room.BotEasy)
+	playerID := state.CurrentTurn
+	events := len(state.Events)
+
+	c := state.Clone()
+	owned := c.BotView(playerID).Owned()[0]
+	if err := c.Deploy(playerID, owned.ID); err != nil {
+		t.Fatalf("Deploy() on the clone error = %v", err)
+	}
+
+	if state.Players[playerID].Armies == c.Players[playerID].Armies {
+		t.Error("deploying on the clone changed the armies of the game")
+	}
+	if got := state.BotView(playerID).Territory(owned.ID).Armies; got != owned.Armies {
+		t.Errorf("territory has %d armies in the game, want %d", got, owned.Armies)
+	}
+	if len(state.Events) != events || len(c.Events) != 0 {
+		t.Errorf("events = %d in the game and %d in the clone, want %d and 0", len(state.Events), len(c.Events), events)
+	}
+
+	// Both roll the same dice from here on
+	a, b := rand.New(state.rng), rand.New(c.rng)
+	if a.Uint64() != b.Uint64() {
+		t.Error("the clone rolls other dice than the game")
+	}
+}
+
+func TestPlayBotTurn_PlaysAGameToTheEnd(t *testing.T) {
+	state := newBotGame(room.BotHard, room.BotMedium, room.BotEasy)
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	for turn := 0; state.Winner() == ""; turn++ {
+		if turn == 2000 {
+			t.Fatal("no winner after 2000 turns")
+		}
+
+		playerID := state.CurrentTurn
+		difficulty := room.BotDifficulty(state.Players[playerID].BotDifficulty)
+		if err := PlayBotTurn(state, NewBotStrategy(difficulty, rng)); err != nil {
+			t.Fatalf("PlayBotTurn() error = %v", err)
+		}
+		if state.Winner() == "" && state.CurrentTurn == playerID {
+			t.Fatalf("turn of %s was not passed", playerID)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"es2.uff/war-server/internal/domain/card"
+	"es2.uff/war-server/internal/repository"
+)
+
+// gameSnapshot is what gets stored for a running game, the deck is kept
+// apart since it is hidden from the state sent to clients. Events are stored
+// in the game's event log instead, so saves do not rewrite them.
+type gameSnapshot struct {
+	State  *GameState  `json:"state"`
+	Deck   *card.Deck  `json:"deck"`
+	Events []GameEvent `json:"events,omitempty"` // Only in snapshots stored before the event log
+	RNG    []byte      `json:"rng"`
+	Log    []Gamelog   `json:"log"`
+	logged int         // How many events were read from the event log
+}
+
+// restore puts the hidden parts of the snapshot back into its state
+func (s *gameSnapshot) restore() (*GameState, error) {
+	if s.State == nil {
+		return nil, fmt.Errorf("snapshot has no state")
+	}
+
+	state := s.State
+	state.Deck = s.Deck
+	if state.Deck == nil {
+		state.Deck = card.NewDeck()
+	}
+	state.Events = s.Events
+
+	state.rng = newRNG()
+	if len(s.RNG) > 0 {
+		if err := state.rng.UnmarshalBinary(s.RNG); err != nil {
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
+
+// persist saves the game after each action so it can be restored if the
+// server restarts, games that never started or already ended are not stored.
+// Only the events since the last save are added to the event log.
+func (g *Game) persist() {
+	if g.archive == nil {
+		return
+	}
+
+	g.GameState.RLock()
+	if g.GameState.CurrentTurn == "" || g.GameState.WinnerID != "" {
+		g.GameState.RUnlock()
+		return
+	}
+	rng, err := g.GameState.rng.MarshalBinary()
+	if err != nil {
+		g.GameState.RUnlock()
+		log.Printf("Error saving dice of game %s: %v", g.ID, err)
+		return
+	}
+	data, err := json.Marshal(gameSnapshot{
+		State: g.GameState,
+		Deck:  g.GameState.Deck,
+		RNG:   rng,
+		Log:   g.log,
+	})
+	saved := min(g.savedEvents, len(g.GameState.Events))
+	var events [][]byte
+	if err == nil {
+		events, err = marshalEvents(g.GameState.Events[saved:])
+	}
+	g.GameState.RUnlock()
+
+	if err != nil {
+		log.Printf("Error marshaling game snapshot: %v", err)
+		return
+	}
+
+	if err := g.archive.SaveGame(g.ID, data, saved, events...); err != nil {
+		log.Printf("Error saving game %s: %v", g.ID, err)
+		return
+	}
+	g.savedEvents = saved + len(events)
+}
+
+func marshalEvents(events []GameEvent) ([][]byte, error) {
+	data := make([][]byte, len(events))
+	for i, e := range events {
+		var err error
+		if data[i], err = json.Marshal(e); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// loadSnapshot reads the stored snapshot of a game along with its event log
+func (gm *GameManager) loadSnapshot(id string) (*gameSnapshot, error) {
+	data, err := gm.archive.GetGame(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot gameSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+
+	events, err := gm.archive.GetEvents(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > 0 {
+		snapshot.Events = make([]GameEvent, len(events))
+		for i, event := range events {
+			if err := json.Unmarshal(event, &snapshot.Events[i]); err != nil {
+				return nil, fmt.Errorf("decoding event %d: %w", i, err)
+			}
+		}
+	}
+	snapshot.logged = len(events)
+
+	return &snapshot, nil
+}
+
+// restoreGame loads the stored snapshot of a game, returning false when
+// there is none
+func (gm *GameManager) restoreGame(game *Game) bool {
+	if gm.archive == nil {
+		return false
+	}
+
+	snapshot, err := gm.loadSnapshot(game.ID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			log.Printf("Error loading game %s: %v", game.ID, err)
+		}
+		return false
+	}
+
+	state, err := snapshot.restore()
+	if err != nil {
+		log.Printf("Error restoring game %s: %v", game.ID, err)
+		return false
+	}
+	game.GameState = state
+	game.savedEvents = snapshot.logged
+
+	if snapshot.Log != nil {
+		game.log = snapshot.Log
+	}
+
+	log.Printf("Restored game %s at turn %d", game.ID, game.GameState.TurnNumber)
+	return true
+}
+
+// RestoreGames resumes every stored game on startup. Turn timers start over
+// and a bot whose turn was interrupted plays it again. Games owned by another
+// running instance stay with it.
+func (gm *GameManager) RestoreGames() error {
+	if gm.archive == nil {
+		return nil
+	}
+
+	ids, err := gm.archive.ListGames()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if _, err := gm.GetOrCreateGame(id); err != nil {
+			log.Printf("Error restoring game %s: %v", id, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package ws
+
+import (
+	"strings"
+	"testing"
+
+	"es2.uff/war-server/internal/domain/room"
+	"es2.uff/war-server/internal/repository"
+	"github.com/google/uuid"
+)
+
+func TestGameManager_RestoreGames(t *testing.T) {
+	archive := repository.NewMemoryGameRepository()
+
+	state := NewGameState("room-1")
+	state.Settings = room.DefaultSettings()
+	for range 3 {
+		id := uuid.NewString()
+		state.Players[id] = &Player{ID: id, Username: "Player"}
+	}
+	state.StartGame()
+
+	manager := NewGameManager(repository.NewMemoryRoomRepository(), archive, nil, NewLocalCluster())
+	game := manager.newGame("room-1", state)
+	game.log = append(game.log, Gamelog{Message: "Partida iniciada."})
+	game.persist()
+
+	restored := NewGameManager(repository.NewMemoryRoomRepository(), archive, nil, NewLocalCluster())
+	if err := restored.RestoreGames(); err != nil {
+		t.Fatalf("RestoreGames() error = %v", err)
+	}
+
+	got, err := restored.GetOrCreateGame("room-1")
+	if err != nil {
+		t.Fatalf("GetOrCreateGame() error = %v", err)
+	}
+	got.GameState.RLock()
+	defer got.GameState.RUnlock()
+
+	if got.GameState.CurrentTurn != state.CurrentTurn || got.GameState.TurnNumber != state.TurnNumber {
+		t.Errorf("restored turn = %s/%d, want %s/%d",
+			got.GameState.CurrentTurn, got.GameState.TurnNumber, state.CurrentTurn, state.TurnNumber)
+	}
+	if len(got.GameState.Territories) != len(state.Territories) {
+		t.Errorf("restored %d territories, want %d", len(got.GameState.Territories), len(state.Territories))
+	}
+	if got.GameState.Deck.Size() != state.Deck.Size() {
+		t.Errorf("restored deck has %d cards, want %d", got.GameState.Deck.Size(), state.Deck.Size())
+	}
+	if len(got.log) != 1 {
+		t.Errorf("restored log has %d entries, want 1", len(got.log))
+	}
+}
+
+func TestGame_Persist_SkipsGamesNotStarted(t *testing.T) {
+	archive := repository.NewMemoryGameRepository()
+
+	manager := NewGameManager(repository.NewMemoryRoomRepository(), archive, nil, NewLocalCluster())
+	manager.newGame("room-1", NewGameState("room-1")).persist()
+
+	if ids, _ := archive.ListGames(); len(ids) != 0 {
+		t.Errorf("ListGames() = %v, want no stored games", ids)
+	}
+}
+
+func TestGame_Persist_AppendsEvents(t *testing.T) {
+	archive := repository.NewMemoryGameRepository()
+
+	state := NewGameState("room-1")
+	state.Settings = room.DefaultSettings()
+	for range 3 {
+		id := uuid.NewString()
+		state.Players[id] = &Player{ID: id, Username: "Player"}
+	}
+	state.StartGame()
+
+	manager := NewGameManager(repository.NewMemoryRoomRepository(), archive, nil, NewLocalCluster())
+	game := manager.newGame("room-1", state)
+	game.persist()
+	for _, eventType := range []GameEventType{EventPaused, EventResumed} {
+		state.Lock()
+		state.recordLocked(GameEvent{Type: eventType})
+		state.Unlock()
+		game.persist()
+	}
+
+	if events, _ := archive.GetEvents("room-1"); len(events) != len(state.Events) {
+		t.Errorf("event log has %d events, want %d", len(events), len(state.Events))
+	}
+	if snapshot, _ := archive.GetGame("room-1"); strings.Contains(string(snapshot), `"events"`) {
+		t.Error("snapshot holds the events kept in the event log")
+	}
+
+	restored := NewGameManager(repository.NewMemoryRoomRepository(), archive, nil, NewLocalCluster())
+	got, err := restored.GetOrCreateGame("room-1")
+	if err != nil {
+		t.Fatalf("GetOrCreateGame() error = %v", err)
+	}
+	got.GameState.RLock()
+	defer got.GameState.RUnlock()
+
+	if len(got.GameState.Events) != len(state.Events) {
+		t.Fatalf("restored %d events, want %d", len(got.GameState.Events), len(state.Events))
+	}
+	if last := got.GameState.Events[len(got.GameState.Events)-1]; last.Type != EventResumed {
+		t.Errorf("last restored event = %s, want %s", last.Type, EventResumed)
+	}
+}
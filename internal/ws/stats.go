@@ -0,0 +1,56 @@
+package ws
+
+import "es2.uff/war-server/internal/domain/territory"
+
+// PlayerStats counts what a player did during a game. The actions keep it
+// up to date, so bots and replayed events are counted like live players.
+type PlayerStats struct {
+	ArmiesDestroyed int            `json:"armies_destroyed"`
+	ArmiesLost      int            `json:"armies_lost"`
+	Conquests       int            `json:"conquests"`
+	Eliminations    int            `json:"eliminations"`
+	Trades          int            `json:"trades"`
+	RegionConquests map[string]int `json:"region_conquests,omitempty"` // Conquests by continent name
+}
+
+func (gs *GameState) statsLocked(playerID string) *PlayerStats {
+	if gs.Stats == nil {
+		gs.Stats = make(map[string]*PlayerStats)
+	}
+
+	stats, exists := gs.Stats[playerID]
+	if !exists {
+		stats = &PlayerStats{}
+		gs.Stats[playerID] = stats
+	}
+	return stats
+}
+
+func (gs *GameState) countBattleLocked(attackerID, defenderID string, attackerLosses, defenderLosses int) {
+	attacker := gs.statsLocked(attackerID)
+	attacker.ArmiesDestroyed += defenderLosses
+	attacker.ArmiesLost += attackerLosses
+
+	defender := gs.statsLocked(defenderID)
+	defender.ArmiesDestroyed += attackerLosses
+	defender.ArmiesLost += defenderLosses
+}
+
+// countConquestLocked is called once t has changed hands, the defender is
+// eliminated when it was their last territory
+func (gs *GameState) countConquestLocked(attackerID, defenderID string, t *Territory) {
+	stats := gs.statsLocked(attackerID)
+	stats.Conquests++
+
+	if stats.RegionConquests == nil {
+		stats.RegionConquests = make(map[string]int)
+	}
+	stats.RegionConquests[territory.RegionNameMap[territory.Region(t.Region)]]++
+
+	for _, other := range gs.Territories {
+		if other.Owner == defenderID {
+			return
+		}
+	}
+	stats.Eliminations++
+}
@@ -0,0 +1,42 @@
+package ws
+
+import (
+	"testing"
+
+	"es2.uff/war-server/internal/domain/card"
+	"es2.uff/war-server/internal/domain/territory"
+)
+
+func TestGameState_Attack_CountsStats(t *testing.T) {
+	state := NewGameState("room-1")
+	state.Players["alice"] = &Player{ID: "alice", Username: "Alice"}
+	state.Players["bob"] = &Player{ID: "bob", Username: "Bob"}
+	state.Deck = card.NewDeck()
+	state.Territories = []*Territory{
+		{ID: "t1", Owner: "alice", Armies: 5, Region: int(territory.Africa), Adjacent: []string{"t2"}},
+		{ID: "t2", Owner: "bob", Armies: 2, Region: int(territory.Africa), Adjacent: []string{"t1"}},
+	}
+
+	state.Lock()
+	// Each side loses one army, then bob's last army falls
+	if _, _, err := state.attackLocked("alice", "t1", "t2", 2, []int{6, 1}, []int{5, 2}); err != nil {
+		t.Fatalf("attackLocked() error = %v", err)
+	}
+	if _, _, err := state.attackLocked("alice", "t1", "t2", 1, []int{6}, []int{3}); err != nil {
+		t.Fatalf("attackLocked() error = %v", err)
+	}
+	state.Unlock()
+
+	alice := state.Stats["alice"]
+	if alice.ArmiesDestroyed != 2 || alice.ArmiesLost != 1 || alice.Conquests != 1 || alice.Eliminations != 1 {
+		t.Errorf("attacker stats = %+v, want 2 destroyed, 1 lost, 1 conquest and 1 elimination", alice)
+	}
+	if alice.RegionConquests["África"] != 1 {
+		t.Errorf("attacker region conquests = %v, want one in África", alice.RegionConquests)
+	}
+
+	bob := state.Stats["bob"]
+	if bob.ArmiesDestroyed != 1 || bob.ArmiesLost != 2 || bob.Conquests != 0 {
+		t.Errorf("defender stats = %+v, want 1 destroyed and 2 lost", bob)
+	}
+}
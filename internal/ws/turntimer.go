@@ -0,0 +1,56 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// resetTurnTimer restarts the countdown of the current turn when the room
+// settings define a turn timer. When it expires a turn_timeout message is
+// queued so the turn is finished from the game's own loop. A paused game has
+// no countdown, and once resumed it gets back the time that was left.
+func (g *Game) resetTurnTimer() {
+	if g.turnTimer != nil {
+		g.turnTimer.Stop()
+		g.turnTimer = nil
+	}
+
+	g.GameState.Lock()
+	seconds := g.GameState.Settings.TurnTimer
+	currentTurn := g.GameState.CurrentTurn
+	turnNumber := g.GameState.TurnNumber
+
+	if seconds <= 0 || currentTurn == "" || g.GameState.WinnerID != "" || g.GameState.Paused {
+		g.GameState.TurnDeadline = nil
+		g.GameState.Unlock()
+		return
+	}
+
+	duration := time.Duration(seconds) * time.Second
+	if g.GameState.TurnTimeLeft > 0 {
+		duration = g.GameState.TurnTimeLeft
+		g.GameState.TurnTimeLeft = 0
+	}
+
+	deadline := time.Now().Add(duration)
+	g.GameState.TurnDeadline = &deadline
+	g.GameState.Unlock()
+
+	g.turnTimer = time.AfterFunc(time.Until(deadline), func() {
+		msg, err := json.Marshal(map[string]any{
+			"type":        "turn_timeout",
+			"player_id":   currentTurn,
+			"turn_number": turnNumber,
+		})
+		if err != nil {
+			log.Printf("Error marshaling turn timeout: %v", err)
+			return
+		}
+
+		select {
+		case g.broadcast <- msg:
+		case <-g.done:
+		}
+	})
+}